@@ -3,13 +3,17 @@ package build // import "github.com/docker/docker/api/server/backend/build"
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
+	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/builder"
 	buildkit "github.com/docker/docker/builder/builder-next"
+	"github.com/docker/docker/builder/dockerfile"
 	"github.com/docker/docker/builder/fscache"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/pkg/errors"
@@ -20,30 +24,39 @@ import (
 type ImageComponent interface {
 	SquashImage(from string, to string) (string, error)
 	TagImageWithReference(image.ID, reference.Named) error
+	SetImageProvenance(image.ID, *imagetypes.Provenance) error
 }
 
 // Builder defines interface for running a build
 type Builder interface {
 	Build(context.Context, backend.BuildConfig) (*builder.Result, error)
+	Cancel(context.Context, string) error
 }
 
 // Backend provides build functionality to the API router
 type Backend struct {
 	builder        Builder
 	fsCache        *fscache.FSCache
+	mountCache     *fscache.MountCache
 	imageComponent ImageComponent
 	buildkit       *buildkit.Builder
 }
 
 // NewBackend creates a new build backend from components
-func NewBackend(components ImageComponent, builder Builder, fsCache *fscache.FSCache, buildkit *buildkit.Builder) (*Backend, error) {
-	return &Backend{imageComponent: components, builder: builder, fsCache: fsCache, buildkit: buildkit}, nil
+func NewBackend(components ImageComponent, builder Builder, fsCache *fscache.FSCache, mountCache *fscache.MountCache, buildkit *buildkit.Builder) (*Backend, error) {
+	return &Backend{imageComponent: components, builder: builder, fsCache: fsCache, mountCache: mountCache, buildkit: buildkit}, nil
 }
 
 // Build builds an image from a Source
 func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string, error) {
 	options := config.Options
 	useBuildKit := options.Version == types.BuilderBuildKit
+	if !useBuildKit && len(options.CacheTo) > 0 {
+		return "", errdefs.InvalidParameter(errors.New("exporting build cache requires BuildKit"))
+	}
+	if !useBuildKit && len(options.Outputs) > 0 {
+		return "", errdefs.InvalidParameter(errors.New("build outputs require BuildKit"))
+	}
 
 	tagger, err := NewTagger(b.imageComponent, config.ProgressWriter.StdoutFormatter, options.Tags)
 	if err != nil {
@@ -63,7 +76,8 @@ func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string
 		}
 	}
 
-	if build == nil {
+	// builds exported to the client do not produce an image
+	if build == nil || build.ImageID == "" {
 		return "", nil
 	}
 
@@ -79,6 +93,12 @@ func (b *Backend) Build(ctx context.Context, config backend.BuildConfig) (string
 		}
 	}
 
+	if build.Provenance != nil {
+		if err := b.imageComponent.SetImageProvenance(image.ID(imageID), build.Provenance); err != nil {
+			return "", err
+		}
+	}
+
 	if !useBuildKit {
 		stdout := config.ProgressWriter.StdoutFormatter
 		fmt.Fprintf(stdout, "Successfully built %s\n", stringid.TruncateID(imageID))
@@ -101,6 +121,18 @@ func (b *Backend) PruneCache(ctx context.Context, opts types.BuildCachePruneOpti
 		return nil
 	})
 
+	var mountCacheSize uint64
+	if b.mountCache != nil {
+		eg.Go(func() error {
+			var err error
+			mountCacheSize, err = b.mountCache.Prune(ctx)
+			if err != nil {
+				return errors.Wrap(err, "failed to prune cache mounts")
+			}
+			return nil
+		})
+	}
+
 	var buildCacheSize int64
 	var cacheIDs []string
 	eg.Go(func() error {
@@ -116,14 +148,22 @@ func (b *Backend) PruneCache(ctx context.Context, opts types.BuildCachePruneOpti
 		return nil, err
 	}
 
-	return &types.BuildCachePruneReport{SpaceReclaimed: fsCacheSize + uint64(buildCacheSize), CachesDeleted: cacheIDs}, nil
+	return &types.BuildCachePruneReport{SpaceReclaimed: fsCacheSize + mountCacheSize + uint64(buildCacheSize), CachesDeleted: cacheIDs}, nil
 }
 
 // Cancel cancels the build by ID
 func (b *Backend) Cancel(ctx context.Context, id string) error {
+	if err := b.builder.Cancel(ctx, id); err != nil {
+		return err
+	}
 	return b.buildkit.Cancel(ctx, id)
 }
 
+// Lint checks the Dockerfile read from r without building it
+func (b *Backend) Lint(ctx context.Context, r io.Reader, options types.DockerfileLintOptions) (*types.DockerfileLintReport, error) {
+	return dockerfile.Lint(r, options)
+}
+
 func squashBuild(build *builder.Result, imageComponent ImageComponent) (string, error) {
 	var fromID string
 	if build.FromImage != nil {
@@ -2,6 +2,7 @@ package build // import "github.com/docker/docker/api/server/router/build"
 
 import (
 	"context"
+	"io"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
@@ -17,6 +18,9 @@ type Backend interface {
 	PruneCache(context.Context, types.BuildCachePruneOptions) (*types.BuildCachePruneReport, error)
 
 	Cancel(context.Context, string) error
+
+	// Lint a Dockerfile without building it
+	Lint(context.Context, io.Reader, types.DockerfileLintOptions) (*types.DockerfileLintReport, error)
 }
 
 type experimentalProvider interface {
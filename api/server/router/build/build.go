@@ -34,6 +34,7 @@ func (r *buildRouter) initRoutes() {
 		router.NewPostRoute("/build", r.postBuild),
 		router.NewPostRoute("/build/prune", r.postPrune),
 		router.NewPostRoute("/build/cancel", r.postCancel),
+		router.NewPostRoute("/build/lint", r.postLint),
 	}
 }
 
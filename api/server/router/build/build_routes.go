@@ -140,8 +140,27 @@ func newImageBuildOptions(ctx context.Context, r *http.Request) (*types.ImageBui
 		}
 		options.CacheFrom = cacheFrom
 	}
+
+	cacheToJSON := r.FormValue("cacheto")
+	if cacheToJSON != "" {
+		var cacheTo = []string{}
+		if err := json.Unmarshal([]byte(cacheToJSON), &cacheTo); err != nil {
+			return nil, errors.Wrap(errdefs.InvalidParameter(err), "error reading cacheto")
+		}
+		options.CacheTo = cacheTo
+	}
+
+	outputsJSON := r.FormValue("outputs")
+	if outputsJSON != "" {
+		var outputs []types.ImageBuildOutput
+		if err := json.Unmarshal([]byte(outputsJSON), &outputs); err != nil {
+			return nil, errors.Wrap(errdefs.InvalidParameter(err), "error reading outputs")
+		}
+		options.Outputs = outputs
+	}
 	options.SessionID = r.FormValue("session")
 	options.BuildID = r.FormValue("buildid")
+	options.ProgressEvents = httputils.BoolValue(r, "progressevents")
 	builderVersion, err := parseVersion(r.FormValue("version"))
 	if err != nil {
 		return nil, err
@@ -202,6 +221,24 @@ func (br *buildRouter) postCancel(ctx context.Context, w http.ResponseWriter, r
 	return br.backend.Cancel(ctx, id)
 }
 
+func (br *buildRouter) postLint(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+	var options types.DockerfileLintOptions
+	if rulesJSON := r.FormValue("rules"); rulesJSON != "" {
+		if err := json.Unmarshal([]byte(rulesJSON), &options.Rules); err != nil {
+			return errors.Wrap(errdefs.InvalidParameter(err), "error reading lint rules")
+		}
+	}
+
+	report, err := br.backend.Lint(ctx, r.Body, options)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
 func (br *buildRouter) postBuild(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	var (
 		notVerboseBuffer = bytes.NewBuffer(nil)
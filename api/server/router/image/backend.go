@@ -22,9 +22,13 @@ type Backend interface {
 type imageBackend interface {
 	ImageDelete(imageRef string, force, prune bool) ([]types.ImageDeleteResponseItem, error)
 	ImageHistory(imageName string) ([]*image.HistoryResponseItem, error)
+	ImageDiff(from, to string) ([]image.DiffResponseItem, error)
+	ImageFiles(imageName, path string) ([]image.FileInfo, error)
 	Images(imageFilters filters.Args, all bool, withExtraAttrs bool) ([]*types.ImageSummary, error)
 	LookupImage(name string) (*types.ImageInspect, error)
 	TagImage(imageName, repository, tag string) (string, error)
+	RebaseImage(imageName, oldBase, newBase string) (string, error)
+	SquashImageLayers(imageName string, from int) (string, error)
 	ImagesPrune(ctx context.Context, pruneFilters filters.Args) (*types.ImagesPruneReport, error)
 }
 
@@ -28,15 +28,19 @@ func (r *imageRouter) initRoutes() {
 		// GET
 		router.NewGetRoute("/images/json", r.getImagesJSON),
 		router.NewGetRoute("/images/search", r.getImagesSearch),
+		router.NewGetRoute("/images/diff", r.getImagesDiff),
 		router.NewGetRoute("/images/get", r.getImagesGet),
 		router.NewGetRoute("/images/{name:.*}/get", r.getImagesGet),
 		router.NewGetRoute("/images/{name:.*}/history", r.getImagesHistory),
 		router.NewGetRoute("/images/{name:.*}/json", r.getImagesByName),
+		router.NewGetRoute("/images/{name:.*}/files", r.getImagesFiles),
 		// POST
 		router.NewPostRoute("/images/load", r.postImagesLoad),
 		router.NewPostRoute("/images/create", r.postImagesCreate),
 		router.NewPostRoute("/images/{name:.*}/push", r.postImagesPush),
 		router.NewPostRoute("/images/{name:.*}/tag", r.postImagesTag),
+		router.NewPostRoute("/images/{name:.*}/rebase", r.postImagesRebase),
+		router.NewPostRoute("/images/{name:.*}/squash", r.postImagesSquash),
 		router.NewPostRoute("/images/prune", r.postImagesPrune),
 		// DELETE
 		router.NewDeleteRoute("/images/{name:.*}", r.deleteImages),
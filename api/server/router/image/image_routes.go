@@ -257,6 +257,32 @@ func (s *imageRouter) getImagesHistory(ctx context.Context, w http.ResponseWrite
 	return httputils.WriteJSON(w, http.StatusOK, history)
 }
 
+func (s *imageRouter) getImagesDiff(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+	from, to := r.Form.Get("from"), r.Form.Get("to")
+	if from == "" || to == "" {
+		return errdefs.InvalidParameter(errors.New("from and to are required"))
+	}
+	diff, err := s.backend.ImageDiff(from, to)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, diff)
+}
+
+func (s *imageRouter) getImagesFiles(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+	files, err := s.backend.ImageFiles(vars["name"], r.Form.Get("path"))
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, files)
+}
+
 func (s *imageRouter) postImagesTag(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := httputils.ParseForm(r); err != nil {
 		return err
@@ -268,6 +294,39 @@ func (s *imageRouter) postImagesTag(ctx context.Context, w http.ResponseWriter,
 	return nil
 }
 
+func (s *imageRouter) postImagesRebase(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+	oldBase, newBase := r.Form.Get("old-base"), r.Form.Get("new-base")
+	if oldBase == "" || newBase == "" {
+		return errdefs.InvalidParameter(errors.New("old-base and new-base are required"))
+	}
+	id, err := s.backend.RebaseImage(vars["name"], oldBase, newBase)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusCreated, &types.IDResponse{ID: id})
+}
+
+func (s *imageRouter) postImagesSquash(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+	var from int
+	if v := r.Form.Get("from"); v != "" {
+		var err error
+		if from, err = strconv.Atoi(v); err != nil {
+			return errdefs.InvalidParameter(errors.Wrapf(err, "invalid from value %q", v))
+		}
+	}
+	id, err := s.backend.SquashImageLayers(vars["name"], from)
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusCreated, &types.IDResponse{ID: id})
+}
+
 func (s *imageRouter) getImagesSearch(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := httputils.ParseForm(r); err != nil {
 		return err
@@ -126,6 +126,20 @@ func (s *systemRouter) getDiskUsage(ctx context.Context, w http.ResponseWriter,
 	return httputils.WriteJSON(w, http.StatusOK, du)
 }
 
+func (s *systemRouter) postFsck(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+
+	report, err := s.backend.SystemFsck(ctx, types.FsckOptions{
+		Quarantine: httputils.BoolValue(r, "quarantine"),
+	})
+	if err != nil {
+		return err
+	}
+	return httputils.WriteJSON(w, http.StatusOK, report)
+}
+
 type invalidRequestError struct {
 	Err error
 }
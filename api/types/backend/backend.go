@@ -125,4 +125,8 @@ type CommitConfig struct {
 	ContainerMountLabel string
 	ContainerOS         string
 	ParentImageID       string
+	// SourceDateEpoch, if set, clamps the timestamps of the image and of the
+	// files in its layer, and leaves the ID of the container out of the
+	// image, so that commits of the same changes are identical.
+	SourceDateEpoch *time.Time
 }
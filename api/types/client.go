@@ -175,7 +175,11 @@ type ImageBuildOptions struct {
 	Squash bool
 	// CacheFrom specifies images that are used for matching cache. Images
 	// specified here do not need to have a valid parent chain to match cache.
-	CacheFrom   []string
+	CacheFrom []string
+	// CacheTo specifies where the build cache is exported, for example
+	// "type=registry,ref=example.com/foo:cache,mode=max" or "type=inline".
+	// It is only supported by BuildKit.
+	CacheTo     []string
 	SecurityOpt []string
 	ExtraHosts  []string // List of extra hosts
 	Target      string
@@ -187,6 +191,30 @@ type ImageBuildOptions struct {
 	// build request. The same identifier can be used to gracefully cancel the
 	// build with the cancel request.
 	BuildID string
+	// Outputs defines where the build result is exported to. Only supported
+	// by BuildKit. By default, the result is an image in the daemon.
+	Outputs []ImageBuildOutput
+	// ProgressEvents makes the classic builder emit a BuildStep aux message
+	// for every step of the build.
+	ProgressEvents bool
+}
+
+// DockerfileLintOptions holds parameters to lint a Dockerfile.
+type DockerfileLintOptions struct {
+	// Rules sets the severity of lint rules, by rule name, to "error",
+	// "warning", "info", or "off" to disable the rule. Rules not set keep
+	// their default severity.
+	Rules map[string]string
+}
+
+// ImageBuildOutput defines where a build result is exported to. Type is one
+// of "moby", the daemon image store, "local", a directory, "tar", a tar
+// archive of the result filesystem, or "oci", an OCI image layout archive.
+// Other types than "moby" are sent to the build session, which must provide
+// a file sync target for them.
+type ImageBuildOutput struct {
+	Type  string
+	Attrs map[string]string
 }
 
 // BuilderVersion sets the version of underlying builder to use
@@ -259,6 +287,17 @@ type RequestPrivilegeFunc func() (string, error)
 //ImagePushOptions holds information to push images.
 type ImagePushOptions ImagePullOptions
 
+// ImageRebaseOptions holds parameters to rebase images.
+type ImageRebaseOptions struct {
+	OldBase string // OldBase is the base image the image was built on
+	NewBase string // NewBase is the base image replacing OldBase
+}
+
+// ImageSquashOptions holds parameters to squash images.
+type ImageSquashOptions struct {
+	From int // From is the index of the first layer to merge, layers below it are kept
+}
+
 // ImageRemoveOptions holds parameters to remove images.
 type ImageRemoveOptions struct {
 	Force         bool
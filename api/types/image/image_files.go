@@ -0,0 +1,29 @@
+package image // import "github.com/docker/docker/api/types/image"
+
+import (
+	"os"
+	"time"
+)
+
+// DiffResponseItem is a path that differs between two images, in response
+// to the ImageDiff operation. Kind has the same values as for container
+// changes: 0 for modified, 1 for added and 2 for deleted paths.
+type DiffResponseItem struct {
+	Kind uint8
+	Path string
+
+	// Size and Digest are set for regular files. For deleted files they
+	// describe the file as it was in the image the diff is taken from.
+	Size   int64  `json:",omitempty"`
+	Digest string `json:",omitempty"`
+}
+
+// FileInfo describes a file in the filesystem of an image, in response to
+// the ImageFiles operation.
+type FileInfo struct {
+	Name       string
+	Size       int64
+	Mode       os.FileMode
+	ModTime    time.Time
+	LinkTarget string `json:",omitempty"`
+}
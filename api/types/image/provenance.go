@@ -0,0 +1,47 @@
+package image // import "github.com/docker/docker/api/types/image"
+
+import "time"
+
+// ProvenanceMediaType is the media type of provenance documents pushed to
+// registries alongside images.
+const ProvenanceMediaType = "application/vnd.docker.build.provenance.v1+json"
+
+// Provenance records how an image was built.
+type Provenance struct {
+	// Builder is the builder that built the image, "classic" or "buildkit".
+	Builder string
+
+	// Dockerfile is the path of the Dockerfile in the build context, and
+	// DockerfileDigest the digest of its content, if known.
+	Dockerfile       string `json:",omitempty"`
+	DockerfileDigest string `json:",omitempty"`
+
+	Target string `json:",omitempty"`
+
+	// BuildArgs are the build args passed to the build. The predefined
+	// proxy args are omitted as they may contain credentials.
+	BuildArgs map[string]string `json:",omitempty"`
+
+	Context    *ProvenanceContext    `json:",omitempty"`
+	BaseImages []ProvenanceBaseImage `json:",omitempty"`
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// ProvenanceContext describes the build context of a build.
+type ProvenanceContext struct {
+	// Type is "archive" for uploaded contexts, "session" for contexts
+	// synced from the client, "git" or "url".
+	Type string
+
+	URL    string `json:",omitempty"`
+	Commit string `json:",omitempty"`
+}
+
+// ProvenanceBaseImage is an image a build used as the base of a stage, with
+// the digest of its configuration (its ID).
+type ProvenanceBaseImage struct {
+	Name   string
+	Digest string
+}
@@ -17,13 +17,16 @@ const (
 	TypeTmpfs Type = "tmpfs"
 	// TypeNamedPipe is the type for mounting Windows named pipes
 	TypeNamedPipe Type = "npipe"
+	// TypeImage is the type for mounting the filesystem of an image read-only
+	TypeImage Type = "image"
 )
 
 // Mount represents a mount (volume).
 type Mount struct {
 	Type Type `json:",omitempty"`
 	// Source specifies the name of the mount. Depending on mount type, this
-	// may be a volume name or a host path, or even ignored.
+	// may be a volume name, a host path or an image name or ID, or even
+	// ignored.
 	// Source is not supported for tmpfs (must be an empty value)
 	Source      string      `json:",omitempty"`
 	Target      string      `json:",omitempty"`
@@ -88,6 +91,10 @@ type VolumeOptions struct {
 	NoCopy       bool              `json:",omitempty"`
 	Labels       map[string]string `json:",omitempty"`
 	DriverConfig *Driver           `json:",omitempty"`
+	// Subpath is the path of a directory inside the volume to mount,
+	// instead of the root of the volume. It must be a relative path,
+	// and must exist in the volume.
+	Subpath string `json:",omitempty"`
 }
 
 // Driver represents a volume driver.
@@ -158,6 +158,9 @@ type Stats struct {
 	// Linux specific stats, not populated on Windows.
 	PidsStats  PidsStats  `json:"pids_stats,omitempty"`
 	BlkioStats BlkioStats `json:"blkio_stats,omitempty"`
+	// WritableLayerBytes is the disk usage of the writable layer of the
+	// container, when the storage driver accounts for it.
+	WritableLayerBytes uint64 `json:"writable_layer_bytes,omitempty"`
 
 	// Windows specific stats, not populated on Linux.
 	NumProcs     uint32       `json:"num_procs"`
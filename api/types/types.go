@@ -10,6 +10,7 @@ import (
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/api/types/registry"
@@ -46,6 +47,10 @@ type ImageInspect struct {
 	GraphDriver     GraphDriverData
 	RootFS          RootFS
 	Metadata        ImageMetadata
+
+	// Provenance records how the image was built, for images built by
+	// the daemon.
+	Provenance *image.Provenance `json:",omitempty"`
 }
 
 // ImageMetadata contains engine-local data about the image
@@ -520,6 +525,33 @@ type DiskUsage struct {
 	BuilderSize int64 // deprecated
 }
 
+// FsckOptions holds parameters to check the integrity of the layer store.
+type FsckOptions struct {
+	// Quarantine moves corrupt layers, and the layers on top of them, out
+	// of the layer store, so that the images using them can be pulled again.
+	Quarantine bool
+}
+
+// FsckProblem is a problem found by checking the integrity of the layer
+// store.
+type FsckProblem struct {
+	// Type is one of "corrupt-layer", "dangling-mount" or
+	// "orphaned-driver-dir".
+	Type    string
+	ID      string
+	Message string
+}
+
+// FsckReport contains the response for Engine API:
+// POST "/system/fsck"
+type FsckReport struct {
+	LayersChecked      int
+	Problems           []FsckProblem
+	QuarantinedLayers  []string
+	AffectedImages     []string
+	AffectedContainers []string
+}
+
 // ContainersPruneReport contains the response for Engine API:
 // POST "/containers/prune"
 type ContainersPruneReport struct {
@@ -592,6 +624,60 @@ type BuildResult struct {
 	ID string
 }
 
+// BuildStep contains the progress of a step of a build by the classic
+// builder. It is emitted as an aux message with the ID "moby.build.step" once
+// the step completes, if ImageBuildOptions.ProgressEvents is set.
+type BuildStep struct {
+	// Step is the index of the step in the build, starting at 1, out of
+	// Total steps.
+	Step  int
+	Total int
+	// Stage is the name of the stage the step belongs to, or "stage-<n>"
+	// for unnamed stages.
+	Stage       string
+	Instruction string
+	// Cached is set if the result of the step was found in the build cache.
+	Cached    bool
+	Started   time.Time
+	Completed time.Time
+	// ImageID is the ID of the image resulting from the step.
+	ImageID string `json:",omitempty"`
+	// LayerSize is the size of the layer produced by the step, if any.
+	LayerSize int64 `json:",omitempty"`
+	// ContainerID is the ID of the container the step was run in, if any.
+	ContainerID string `json:",omitempty"`
+	// Error is set if the step failed.
+	Error string `json:",omitempty"`
+}
+
+// BuildQueueStatus contains the position of a build waiting to start, as
+// the daemon runs a limited number of builds at the same time. It is emitted
+// as an aux message with the ID "moby.build.queue" each time the position
+// changes.
+type BuildQueueStatus struct {
+	// Position is the position of the build in the queue, starting at 1
+	// for the next build to start.
+	Position int
+}
+
+// DockerfileLintFinding is a problem found in a Dockerfile by a lint rule.
+type DockerfileLintFinding struct {
+	// Rule is the name of the rule that found the problem.
+	Rule string
+	// Severity is one of "error", "warning" or "info".
+	Severity string
+	Message  string
+	// Line is the line of the instruction with the problem, starting at 1,
+	// or 0 if the problem is not about a single instruction.
+	Line int
+}
+
+// DockerfileLintReport contains the problems found in a Dockerfile, ordered
+// by line.
+type DockerfileLintReport struct {
+	Findings []DockerfileLintFinding
+}
+
 // BuildCache contains information about a build cache record
 type BuildCache struct {
 	ID          string
@@ -53,6 +53,12 @@ type Volume struct {
 // swagger:model VolumeUsageData
 type VolumeUsageData struct {
 
+	// Size limit of the volume (in bytes), for volumes created with the
+	// `"local"` volume driver and a `size` option. This field is omitted
+	// for volumes without a size limit.
+	//
+	Limit int64 `json:"Limit,omitempty"`
+
 	// The number of containers referencing this volume. This field
 	// is set to `-1` if the reference-count is not available.
 	//
@@ -0,0 +1,20 @@
+package containerimage
+
+import (
+	"context"
+
+	"github.com/docker/docker/builder"
+)
+
+type provenanceKeyT struct{}
+
+// WithProvenanceRecorder returns a context that makes the image source record
+// the images it resolves the config of as base images of the build in r.
+func WithProvenanceRecorder(ctx context.Context, r *builder.ProvenanceRecorder) context.Context {
+	return context.WithValue(ctx, provenanceKeyT{}, r)
+}
+
+func provenanceRecorderFromContext(ctx context.Context) *builder.ProvenanceRecorder {
+	r, _ := ctx.Value(provenanceKeyT{}).(*builder.ProvenanceRecorder)
+	return r
+}
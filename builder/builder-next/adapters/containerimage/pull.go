@@ -143,6 +143,17 @@ func (is *imageSource) resolveRemote(ctx context.Context, ref string, platform *
 }
 
 func (is *imageSource) ResolveImageConfig(ctx context.Context, ref string, opt gw.ResolveImageConfigOpt) (digest.Digest, []byte, error) {
+	dgst, dt, err := is.resolveImageConfig(ctx, ref, opt)
+	if err != nil {
+		return "", nil, err
+	}
+	if r := provenanceRecorderFromContext(ctx); r != nil {
+		r.AddBaseImage(ref, digest.FromBytes(dt))
+	}
+	return dgst, dt, nil
+}
+
+func (is *imageSource) resolveImageConfig(ctx context.Context, ref string, opt gw.ResolveImageConfigOpt) (digest.Digest, []byte, error) {
 	resolveMode, err := source.ParseImageResolveMode(opt.ResolveMode)
 	if err != nil {
 		return "", nil, err
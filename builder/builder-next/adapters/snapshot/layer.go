@@ -12,6 +12,16 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
+// GetDiffIDs returns the diff chain of the layer committed for key, or nil if
+// no layer has been committed for it yet.
+func (s *snapshotter) GetDiffIDs(ctx context.Context, key string) ([]layer.DiffID, error) {
+	l, err := s.getLayer(key, true)
+	if err != nil || l == nil {
+		return nil, err
+	}
+	return getDiffChain(l), nil
+}
+
 func (s *snapshotter) EnsureLayer(ctx context.Context, key string) ([]layer.DiffID, error) {
 	if l, err := s.getLayer(key, true); err != nil {
 		return nil, err
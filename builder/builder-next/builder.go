@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,8 +15,12 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/builder-next/adapters/containerimage"
+	containerimageexp "github.com/docker/docker/builder/builder-next/exporter"
+	"github.com/docker/docker/builder/dockerfile"
 	"github.com/docker/docker/daemon/config"
 	"github.com/docker/docker/daemon/images"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/docker/docker/pkg/system"
 	"github.com/docker/libnetwork"
@@ -62,6 +67,15 @@ var cacheFields = map[string]bool{
 	"immutable": false,
 }
 
+// outputTypes are the types of build outputs. Outputs other than "moby",
+// the image store, are sent to the client through the build session.
+var outputTypes = map[string]bool{
+	"moby":  true,
+	"local": true,
+	"tar":   true,
+	"oci":   true,
+}
+
 func init() {
 	llbsolver.AllowNetworkHostUnstable = true
 }
@@ -82,6 +96,8 @@ type Builder struct {
 	controller     *control.Controller
 	reqBodyHandler *reqBodyHandler
 
+	queue *dockerfile.BuildQueue
+
 	mu   sync.Mutex
 	jobs map[string]*buildJob
 }
@@ -97,6 +113,7 @@ func New(opt Opt) (*Builder, error) {
 	b := &Builder{
 		controller:     c,
 		reqBodyHandler: reqHandler,
+		queue:          dockerfile.NewBuildQueue("buildkit", opt.BuilderConfig.MaxConcurrentBuilds.BuildKit),
 		jobs:           map[string]*buildJob{},
 	}
 	return b, nil
@@ -235,10 +252,19 @@ func (b *Builder) Build(ctx context.Context, opt backend.BuildConfig) (*builder.
 		}()
 	}
 
+	release, err := b.queue.Acquire(ctx, dockerfile.QueuePositionReporter(opt.ProgressWriter))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	var out builder.Result
 
 	id := identity.NewID()
 
+	provenance := builder.NewProvenanceRecorder("buildkit", opt.Options)
+	ctx = containerimage.WithProvenanceRecorder(ctx, provenance)
+
 	frontendAttrs := map[string]string{}
 
 	if opt.Options.Target != "" {
@@ -311,15 +337,38 @@ func (b *Builder) Build(ctx context.Context, opt backend.BuildConfig) (*builder.
 	}
 	frontendAttrs["add-hosts"] = extraHosts
 
+	exporterName := "moby"
 	exporterAttrs := map[string]string{}
 
-	if len(opt.Options.Tags) > 0 {
+	switch len(opt.Options.Outputs) {
+	case 0:
+	case 1:
+		exporterName = opt.Options.Outputs[0].Type
+		if !outputTypes[exporterName] {
+			return nil, errdefs.InvalidParameter(errors.Errorf("unsupported output type %q", exporterName))
+		}
+		for k, v := range opt.Options.Outputs[0].Attrs {
+			exporterAttrs[k] = v
+		}
+	default:
+		return nil, errdefs.InvalidParameter(errors.New("multiple outputs are not supported"))
+	}
+
+	sourceDateEpoch, err := builder.SourceDateEpoch(opt.Options)
+	if err != nil {
+		return nil, err
+	}
+	if sourceDateEpoch != nil && (exporterName == "moby" || exporterName == "oci") {
+		exporterAttrs[containerimageexp.KeySourceDateEpoch] = strconv.FormatInt(sourceDateEpoch.Unix(), 10)
+	}
+
+	if _, ok := exporterAttrs["name"]; !ok && len(opt.Options.Tags) > 0 && (exporterName == "moby" || exporterName == "oci") {
 		exporterAttrs["name"] = strings.Join(opt.Options.Tags, ",")
 	}
 
 	req := &controlapi.SolveRequest{
 		Ref:           id,
-		Exporter:      "moby",
+		Exporter:      exporterName,
 		ExporterAttrs: exporterAttrs,
 		Frontend:      "dockerfile.v0",
 		FrontendAttrs: frontendAttrs,
@@ -330,6 +379,32 @@ func (b *Builder) Build(ctx context.Context, opt backend.BuildConfig) (*builder.
 		req.Entitlements = append(req.Entitlements, entitlements.EntitlementNetworkHost)
 	}
 
+	cacheExport, err := parseCacheTo(opt.Options.CacheTo)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		inlineCache         *containerimageexp.InlineCache
+		inlineCacheExporter *inlineCacheExporter
+	)
+	if cacheExport != nil {
+		if cacheExport.typ == cacheExportInline {
+			if exporterName != "moby" {
+				return nil, errdefs.InvalidParameter(errors.New("inline cache is only supported when exporting to the image store"))
+			}
+			inlineCache = &containerimageexp.InlineCache{}
+			inlineCacheExporter = newInlineCacheExporter()
+			ctx = containerimageexp.WithInlineCache(ctx, inlineCache)
+			ctx = withInlineCacheExporter(ctx, inlineCacheExporter)
+			req.Cache.ExportRef = inlineCacheExportRef
+		} else {
+			req.Cache.ExportRef = cacheExport.ref
+			if cacheExport.mode != "" {
+				req.Cache.ExportAttrs = map[string]string{"mode": cacheExport.mode}
+			}
+		}
+	}
+
 	aux := streamformatter.AuxFormatter{Writer: opt.ProgressWriter.Output}
 
 	eg, ctx := errgroup.WithContext(ctx)
@@ -339,11 +414,19 @@ func (b *Builder) Build(ctx context.Context, opt backend.BuildConfig) (*builder.
 		if err != nil {
 			return err
 		}
+		if exporterName != "moby" {
+			return nil
+		}
 		id, ok := resp.ExporterResponse["containerimage.digest"]
-		if !ok {
+		if inlineCache != nil {
+			if id, err = commitInlineCache(ctx, inlineCache, inlineCacheExporter); err != nil {
+				return err
+			}
+		} else if !ok {
 			return errors.Errorf("missing image id")
 		}
 		out.ImageID = id
+		out.Provenance = provenance.Provenance()
 		return aux.Emit("moby.image.id", types.BuildResult{ID: id})
 	})
 
@@ -0,0 +1,193 @@
+package buildkit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+
+	containerimageexp "github.com/docker/docker/builder/builder-next/exporter"
+	"github.com/docker/docker/errdefs"
+	"github.com/moby/buildkit/cache/remotecache"
+	registryremotecache "github.com/moby/buildkit/cache/remotecache/registry"
+	v1 "github.com/moby/buildkit/cache/remotecache/v1"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/util/resolver"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	cacheExportRegistry = "registry"
+	cacheExportInline   = "inline"
+
+	// inlineCacheExportRef is the cache export reference passed to the
+	// controller for inline cache. The controller only resolves a cache
+	// exporter if a reference is set, but the inline exporter ignores it.
+	inlineCacheExportRef = "moby-inline-cache"
+)
+
+type cacheExportOpt struct {
+	typ  string
+	ref  string
+	mode string
+}
+
+// parseCacheTo parses the cache export options of a build, in the
+// "type=registry,ref=example.com/foo:cache,mode=max" or "type=inline" format.
+// A single cache export is supported.
+func parseCacheTo(cacheTo []string) (*cacheExportOpt, error) {
+	if len(cacheTo) == 0 {
+		return nil, nil
+	}
+	if len(cacheTo) > 1 {
+		return nil, errdefs.InvalidParameter(errors.New("only one cache export is supported"))
+	}
+
+	fields, err := csv.NewReader(strings.NewReader(cacheTo[0])).Read()
+	if err != nil {
+		return nil, errdefs.InvalidParameter(errors.Wrapf(err, "invalid cache export %q", cacheTo[0]))
+	}
+	opt := &cacheExportOpt{}
+	for _, field := range fields {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, errdefs.InvalidParameter(errors.Errorf("invalid cache export field %q, must be a key=value pair", field))
+		}
+		switch key, value := strings.ToLower(parts[0]), parts[1]; key {
+		case "type":
+			opt.typ = value
+		case "ref":
+			opt.ref = value
+		case "mode":
+			if value != "min" && value != "max" {
+				return nil, errdefs.InvalidParameter(errors.Errorf("invalid cache export mode %q", value))
+			}
+			opt.mode = value
+		default:
+			return nil, errdefs.InvalidParameter(errors.Errorf("unexpected cache export key %q", key))
+		}
+	}
+
+	switch opt.typ {
+	case "", cacheExportRegistry:
+		if opt.ref == "" {
+			return nil, errdefs.InvalidParameter(errors.New("registry cache export requires a ref"))
+		}
+		opt.typ = cacheExportRegistry
+	case cacheExportInline:
+		if opt.ref != "" {
+			return nil, errdefs.InvalidParameter(errors.New("inline cache export does not take a ref"))
+		}
+		if opt.mode == "max" {
+			return nil, errdefs.InvalidParameter(errors.New("inline cache export only supports mode=min"))
+		}
+	default:
+		return nil, errdefs.InvalidParameter(errors.Errorf("unsupported cache export type %q", opt.typ))
+	}
+	return opt, nil
+}
+
+type inlineCacheExporterKey struct{}
+
+func withInlineCacheExporter(ctx context.Context, e *inlineCacheExporter) context.Context {
+	return context.WithValue(ctx, inlineCacheExporterKey{}, e)
+}
+
+// resolveCacheExporterFunc returns the inline cache exporter of the build if
+// it has one, and a registry cache exporter otherwise. Registry credentials
+// are requested through the session of the build.
+func resolveCacheExporterFunc(sm *session.Manager, resolverOpt resolver.ResolveOptionsFunc) remotecache.ResolveCacheExporterFunc {
+	registry := registryremotecache.ResolveCacheExporterFunc(sm, resolverOpt)
+	return func(ctx context.Context, typ, ref string) (remotecache.Exporter, error) {
+		if e, ok := ctx.Value(inlineCacheExporterKey{}).(*inlineCacheExporter); ok {
+			return e, nil
+		}
+		return registry(ctx, typ, ref)
+	}
+}
+
+// inlineCacheExporter collects the build cache so that it can be embedded
+// in the config of the exported image.
+type inlineCacheExporter struct {
+	*v1.CacheChains
+	config *v1.CacheConfig
+	descs  v1.DescriptorProvider
+}
+
+func newInlineCacheExporter() *inlineCacheExporter {
+	return &inlineCacheExporter{CacheChains: v1.NewCacheChains()}
+}
+
+func (e *inlineCacheExporter) Finalize(ctx context.Context) error {
+	config, descs, err := e.CacheChains.Marshal()
+	if err != nil {
+		return err
+	}
+	e.config, e.descs = config, descs
+	return nil
+}
+
+// cacheForLayers returns the cache records that have results in the image
+// with the given layers, with the result layers replaced by indexes in the
+// layers of the image. It returns nil if no record matches the image.
+func (e *inlineCacheExporter) cacheForLayers(layers []digest.Digest) ([]byte, error) {
+	if e.config == nil {
+		return nil, nil
+	}
+
+	index := make(map[int]int)
+	var layerIndex func(int) int
+	layerIndex = func(i int) int {
+		if n, ok := index[i]; ok {
+			return n
+		}
+		index[i] = -1
+		l := e.config.Layers[i]
+		n := 0
+		if l.ParentIndex != -1 {
+			if n = layerIndex(l.ParentIndex); n == -1 {
+				return -1
+			}
+			n++
+		}
+		desc := e.descs[l.Blob].Descriptor
+		if n < len(layers) && desc.Annotations["containerd.io/uncompressed"] == layers[n].String() {
+			index[i] = n
+		}
+		return index[i]
+	}
+
+	var matched bool
+	records := make([]v1.CacheRecord, len(e.config.Records))
+	for i, r := range e.config.Records {
+		records[i] = v1.CacheRecord{Digest: r.Digest, Inputs: r.Inputs}
+		for _, res := range r.Results {
+			if n := layerIndex(res.LayerIndex); n != -1 {
+				res.LayerIndex = n
+				records[i].Results = append(records[i].Results, res)
+				matched = true
+			}
+		}
+	}
+	if !matched {
+		logrus.Warn("build cache does not match any layer of the exported image")
+		return nil, nil
+	}
+	return json.Marshal(records)
+}
+
+// commitInlineCache writes the image held back by ic with the cache
+// collected by e embedded in its config.
+func commitInlineCache(ctx context.Context, ic *containerimageexp.InlineCache, e *inlineCacheExporter) (string, error) {
+	cache, err := e.cacheForLayers(ic.Layers())
+	if err != nil {
+		return "", err
+	}
+	id, err := ic.Commit(ctx, cache)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
@@ -0,0 +1,86 @@
+package buildkit
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/moby/buildkit/cache/remotecache/v1"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestParseCacheTo(t *testing.T) {
+	opt, err := parseCacheTo(nil)
+	assert.NilError(t, err)
+	assert.Check(t, opt == nil)
+
+	opt, err = parseCacheTo([]string{"type=registry,ref=example.com/foo:cache,mode=max"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(cacheExportOpt{typ: cacheExportRegistry, ref: "example.com/foo:cache", mode: "max"}, *opt))
+
+	opt, err = parseCacheTo([]string{"ref=example.com/foo:cache"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(cacheExportRegistry, opt.typ))
+
+	opt, err = parseCacheTo([]string{"type=inline"})
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(cacheExportInline, opt.typ))
+
+	for _, invalid := range []string{
+		"type=registry",
+		"type=inline,mode=max",
+		"type=inline,ref=example.com/foo:cache",
+		"type=local,dest=/tmp",
+		"ref=example.com/foo:cache,mode=all",
+		"ref",
+	} {
+		_, err := parseCacheTo([]string{invalid})
+		assert.Check(t, err != nil, invalid)
+	}
+	_, err = parseCacheTo([]string{"type=inline", "ref=example.com/foo:cache"})
+	assert.Check(t, is.ErrorContains(err, "only one"))
+}
+
+func TestInlineCacheForLayers(t *testing.T) {
+	diffIDs := []digest.Digest{digest.FromString("base"), digest.FromString("app"), digest.FromString("other")}
+	blob := func(diffID digest.Digest) v1.DescriptorProviderPair {
+		return v1.DescriptorProviderPair{Descriptor: ocispec.Descriptor{
+			Digest:      digest.FromString("gzip " + diffID.String()),
+			Annotations: map[string]string{"containerd.io/uncompressed": diffID.String()},
+		}}
+	}
+	descs := v1.DescriptorProvider{}
+	for _, d := range diffIDs {
+		descs[blob(d).Descriptor.Digest] = blob(d)
+	}
+
+	e := newInlineCacheExporter()
+	e.config = &v1.CacheConfig{
+		Layers: []v1.CacheLayer{
+			{Blob: blob(diffIDs[0]).Descriptor.Digest, ParentIndex: -1},
+			{Blob: blob(diffIDs[2]).Descriptor.Digest, ParentIndex: 0},
+			{Blob: blob(diffIDs[1]).Descriptor.Digest, ParentIndex: 0},
+		},
+		Records: []v1.CacheRecord{
+			{Digest: digest.FromString("from"), Results: []v1.CacheResult{{LayerIndex: 0}}},
+			{Digest: digest.FromString("run other"), Results: []v1.CacheResult{{LayerIndex: 1}}, Inputs: [][]v1.CacheInput{{{LinkIndex: 0}}}},
+			{Digest: digest.FromString("run app"), Results: []v1.CacheResult{{LayerIndex: 2}}, Inputs: [][]v1.CacheInput{{{LinkIndex: 0}}}},
+		},
+	}
+	e.descs = descs
+
+	dt, err := e.cacheForLayers(diffIDs[:2])
+	assert.NilError(t, err)
+	var records []v1.CacheRecord
+	assert.NilError(t, json.Unmarshal(dt, &records))
+	assert.Assert(t, is.Len(records, 3))
+	assert.Check(t, is.DeepEqual([]v1.CacheResult{{LayerIndex: 0}}, records[0].Results))
+	assert.Check(t, is.Len(records[1].Results, 0))
+	assert.Check(t, is.DeepEqual([]v1.CacheResult{{LayerIndex: 1}}, records[2].Results))
+
+	dt, err = e.cacheForLayers([]digest.Digest{digest.FromString("unrelated")})
+	assert.NilError(t, err)
+	assert.Check(t, dt == nil)
+}
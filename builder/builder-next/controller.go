@@ -10,6 +10,7 @@ import (
 	"github.com/docker/docker/builder/builder-next/adapters/containerimage"
 	"github.com/docker/docker/builder/builder-next/adapters/snapshot"
 	containerimageexp "github.com/docker/docker/builder/builder-next/exporter"
+	localexporter "github.com/docker/docker/builder/builder-next/exporter/local"
 	"github.com/docker/docker/builder/builder-next/imagerefchecker"
 	mobyworker "github.com/docker/docker/builder/builder-next/worker"
 	"github.com/docker/docker/daemon/config"
@@ -74,6 +75,11 @@ func newController(rt http.RoundTripper, opt Opt) (*control.Controller, error) {
 		MetadataStore: md,
 	})
 
+	layers, ok := sbase.(mobyworker.LayerAccess)
+	if !ok {
+		return nil, errors.Errorf("snapshotter does not implement LayerAccess")
+	}
+
 	layerGetter, ok := sbase.(imagerefchecker.LayerGetter)
 	if !ok {
 		return nil, errors.Errorf("snapshotter does not implement layergetter")
@@ -120,6 +126,30 @@ func newController(rt http.RoundTripper, opt Opt) (*control.Controller, error) {
 	exp, err := containerimageexp.New(containerimageexp.Opt{
 		ImageStore:     dist.ImageStore,
 		ReferenceStore: dist.ReferenceStore,
+		LayerStore:     dist.LayerStore,
+		Differ:         differ,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	localExp, err := localexporter.New(localexporter.Opt{
+		SessionManager: opt.SessionManager,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tarExp, err := localexporter.NewTar(localexporter.Opt{
+		SessionManager: opt.SessionManager,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ociExp, err := containerimageexp.NewOCI(containerimageexp.OCIOpt{
+		SessionManager: opt.SessionManager,
+		LayerStore:     dist.LayerStore,
 		Differ:         differ,
 	})
 	if err != nil {
@@ -149,9 +179,14 @@ func newController(rt http.RoundTripper, opt Opt) (*control.Controller, error) {
 		DownloadManager:   dist.DownloadManager,
 		V2MetadataService: dist.V2MetadataService,
 		Exporters: map[string]exporter.Exporter{
-			"moby": exp,
+			"moby":  exp,
+			"local": localExp,
+			"tar":   tarExp,
+			"oci":   ociExp,
 		},
-		Transport: rt,
+		Transport:  rt,
+		Layers:     layers,
+		LayerStore: dist.LayerStore,
 	}
 
 	wc := &worker.Controller{}
@@ -172,7 +207,7 @@ func newController(rt http.RoundTripper, opt Opt) (*control.Controller, error) {
 		Frontends:                frontends,
 		CacheKeyStorage:          cacheStorage,
 		ResolveCacheImporterFunc: registryremotecache.ResolveCacheImporterFunc(opt.SessionManager, opt.ResolverOpt),
-		// TODO: set ResolveCacheExporterFunc for exporting cache
+		ResolveCacheExporterFunc: resolveCacheExporterFunc(opt.SessionManager, opt.ResolverOpt),
 	})
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	distref "github.com/docker/distribution/reference"
 	"github.com/docker/docker/image"
@@ -30,6 +31,7 @@ type Differ interface {
 type Opt struct {
 	ImageStore     image.Store
 	ReferenceStore reference.Store
+	LayerStore     layer.Store
 	Differ         Differ
 }
 
@@ -44,7 +46,7 @@ func New(opt Opt) (exporter.Exporter, error) {
 }
 
 func (e *imageExporter) Resolve(ctx context.Context, opt map[string]string) (exporter.ExporterInstance, error) {
-	i := &imageExporterInstance{imageExporter: e}
+	i := &imageExporterInstance{imageExporter: e, inlineCache: inlineCacheFromContext(ctx)}
 	for k, v := range opt {
 		switch k {
 		case keyImageName:
@@ -60,6 +62,12 @@ func (e *imageExporter) Resolve(ctx context.Context, opt map[string]string) (exp
 				i.meta = make(map[string][]byte)
 			}
 			i.meta[k] = []byte(v)
+		case KeySourceDateEpoch:
+			epoch, err := parseSourceDateEpoch(v)
+			if err != nil {
+				return nil, err
+			}
+			i.sourceDateEpoch = epoch
 		default:
 			logrus.Warnf("image exporter: unknown option %s", k)
 		}
@@ -69,8 +77,10 @@ func (e *imageExporter) Resolve(ctx context.Context, opt map[string]string) (exp
 
 type imageExporterInstance struct {
 	*imageExporter
-	targetNames []distref.Named
-	meta        map[string][]byte
+	targetNames     []distref.Named
+	meta            map[string][]byte
+	inlineCache     *InlineCache
+	sourceDateEpoch *time.Time
 }
 
 func (e *imageExporterInstance) Name() string {
@@ -78,14 +88,75 @@ func (e *imageExporterInstance) Name() string {
 }
 
 func (e *imageExporterInstance) Export(ctx context.Context, inp exporter.Source) (map[string]string, error) {
+	config, diffs, err := exportConfig(ctx, e.opt.Differ, inp, e.sourceDateEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	release := func() {}
+	if e.sourceDateEpoch != nil {
+		layersDone := oneOffProgress(ctx, "normalizing layer timestamps")
+		config, diffs, release, err = clampLayers(e.opt.LayerStore, config, diffs, *e.sourceDateEpoch)
+		if err != nil {
+			return nil, layersDone(err)
+		}
+		layersDone(nil)
+	}
+
+	if e.inlineCache != nil {
+		// the image is written when the cache metadata is committed
+		e.inlineCache.set(config, diffs, func(ctx context.Context, config []byte) (image.ID, error) {
+			defer release()
+			return e.commit(ctx, config)
+		})
+		return map[string]string{}, nil
+	}
+
+	defer release()
+	id, err := e.commit(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"containerimage.digest": id.String(),
+	}, nil
+}
+
+func (e *imageExporterInstance) commit(ctx context.Context, config []byte) (image.ID, error) {
+	configDigest := digest.FromBytes(config)
+
+	configDone := oneOffProgress(ctx, fmt.Sprintf("writing image %s", configDigest))
+	id, err := e.opt.ImageStore.Create(config)
+	if err != nil {
+		return "", configDone(err)
+	}
+	configDone(nil)
+
+	if e.opt.ReferenceStore != nil {
+		for _, targetName := range e.targetNames {
+			tagDone := oneOffProgress(ctx, "naming to "+targetName.String())
+
+			if err := e.opt.ReferenceStore.AddTag(targetName, digest.Digest(id), true); err != nil {
+				return "", tagDone(err)
+			}
+			tagDone(nil)
+		}
+	}
+	return id, nil
+}
 
+// exportConfig returns the config of the image exported from inp, and the
+// diff IDs of its layers. The layers are created if needed. If
+// sourceDateEpoch is set, the timestamps of the config are clamped to it.
+func exportConfig(ctx context.Context, differ Differ, inp exporter.Source, sourceDateEpoch *time.Time) ([]byte, []digest.Digest, error) {
 	if len(inp.Refs) > 1 {
-		return nil, fmt.Errorf("exporting multiple references to image store is currently unsupported")
+		return nil, nil, fmt.Errorf("exporting multiple references to an image is currently unsupported")
 	}
 
 	ref := inp.Ref
 	if ref != nil && len(inp.Refs) == 1 {
-		return nil, fmt.Errorf("invalid exporter input: Ref and Refs are mutually exclusive")
+		return nil, nil, fmt.Errorf("invalid exporter input: Ref and Refs are mutually exclusive")
 	}
 
 	// only one loop
@@ -100,14 +171,14 @@ func (e *imageExporterInstance) Export(ctx context.Context, inp exporter.Source)
 	case 1:
 		platformsBytes, ok := inp.Metadata[exptypes.ExporterPlatformsKey]
 		if !ok {
-			return nil, fmt.Errorf("cannot export image, missing platforms mapping")
+			return nil, nil, fmt.Errorf("cannot export image, missing platforms mapping")
 		}
 		var p exptypes.Platforms
 		if err := json.Unmarshal(platformsBytes, &p); err != nil {
-			return nil, errors.Wrapf(err, "failed to parse platforms passed to exporter")
+			return nil, nil, errors.Wrapf(err, "failed to parse platforms passed to exporter")
 		}
 		if len(p.Platforms) != len(inp.Refs) {
-			return nil, errors.Errorf("number of platforms does not match references %d %d", len(p.Platforms), len(inp.Refs))
+			return nil, nil, errors.Errorf("number of platforms does not match references %d %d", len(p.Platforms), len(inp.Refs))
 		}
 		config = inp.Metadata[fmt.Sprintf("%s/%s", exptypes.ExporterImageConfigKey, p.Platforms[0].ID)]
 	}
@@ -117,12 +188,12 @@ func (e *imageExporterInstance) Export(ctx context.Context, inp exporter.Source)
 		layersDone := oneOffProgress(ctx, "exporting layers")
 
 		if err := ref.Finalize(ctx, true); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		diffIDs, err := e.opt.Differ.EnsureLayer(ctx, ref.ID())
+		diffIDs, err := differ.EnsureLayer(ctx, ref.ID())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		diffs = make([]digest.Digest, len(diffIDs))
@@ -137,43 +208,28 @@ func (e *imageExporterInstance) Export(ctx context.Context, inp exporter.Source)
 		var err error
 		config, err = emptyImageConfig()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	history, err := parseHistoryFromConfig(config)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	diffs, history = normalizeLayersAndHistory(diffs, history, ref)
-
-	config, err = patchImageConfig(config, diffs, history)
-	if err != nil {
-		return nil, err
+	if sourceDateEpoch != nil {
+		clampHistory(history, *sourceDateEpoch)
 	}
 
-	configDigest := digest.FromBytes(config)
-
-	configDone := oneOffProgress(ctx, fmt.Sprintf("writing image %s", configDigest))
-	id, err := e.opt.ImageStore.Create(config)
+	config, err = patchImageConfig(config, diffs, history)
 	if err != nil {
-		return nil, configDone(err)
+		return nil, nil, err
 	}
-	configDone(nil)
-
-	if e.opt.ReferenceStore != nil {
-		for _, targetName := range e.targetNames {
-			tagDone := oneOffProgress(ctx, "naming to "+targetName.String())
-
-			if err := e.opt.ReferenceStore.AddTag(targetName, digest.Digest(id), true); err != nil {
-				return nil, tagDone(err)
-			}
-			tagDone(nil)
+	if sourceDateEpoch != nil {
+		if config, err = clampCreated(config, *sourceDateEpoch); err != nil {
+			return nil, nil, err
 		}
 	}
-
-	return map[string]string{
-		"containerimage.digest": id.String(),
-	}, nil
+	return config, diffs, nil
 }
@@ -0,0 +1,78 @@
+package containerimage
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/docker/docker/image"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// InlineCacheKey is the image config key the build cache metadata is
+// embedded under.
+const InlineCacheKey = "moby.buildkit.cache.v0"
+
+type inlineCacheKeyT struct{}
+
+// InlineCache holds back the image exported by a build until the build cache
+// metadata has been embedded in its config. The solver only exports the
+// cache after the image, so the image is written by Commit instead of by the
+// exporter.
+type InlineCache struct {
+	mu     sync.Mutex
+	config []byte
+	diffs  []digest.Digest
+	commit func(context.Context, []byte) (image.ID, error)
+}
+
+// WithInlineCache returns a context that makes the exporter hand the image
+// it exports over to ic.
+func WithInlineCache(ctx context.Context, ic *InlineCache) context.Context {
+	return context.WithValue(ctx, inlineCacheKeyT{}, ic)
+}
+
+func inlineCacheFromContext(ctx context.Context) *InlineCache {
+	ic, _ := ctx.Value(inlineCacheKeyT{}).(*InlineCache)
+	return ic
+}
+
+func (ic *InlineCache) set(config []byte, diffs []digest.Digest, commit func(context.Context, []byte) (image.ID, error)) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.config = config
+	ic.diffs = diffs
+	ic.commit = commit
+}
+
+// Layers returns the diff IDs of the exported image.
+func (ic *InlineCache) Layers() []digest.Digest {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	return ic.diffs
+}
+
+// Commit writes the exported image with cache embedded in its config, tags
+// it and returns its ID. If cache is empty, the image is written unchanged.
+func (ic *InlineCache) Commit(ctx context.Context, cache []byte) (image.ID, error) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	if ic.commit == nil {
+		return "", errors.New("no image was exported")
+	}
+
+	config := ic.config
+	if len(cache) > 0 {
+		m := map[string]json.RawMessage{}
+		if err := json.Unmarshal(config, &m); err != nil {
+			return "", errors.Wrap(err, "failed to parse image config")
+		}
+		m[InlineCacheKey] = cache
+		var err error
+		if config, err = json.Marshal(m); err != nil {
+			return "", errors.Wrap(err, "failed to marshal image config")
+		}
+	}
+	return ic.commit(ctx, config)
+}
@@ -0,0 +1,165 @@
+package local
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/docker/docker/pkg/archive"
+	"github.com/moby/buildkit/exporter"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/filesync"
+	"github.com/moby/buildkit/snapshot"
+	"github.com/moby/buildkit/util/progress"
+	"github.com/pkg/errors"
+	"github.com/tonistiigi/fsutil"
+)
+
+// Opt defines a struct for creating new local and tar exporters
+type Opt struct {
+	SessionManager *session.Manager
+}
+
+type localExporter struct {
+	opt Opt
+	tar bool
+}
+
+// New creates a new exporter that copies the build result to a directory
+// on the client, through the build session
+func New(opt Opt) (exporter.Exporter, error) {
+	return &localExporter{opt: opt}, nil
+}
+
+// NewTar creates a new exporter that sends the build result to the client
+// as a tar archive, through the build session
+func NewTar(opt Opt) (exporter.Exporter, error) {
+	return &localExporter{opt: opt, tar: true}, nil
+}
+
+func (e *localExporter) Resolve(ctx context.Context, opt map[string]string) (exporter.ExporterInstance, error) {
+	caller, err := sessionCaller(ctx, e.opt.SessionManager)
+	if err != nil {
+		return nil, err
+	}
+	return &localExporterInstance{localExporter: e, caller: caller}, nil
+}
+
+type localExporterInstance struct {
+	*localExporter
+	caller session.Caller
+}
+
+func (e *localExporterInstance) Name() string {
+	if e.tar {
+		return "exporting to client tarball"
+	}
+	return "exporting to client directory"
+}
+
+func (e *localExporterInstance) Export(ctx context.Context, inp exporter.Source) (map[string]string, error) {
+	if len(inp.Refs) > 0 {
+		return nil, errors.New("exporting multiple platforms to the client is currently unsupported")
+	}
+
+	var src string
+	if inp.Ref == nil {
+		// an empty result is exported as an empty directory
+		tmpDir, err := ioutil.TempDir("", "buildkit-export")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmpDir)
+		src = tmpDir
+	} else {
+		mountable, err := inp.Ref.Mount(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+		lm := snapshot.LocalMounter(mountable)
+		if src, err = lm.Mount(); err != nil {
+			return nil, err
+		}
+		defer lm.Unmount()
+	}
+
+	if !e.tar {
+		progress := newProgressHandler(ctx, "copying files")
+		return nil, filesync.CopyToCaller(ctx, fsutil.NewFS(src, nil), e.caller, progress)
+	}
+
+	w, err := filesync.CopyFileWriter(ctx, e.caller)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := archive.TarWithOptions(src, &archive.TarOptions{})
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+	defer rc.Close()
+
+	sendDone := oneOffProgress(ctx, "sending tarball")
+	if _, err := io.Copy(w, rc); err != nil {
+		w.Close()
+		return nil, sendDone(err)
+	}
+	return nil, sendDone(w.Close())
+}
+
+// sessionCaller returns the caller of the session the build was started with.
+func sessionCaller(ctx context.Context, sm *session.Manager) (session.Caller, error) {
+	id := session.FromContext(ctx)
+	if id == "" {
+		return nil, errors.New("exporting to the client requires a build session")
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	caller, err := sm.Get(timeoutCtx, id)
+	if err != nil {
+		return nil, err
+	}
+	return caller, nil
+}
+
+func newProgressHandler(ctx context.Context, id string) func(int, bool) {
+	limiter := time.Now()
+	pw, _, _ := progress.FromContext(ctx)
+	now := time.Now()
+	st := progress.Status{
+		Started: &now,
+		Action:  "transferring",
+	}
+	pw.Write(id, st)
+	return func(s int, last bool) {
+		if last || time.Since(limiter) > 100*time.Millisecond {
+			st.Current = s
+			if last {
+				now := time.Now()
+				st.Completed = &now
+			}
+			pw.Write(id, st)
+			limiter = time.Now()
+		}
+	}
+}
+
+func oneOffProgress(ctx context.Context, id string) func(err error) error {
+	pw, _, _ := progress.FromContext(ctx)
+	now := time.Now()
+	st := progress.Status{
+		Started: &now,
+	}
+	pw.Write(id, st)
+	return func(err error) error {
+		now := time.Now()
+		st.Completed = &now
+		pw.Write(id, st)
+		pw.Close()
+		return err
+	}
+}
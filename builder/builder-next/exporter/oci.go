@@ -0,0 +1,281 @@
+package containerimage
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	distref "github.com/docker/distribution/reference"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/moby/buildkit/exporter"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/filesync"
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// OCIOpt defines a struct for creating new OCI archive exporter
+type OCIOpt struct {
+	SessionManager *session.Manager
+	LayerStore     layer.Store
+	Differ         Differ
+}
+
+type ociExporter struct {
+	opt OCIOpt
+}
+
+// NewOCI creates a new exporter that sends the built image to the client as
+// an OCI image layout tar archive, through the build session
+func NewOCI(opt OCIOpt) (exporter.Exporter, error) {
+	return &ociExporter{opt: opt}, nil
+}
+
+func (e *ociExporter) Resolve(ctx context.Context, opt map[string]string) (exporter.ExporterInstance, error) {
+	i := &ociExporterInstance{ociExporter: e}
+	for k, v := range opt {
+		switch k {
+		case keyImageName:
+			for _, v := range strings.Split(v, ",") {
+				ref, err := distref.ParseNormalizedNamed(v)
+				if err != nil {
+					return nil, err
+				}
+				i.targetNames = append(i.targetNames, distref.TagNameOnly(ref))
+			}
+		case KeySourceDateEpoch:
+			epoch, err := parseSourceDateEpoch(v)
+			if err != nil {
+				return nil, err
+			}
+			i.sourceDateEpoch = epoch
+		default:
+			logrus.Warnf("oci exporter: unknown option %s", k)
+		}
+	}
+
+	id := session.FromContext(ctx)
+	if id == "" {
+		return nil, errors.New("exporting to the client requires a build session")
+	}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	caller, err := e.opt.SessionManager.Get(timeoutCtx, id)
+	if err != nil {
+		return nil, err
+	}
+	i.caller = caller
+	return i, nil
+}
+
+type ociExporterInstance struct {
+	*ociExporter
+	targetNames     []distref.Named
+	caller          session.Caller
+	sourceDateEpoch *time.Time
+}
+
+func (e *ociExporterInstance) Name() string {
+	return "exporting to oci image format"
+}
+
+func (e *ociExporterInstance) Export(ctx context.Context, inp exporter.Source) (map[string]string, error) {
+	config, diffs, err := exportConfig(ctx, e.opt.Differ, inp, e.sourceDateEpoch)
+	if err != nil {
+		return nil, err
+	}
+	if e.sourceDateEpoch != nil {
+		var release func()
+		if config, diffs, release, err = clampLayers(e.opt.LayerStore, config, diffs, *e.sourceDateEpoch); err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
+	tmpDir, err := ioutil.TempDir("", "buildkit-oci-export")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	layersDone := oneOffProgress(ctx, "compressing layers")
+	blobs := make([]*ociBlob, len(diffs))
+	for i := range diffs {
+		diffIDs := make([]layer.DiffID, i+1)
+		for j := range diffIDs {
+			diffIDs[j] = layer.DiffID(diffs[j])
+		}
+		if blobs[i], err = e.compressLayer(tmpDir, layer.CreateChainID(diffIDs)); err != nil {
+			return nil, layersDone(err)
+		}
+	}
+	layersDone(nil)
+
+	manifest := ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		},
+	}
+	for _, b := range blobs {
+		manifest.Layers = append(manifest.Layers, b.desc)
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	manifestDesc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageManifest,
+		Digest:    digest.FromBytes(manifestJSON),
+		Size:      int64(len(manifestJSON)),
+	}
+
+	index := ocispec.Index{Versioned: specs.Versioned{SchemaVersion: 2}}
+	if len(e.targetNames) == 0 {
+		index.Manifests = append(index.Manifests, manifestDesc)
+	}
+	for _, name := range e.targetNames {
+		desc := manifestDesc
+		desc.Annotations = map[string]string{
+			ocispec.AnnotationRefName: name.(distref.Tagged).Tag(),
+		}
+		index.Manifests = append(index.Manifests, desc)
+	}
+	indexJSON, err := json.Marshal(index)
+	if err != nil {
+		return nil, err
+	}
+	layoutJSON, err := json.Marshal(ocispec.ImageLayout{Version: ocispec.ImageLayoutVersion})
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := filesync.CopyFileWriter(ctx, e.caller)
+	if err != nil {
+		return nil, err
+	}
+	sendDone := oneOffProgress(ctx, "sending tarball")
+	tw := tar.NewWriter(w)
+	err = writeTarFile(tw, ocispec.ImageLayoutFile, layoutJSON)
+	written := make(map[digest.Digest]bool)
+	for _, b := range blobs {
+		if err == nil && !written[b.desc.Digest] {
+			err = writeTarBlob(tw, b)
+			written[b.desc.Digest] = true
+		}
+	}
+	if err == nil {
+		err = writeTarFile(tw, blobPath(manifest.Config.Digest), config)
+	}
+	if err == nil {
+		err = writeTarFile(tw, blobPath(manifestDesc.Digest), manifestJSON)
+	}
+	if err == nil {
+		err = writeTarFile(tw, "index.json", indexJSON)
+	}
+	if err == nil {
+		err = tw.Close()
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return nil, sendDone(err)
+}
+
+type ociBlob struct {
+	desc ocispec.Descriptor
+	path string
+}
+
+// compressLayer writes the compressed content of the layer chainID to a file
+// in dir.
+func (e *ociExporterInstance) compressLayer(dir string, chainID layer.ChainID) (*ociBlob, error) {
+	l, err := e.opt.LayerStore.Get(chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer layer.ReleaseAndLog(e.opt.LayerStore, l)
+
+	ts, err := l.TarStream()
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Close()
+
+	f, err := ioutil.TempFile(dir, "layer")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	digester := digest.Canonical.Digester()
+	compressed, err := archive.CompressStream(io.MultiWriter(f, digester.Hash()), archive.Gzip)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(compressed, ts); err != nil {
+		compressed.Close()
+		return nil, err
+	}
+	if err := compressed.Close(); err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &ociBlob{
+		desc: ocispec.Descriptor{
+			MediaType: ocispec.MediaTypeImageLayerGzip,
+			Digest:    digester.Digest(),
+			Size:      fi.Size(),
+		},
+		path: f.Name(),
+	}, nil
+}
+
+func blobPath(dgst digest.Digest) string {
+	return "blobs/" + dgst.Algorithm().String() + "/" + dgst.Hex()
+}
+
+func writeTarFile(tw *tar.Writer, name string, dt []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0444,
+		Size:     int64(len(dt)),
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(dt)
+	return err
+}
+
+func writeTarBlob(tw *tar.Writer, b *ociBlob) error {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     blobPath(b.desc.Digest),
+		Mode:     0444,
+		Size:     b.desc.Size,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
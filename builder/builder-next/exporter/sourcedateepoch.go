@@ -0,0 +1,166 @@
+package containerimage
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+)
+
+// KeySourceDateEpoch is the exporter option that clamps the timestamps of
+// the exported image to a number of seconds since the Unix epoch, so that
+// builds of the same sources produce the same image.
+const KeySourceDateEpoch = "source-date-epoch"
+
+func parseSourceDateEpoch(v string) (*time.Time, error) {
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec < 0 {
+		return nil, errors.Errorf("invalid %s %q", KeySourceDateEpoch, v)
+	}
+	epoch := time.Unix(sec, 0).UTC()
+	return &epoch, nil
+}
+
+func clampHistory(history []ocispec.History, epoch time.Time) {
+	for i, h := range history {
+		if h.Created != nil && h.Created.After(epoch) {
+			created := epoch
+			history[i].Created = &created
+		}
+	}
+}
+
+func clampCreated(dt []byte, epoch time.Time) ([]byte, error) {
+	m := map[string]json.RawMessage{}
+	if err := json.Unmarshal(dt, &m); err != nil {
+		return nil, errors.Wrap(err, "failed to parse image config for patch")
+	}
+	var created *time.Time
+	if v, ok := m["created"]; ok {
+		if err := json.Unmarshal(v, &created); err != nil {
+			return nil, errors.Wrap(err, "failed to parse creation time")
+		}
+	}
+	if created != nil && !created.After(epoch) {
+		return dt, nil
+	}
+	v, err := json.Marshal(epoch)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal creation time")
+	}
+	m["created"] = v
+	dt, err = json.Marshal(m)
+	return dt, errors.Wrap(err, "failed to marshal config after patch")
+}
+
+// clampLayers rewrites the layers of the image with the given config and
+// diff IDs so that no file in them is more recent than epoch, and returns the
+// config and diff IDs of the rewritten image. Layers are only rewritten from
+// the first one with a more recent file, so that the layers of base images
+// older than epoch are kept. release must be called once the rewritten
+// layers are referenced by an image, or no longer needed.
+func clampLayers(ls layer.Store, config []byte, diffs []digest.Digest, epoch time.Time) (_ []byte, _ []digest.Digest, release func(), err error) {
+	var layers []layer.Layer
+	release = func() {
+		for _, l := range layers {
+			layer.ReleaseAndLog(ls, l)
+		}
+	}
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
+
+	var (
+		diffIDs []layer.DiffID
+		parent  layer.ChainID
+		rewrite bool
+		clamped = make([]digest.Digest, len(diffs))
+	)
+	for i, dgst := range diffs {
+		diffIDs = append(diffIDs, layer.DiffID(dgst))
+		l, err := ls.Get(layer.CreateChainID(diffIDs))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if !rewrite {
+			if rewrite, err = hasFilesAfter(l, epoch); err != nil {
+				layer.ReleaseAndLog(ls, l)
+				return nil, nil, nil, err
+			}
+		}
+		if !rewrite {
+			clamped[i] = dgst
+			parent = l.ChainID()
+			layer.ReleaseAndLog(ls, l)
+			continue
+		}
+		nl, err := registerClamped(ls, l, parent, epoch)
+		layer.ReleaseAndLog(ls, l)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		layers = append(layers, nl)
+		clamped[i] = digest.Digest(nl.DiffID())
+		parent = nl.ChainID()
+	}
+
+	m := map[string]json.RawMessage{}
+	if err := json.Unmarshal(config, &m); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to parse image config for patch")
+	}
+	var rootFS ocispec.RootFS
+	rootFS.Type = "layers"
+	rootFS.DiffIDs = clamped
+	dt, err := json.Marshal(rootFS)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to marshal rootfs")
+	}
+	m["rootfs"] = dt
+	if config, err = json.Marshal(m); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to marshal config after patch")
+	}
+	return config, clamped, release, nil
+}
+
+// hasFilesAfter returns whether any file in the layer l has a timestamp
+// later than epoch.
+func hasFilesAfter(l layer.Layer, epoch time.Time) (bool, error) {
+	ts, err := l.TarStream()
+	if err != nil {
+		return false, err
+	}
+	defer ts.Close()
+
+	tr := tar.NewReader(ts)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if hdr.ModTime.After(epoch) || hdr.AccessTime.After(epoch) || hdr.ChangeTime.After(epoch) {
+			return true, nil
+		}
+	}
+}
+
+func registerClamped(ls layer.Store, l layer.Layer, parent layer.ChainID, epoch time.Time) (layer.Layer, error) {
+	ts, err := l.TarStream()
+	if err != nil {
+		return nil, err
+	}
+	clamped := archive.ClampTimestampsTarWrapper(ts, epoch)
+	defer clamped.Close()
+	return ls.Register(clamped, parent)
+}
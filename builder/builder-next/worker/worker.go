@@ -7,9 +7,12 @@ import (
 	"io/ioutil"
 	nethttp "net/http"
 	"runtime"
+	"sync"
 	"time"
 
 	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
 	"github.com/containerd/containerd/platforms"
 	"github.com/containerd/containerd/rootfs"
 	"github.com/docker/docker/distribution"
@@ -17,6 +20,7 @@ import (
 	"github.com/docker/docker/distribution/xfer"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	pkgprogress "github.com/docker/docker/pkg/progress"
 	"github.com/moby/buildkit/cache"
 	"github.com/moby/buildkit/cache/metadata"
@@ -58,6 +62,14 @@ type Opt struct {
 	DownloadManager   distribution.RootFSDownloadManager
 	V2MetadataService distmetadata.V2MetadataService
 	Transport         nethttp.RoundTripper
+	Layers            LayerAccess
+	LayerStore        layer.Store
+}
+
+// LayerAccess provides access to the moby layers of snapshots
+type LayerAccess interface {
+	GetDiffIDs(ctx context.Context, key string) ([]layer.DiffID, error)
+	EnsureLayer(ctx context.Context, key string) ([]layer.DiffID, error)
 }
 
 // Worker is a local worker instance with dedicated snapshotter, cache, and so on.
@@ -65,6 +77,9 @@ type Opt struct {
 type Worker struct {
 	Opt
 	SourceManager *source.Manager
+
+	mu    sync.Mutex
+	blobs map[layer.ChainID]ocispec.Descriptor
 }
 
 // NewWorker instantiates a local worker
@@ -112,6 +127,7 @@ func NewWorker(opt Opt) (*Worker, error) {
 	return &Worker{
 		Opt:           opt,
 		SourceManager: sm,
+		blobs:         make(map[layer.ChainID]ocispec.Descriptor),
 	}, nil
 }
 
@@ -201,7 +217,112 @@ func (w *Worker) Exporter(name string) (exporter.Exporter, error) {
 
 // GetRemote returns a remote snapshot reference for a local one
 func (w *Worker) GetRemote(ctx context.Context, ref cache.ImmutableRef, createIfNeeded bool) (*solver.Remote, error) {
-	return nil, errors.Errorf("getremote not implemented")
+	var (
+		diffIDs []layer.DiffID
+		err     error
+	)
+	if createIfNeeded {
+		if err := ref.Finalize(ctx, true); err != nil {
+			return nil, err
+		}
+		diffIDs, err = w.Layers.EnsureLayer(ctx, ref.ID())
+	} else {
+		diffIDs, err = w.Layers.GetDiffIDs(ctx, ref.ID())
+		if err == nil && diffIDs == nil {
+			err = errors.Errorf("no layer for %s", ref.ID())
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	descriptors := make([]ocispec.Descriptor, len(diffIDs))
+	for i := range diffIDs {
+		desc, err := w.ensureBlob(ctx, layer.CreateChainID(diffIDs[:i+1]))
+		if err != nil {
+			return nil, err
+		}
+		descriptors[i] = desc
+	}
+	return &solver.Remote{
+		Descriptors: descriptors,
+		Provider:    w.ContentStore,
+	}, nil
+}
+
+// ensureBlob returns the descriptor of a compressed blob for the layer
+// chainID, writing it to the content store if it is not there yet.
+func (w *Worker) ensureBlob(ctx context.Context, chainID layer.ChainID) (ocispec.Descriptor, error) {
+	w.mu.Lock()
+	desc, ok := w.blobs[chainID]
+	w.mu.Unlock()
+	if ok {
+		if _, err := w.ContentStore.Info(ctx, desc.Digest); err == nil {
+			return desc, nil
+		}
+	}
+
+	l, err := w.LayerStore.Get(chainID)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer layer.ReleaseAndLog(w.LayerStore, l)
+
+	ts, err := l.TarStream()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer ts.Close()
+
+	cw, err := content.OpenWriter(ctx, w.ContentStore, content.WithRef("blob-"+chainID.String()))
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer cw.Close()
+	if err := cw.Truncate(0); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	digester := digest.Canonical.Digester()
+	counter := &countingWriter{w: io.MultiWriter(cw, digester.Hash())}
+	compressed, err := archive.CompressStream(counter, archive.Gzip)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if _, err := io.Copy(compressed, ts); err != nil {
+		compressed.Close()
+		return ocispec.Descriptor{}, err
+	}
+	if err := compressed.Close(); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if err := cw.Commit(ctx, counter.n, digester.Digest()); err != nil && !errdefs.IsAlreadyExists(err) {
+		return ocispec.Descriptor{}, err
+	}
+
+	desc = ocispec.Descriptor{
+		MediaType: images.MediaTypeDockerSchema2LayerGzip,
+		Digest:    digester.Digest(),
+		Size:      counter.n,
+		Annotations: map[string]string{
+			"containerd.io/uncompressed": l.DiffID().String(),
+		},
+	}
+	w.mu.Lock()
+	w.blobs[chainID] = desc
+	w.mu.Unlock()
+	return desc, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
 
 // FromRemote converts a remote snapshot reference to a local one
@@ -7,10 +7,12 @@ package builder // import "github.com/docker/docker/builder"
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	imagetypes "github.com/docker/docker/api/types/image"
 	containerpkg "github.com/docker/docker/container"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
@@ -74,8 +76,9 @@ type ExecBackend interface {
 
 // Result is the output produced by a Builder
 type Result struct {
-	ImageID   string
-	FromImage Image
+	ImageID    string
+	FromImage  Image
+	Provenance *imagetypes.Provenance
 }
 
 // ImageCacheBuilder represents a generator for stateful image cache.
@@ -105,11 +108,17 @@ type ROLayer interface {
 	Release() error
 	NewRWLayer() (RWLayer, error)
 	DiffID() layer.DiffID
+	// DiffSize returns the size of the changes made by the layer to its
+	// parent.
+	DiffSize() (int64, error)
 }
 
 // RWLayer is active layer that can be read/modified
 type RWLayer interface {
 	Release() error
 	Root() containerfs.ContainerFS
-	Commit() (ROLayer, error)
+	// Commit creates a layer from the changes made to the RWLayer. If
+	// sourceDateEpoch is set, the timestamps of the files in the layer are
+	// clamped to it.
+	Commit(sourceDateEpoch *time.Time) (ROLayer, error)
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/docker/docker/builder"
 	"github.com/docker/docker/runconfig/opts"
 )
 
@@ -11,6 +12,7 @@ import (
 // these args are considered transparent and are excluded from the image history.
 // Filtering from history is implemented in dispatchers.go
 var builtinAllowedBuildArgs = map[string]bool{
+
 	"HTTP_PROXY":  true,
 	"http_proxy":  true,
 	"HTTPS_PROXY": true,
@@ -19,6 +21,8 @@ var builtinAllowedBuildArgs = map[string]bool{
 	"ftp_proxy":   true,
 	"NO_PROXY":    true,
 	"no_proxy":    true,
+
+	builder.SourceDateEpochBuildArg: true,
 }
 
 // BuildArgs manages arguments used by the builder
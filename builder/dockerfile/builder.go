@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/containerd/containerd/platforms"
@@ -17,18 +19,22 @@ import (
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/builder/fscache"
 	"github.com/docker/docker/builder/remotecontext"
+	"github.com/docker/docker/builder/remotecontext/git"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/streamformatter"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/pkg/system"
+	"github.com/docker/docker/pkg/urlutil"
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/moby/buildkit/frontend/dockerfile/parser"
 	"github.com/moby/buildkit/frontend/dockerfile/shell"
 	"github.com/moby/buildkit/session"
+	digest "github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
 	"golang.org/x/sync/syncmap"
 )
 
@@ -56,25 +62,50 @@ type SessionGetter interface {
 
 // BuildManager is shared across all Builder objects
 type BuildManager struct {
-	idMapping *idtools.IdentityMapping
-	backend   builder.Backend
-	pathCache pathCache // TODO: make this persistent
-	sg        SessionGetter
-	fsCache   *fscache.FSCache
+	idMapping   *idtools.IdentityMapping
+	backend     builder.Backend
+	pathCache   pathCache // TODO: make this persistent
+	sg          SessionGetter
+	fsCache     *fscache.FSCache
+	mountCache  *fscache.MountCache
+	gitMirrors  *git.Mirrors
+	parallelism int
+	queue       *BuildQueue
+
+	mu     sync.Mutex
+	builds map[string]context.CancelFunc // by build ID
 }
 
-// NewBuildManager creates a BuildManager
-func NewBuildManager(b builder.Backend, sg SessionGetter, fsCache *fscache.FSCache, identityMapping *idtools.IdentityMapping) (*BuildManager, error) {
+// NewBuildManager creates a BuildManager. Builds run at most parallelism of
+// their stages at the same time; if parallelism is not positive, the number
+// of CPUs is used. If gitMirrors is not nil, the contexts of git URLs are
+// fetched through it and kept in fsCache. At most maxBuilds builds run at the
+// same time, the others are queued; there is no limit if maxBuilds is not
+// positive.
+func NewBuildManager(b builder.Backend, sg SessionGetter, fsCache *fscache.FSCache, mountCache *fscache.MountCache, gitMirrors *git.Mirrors, identityMapping *idtools.IdentityMapping, parallelism, maxBuilds int) (*BuildManager, error) {
+	if parallelism <= 0 {
+		parallelism = runtime.NumCPU()
+	}
 	bm := &BuildManager{
-		backend:   b,
-		pathCache: &syncmap.Map{},
-		sg:        sg,
-		idMapping: identityMapping,
-		fsCache:   fsCache,
+		backend:     b,
+		pathCache:   &syncmap.Map{},
+		sg:          sg,
+		idMapping:   identityMapping,
+		fsCache:     fsCache,
+		mountCache:  mountCache,
+		gitMirrors:  gitMirrors,
+		parallelism: parallelism,
+		queue:       NewBuildQueue("classic", maxBuilds),
+		builds:      make(map[string]context.CancelFunc),
 	}
 	if err := fsCache.RegisterTransport(remotecontext.ClientSessionRemote, NewClientSessionTransport()); err != nil {
 		return nil, err
 	}
+	if gitMirrors != nil {
+		if err := fsCache.RegisterTransport(gitRemote, NewGitTransport(gitMirrors)); err != nil {
+			return nil, err
+		}
+	}
 	return bm, nil
 }
 
@@ -85,7 +116,28 @@ func (bm *BuildManager) Build(ctx context.Context, config backend.BuildConfig) (
 		config.Options.Dockerfile = builder.DefaultDockerfileName
 	}
 
-	source, dockerfile, err := remotecontext.Detect(config)
+	if buildID := config.Options.BuildID; buildID != "" {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		bm.mu.Lock()
+		bm.builds[buildID] = cancel
+		bm.mu.Unlock()
+		defer func() {
+			bm.mu.Lock()
+			delete(bm.builds, buildID)
+			bm.mu.Unlock()
+		}()
+	}
+
+	release, err := bm.queue.Acquire(ctx, QueuePositionReporter(config.ProgressWriter))
+	if err != nil {
+		buildsFailed.WithValues(metricsBuildCanceled).Inc()
+		return nil, err
+	}
+	defer release()
+
+	source, dockerfile, dockerfileDigest, err := bm.detectContext(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -108,16 +160,69 @@ func (bm *BuildManager) Build(ctx context.Context, config backend.BuildConfig) (
 
 	builderOptions := builderOptions{
 		Options:        config.Options,
-		ProgressWriter: config.ProgressWriter,
+		ProgressWriter: syncProgressWriter(config.ProgressWriter),
 		Backend:        bm.backend,
 		PathCache:      bm.pathCache,
 		IDMapping:      bm.idMapping,
+		SessionGetter:  bm.sg,
+		MountCache:     bm.mountCache,
+		Parallelism:    bm.parallelism,
 	}
 	b, err := newBuilder(ctx, builderOptions)
 	if err != nil {
 		return nil, err
 	}
-	return b.build(source, dockerfile)
+	result, err := b.build(source, dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	result.Provenance = b.provenance.Provenance()
+	result.Provenance.DockerfileDigest = dockerfileDigest.String()
+	if gs, ok := source.(interface{ GitCommit() string }); ok && result.Provenance.Context != nil {
+		result.Provenance.Context.Commit = gs.GitCommit()
+	}
+	return result, nil
+}
+
+// Cancel cancels the build with the given ID, whether it is running or
+// queued.
+func (bm *BuildManager) Cancel(ctx context.Context, id string) error {
+	bm.mu.Lock()
+	if cancel, ok := bm.builds[id]; ok {
+		cancel()
+	}
+	bm.mu.Unlock()
+	return nil
+}
+
+// detectContext returns the context and the Dockerfile of a build. Contexts
+// of git URLs are synced to the cache from the mirrors of their repositories,
+// so that builds of the same commit reuse them.
+func (bm *BuildManager) detectContext(ctx context.Context, config backend.BuildConfig) (builder.Source, *parser.Result, digest.Digest, error) {
+	if bm.gitMirrors == nil || !urlutil.IsGitURL(config.Options.RemoteContext) {
+		return remotecontext.Detect(config)
+	}
+	st := time.Now()
+	gsi, err := NewGitSourceIdentifier(bm.gitMirrors, config.Options.RemoteContext)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	src, err := bm.fsCache.SyncFrom(ctx, gsi)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	dockerfile, dgst, err := remotecontext.DockerfileFromSource(src, config.Options.Dockerfile)
+	if err != nil {
+		src.Close()
+		if err == remotecontext.ErrDockerfileIgnored {
+			// the cached context must not be modified, so fall back to
+			// a context of its own to remove the ignored files from
+			return remotecontext.Detect(config)
+		}
+		return nil, nil, "", err
+	}
+	logrus.Debugf("git-sync-time: %v", time.Since(st))
+	return &gitSource{Source: src, commit: gsi.commit}, dockerfile, dgst, nil
 }
 
 func (bm *BuildManager) initializeClientSession(ctx context.Context, cancel func(), options *types.ImageBuildOptions) (builder.Source, error) {
@@ -160,6 +265,9 @@ type builderOptions struct {
 	ProgressWriter backend.ProgressWriter
 	PathCache      pathCache
 	IDMapping      *idtools.IdentityMapping
+	SessionGetter  SessionGetter
+	MountCache     *fscache.MountCache
+	Parallelism    int
 }
 
 // Builder is a Dockerfile builder
@@ -182,6 +290,12 @@ type Builder struct {
 	containerManager *containerManager
 	imageProber      ImageProber
 	platform         *specs.Platform
+	sessionGetter    SessionGetter
+	mountCache       *fscache.MountCache
+	parallelism      int
+	provenance       *builder.ProvenanceRecorder
+	sourceDateEpoch  *time.Time
+	step             *types.BuildStep // progress of the current step, if requested
 }
 
 // newBuilder creates a new Dockerfile builder from an optional dockerfile and a Options.
@@ -204,7 +318,17 @@ func newBuilder(clientCtx context.Context, options builderOptions) (*Builder, er
 		pathCache:        options.PathCache,
 		imageProber:      newImageProber(options.Backend, config.CacheFrom, config.NoCache),
 		containerManager: newContainerManager(options.Backend),
+		sessionGetter:    options.SessionGetter,
+		mountCache:       options.MountCache,
+		parallelism:      options.Parallelism,
+		provenance:       builder.NewProvenanceRecorder("classic", config),
+	}
+
+	sourceDateEpoch, err := builder.SourceDateEpoch(config)
+	if err != nil {
+		return nil, err
 	}
+	b.sourceDateEpoch = sourceDateEpoch
 
 	// same as in Builder.Build in builder/builder-next/builder.go
 	// TODO: remove once config.Platform is of type specs.Platform
@@ -300,69 +424,216 @@ func printCommand(out io.Writer, currentCommandIndex int, totalCommands int, cmd
 }
 
 func (b *Builder) dispatchDockerfileWithCancellation(parseResult []instructions.Stage, metaArgs []instructions.ArgCommand, escapeToken rune, source builder.Source) (*dispatchState, error) {
-	dispatchRequest := dispatchRequest{}
 	buildArgs := NewBuildArgs(b.options.BuildArgs)
-	totalCommands := len(metaArgs) + len(parseResult)
-	currentCommandIndex := 1
-	for _, stage := range parseResult {
-		totalCommands += len(stage.Commands)
-	}
 	shlex := shell.NewLex(escapeToken)
-	for _, meta := range metaArgs {
-		currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, &meta)
 
-		err := processMetaArg(meta, shlex, buildArgs)
-		if err != nil {
-			return nil, err
+	// The meta args are evaluated ahead of time as they may be used in the
+	// base names of the stages. Errors are reported when they are dispatched.
+	stageArgs := NewBuildArgs(b.options.BuildArgs)
+	for _, meta := range metaArgs {
+		if err := processMetaArg(meta, shlex, stageArgs); err != nil {
+			break
 		}
 	}
+	deps, err := stageDependencies(parseResult, shlex, stageArgs.GetAllMeta())
+	if err != nil {
+		return nil, err
+	}
+	// stages the last stage does not depend on are skipped
+	needed := make([]bool, len(parseResult))
+	if len(parseResult) > 0 {
+		needed = neededStages(deps, len(parseResult)-1)
+	}
 
-	stagesResults := newStagesBuildResults()
+	steps := &stepCounter{current: 1, total: len(metaArgs)}
+	for i, stage := range parseResult {
+		if needed[i] {
+			steps.total += 1 + len(stage.Commands)
+		}
+	}
+	for _, meta := range metaArgs {
+		steps.print(b.Stdout, &meta)
 
-	for _, stage := range parseResult {
-		if err := stagesResults.checkStageNameAvailable(stage.Name); err != nil {
+		err := processMetaArg(meta, shlex, buildArgs)
+		if err != nil {
 			return nil, err
 		}
-		dispatchRequest = newDispatchRequest(b, escapeToken, source, buildArgs, stagesResults)
+	}
 
-		currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, stage.SourceCode)
-		if err := initializeStage(dispatchRequest, &stage); err != nil {
+	sd := &stagesDispatcher{
+		ctx:         b.clientCtx,
+		escapeToken: escapeToken,
+		source:      source,
+		stages:      parseResult,
+		results:     newStagesBuildResults(),
+		steps:       steps,
+		buildArgs:   buildArgs,
+	}
+	var state *dispatchState
+	if b.parallelism > 1 && hasConcurrentStages(deps, needed) {
+		if state, err = sd.dispatchConcurrently(b, deps, needed, b.parallelism); err != nil {
 			return nil, err
 		}
-		dispatchRequest.state.updateRunConfig()
-		fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
-		for _, cmd := range stage.Commands {
-			select {
-			case <-b.clientCtx.Done():
-				logrus.Debug("Builder: build cancelled!")
-				fmt.Fprint(b.Stdout, "Build cancelled\n")
-				buildsFailed.WithValues(metricsBuildCanceled).Inc()
-				return nil, errors.New("Build cancelled")
-			default:
-				// Not cancelled yet, keep going...
+	} else {
+		for i := range parseResult {
+			if !needed[i] {
+				logrus.Debugf("[BUILDER] skipping unused stage %s", stageName(parseResult[i], i))
+				continue
 			}
-
-			currentCommandIndex = printCommand(b.Stdout, currentCommandIndex, totalCommands, cmd)
-
-			if err := dispatch(dispatchRequest, cmd); err != nil {
+			if state, err = sd.dispatchStage(b, i); err != nil {
 				return nil, err
 			}
-			dispatchRequest.state.updateRunConfig()
-			fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
+		}
+	}
+	buildArgs.WarnOnUnusedBuildArgs(b.Stdout)
+	return state, nil
+}
+
+// stagesDispatcher holds the state shared by the stages of a build.
+type stagesDispatcher struct {
+	ctx         context.Context
+	escapeToken rune
+	source      builder.Source
+	stages      []instructions.Stage
+	results     *stagesBuildResults
+	steps       *stepCounter
+
+	mu        sync.Mutex // protects buildArgs
+	buildArgs *BuildArgs
+}
+
+// dispatchStage runs the instructions of stage i with b.
+func (sd *stagesDispatcher) dispatchStage(b *Builder, i int) (*dispatchState, error) {
+	stage := &sd.stages[i]
+	if err := sd.results.checkStageNameAvailable(stage.Name); err != nil {
+		return nil, err
+	}
+	sd.mu.Lock()
+	dispatchRequest := newDispatchRequest(b, sd.escapeToken, sd.source, sd.buildArgs, sd.results)
+	sd.mu.Unlock()
+	dispatchRequest.state.stageIndex = i
 
+	name := stageName(*stage, i)
+	b.startStep(sd.steps.print(b.Stdout, stage.SourceCode), sd.steps.total, name, stage.SourceCode)
+	err := initializeStage(dispatchRequest, stage)
+	// the base image of the stage is not a layer produced by the step
+	if err := b.finishStep(dispatchRequest.state, dispatchRequest.state.imageID, err); err != nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	dispatchRequest.state.updateRunConfig()
+	fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
+	for _, cmd := range stage.Commands {
+		select {
+		case <-b.clientCtx.Done():
+			if sd.ctx.Err() == nil {
+				// another stage of the build failed
+				return nil, b.clientCtx.Err()
+			}
+			logrus.Debug("Builder: build cancelled!")
+			fmt.Fprint(b.Stdout, "Build cancelled\n")
+			buildsFailed.WithValues(metricsBuildCanceled).Inc()
+			return nil, errors.New("Build cancelled")
+		default:
+			// Not cancelled yet, keep going...
 		}
-		if err := emitImageID(b.Aux, dispatchRequest.state); err != nil {
+
+		b.startStep(sd.steps.print(b.Stdout, cmd), sd.steps.total, name, fmt.Sprint(cmd))
+		parentID := dispatchRequest.state.imageID
+		err := dispatch(dispatchRequest, cmd)
+		if err := b.finishStep(dispatchRequest.state, parentID, err); err != nil {
 			return nil, err
 		}
-		buildArgs.MergeReferencedArgs(dispatchRequest.state.buildArgs)
-		if err := commitStage(dispatchRequest.state, stagesResults); err != nil {
+		if err != nil {
 			return nil, err
 		}
+		dispatchRequest.state.updateRunConfig()
+		fmt.Fprintf(b.Stdout, " ---> %s\n", stringid.TruncateID(dispatchRequest.state.imageID))
+
+	}
+	if err := emitImageID(b.Aux, dispatchRequest.state); err != nil {
+		return nil, err
+	}
+	sd.mu.Lock()
+	sd.buildArgs.MergeReferencedArgs(dispatchRequest.state.buildArgs)
+	sd.mu.Unlock()
+	if err := commitStage(dispatchRequest.state, sd.results); err != nil {
+		return nil, err
 	}
-	buildArgs.WarnOnUnusedBuildArgs(b.Stdout)
 	return dispatchRequest.state, nil
 }
 
+// dispatchConcurrently runs the needed stages, each one as soon as the
+// stages it depends on are built, with at most parallelism stages running
+// at the same time. The output of each stage is prefixed with its name.
+func (sd *stagesDispatcher) dispatchConcurrently(b *Builder, deps [][]int, needed []bool, parallelism int) (*dispatchState, error) {
+	eg, ctx := errgroup.WithContext(b.clientCtx)
+	sem := make(chan struct{}, parallelism)
+	done := make([]chan struct{}, len(sd.stages))
+	states := make([]*dispatchState, len(sd.stages))
+	for i := range sd.stages {
+		done[i] = make(chan struct{})
+	}
+
+	for i := range sd.stages {
+		if !needed[i] {
+			logrus.Debugf("[BUILDER] skipping unused stage %s", stageName(sd.stages[i], i))
+			continue
+		}
+		i := i
+		eg.Go(func() error {
+			for _, j := range deps[i] {
+				select {
+				case <-done[j]:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			defer func() { <-sem }()
+
+			sb, flush := b.forStage(ctx, stageName(sd.stages[i], i))
+			state, err := sd.dispatchStage(sb, i)
+			flush()
+			if err != nil {
+				return err
+			}
+			states[i] = state
+			close(done[i])
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return states[len(sd.stages)-1], nil
+}
+
+// forStage returns a copy of b for running a stage concurrently with other
+// stages. The output of the copy is prefixed with name, and must be flushed
+// once the stage completes.
+func (b *Builder) forStage(ctx context.Context, name string) (*Builder, func()) {
+	stdout := newPrefixWriter(b.Stdout, name)
+	stderr := newPrefixWriter(b.Stderr, name)
+
+	sb := *b
+	sb.clientCtx = ctx
+	sb.Stdout = stdout
+	sb.Stderr = stderr
+	sb.containerManager = newContainerManager(b.docker)
+	sb.imageProber = newImageProber(b.docker, b.options.CacheFrom, b.options.NoCache)
+	return &sb, func() {
+		stdout.Flush()
+		stderr.Flush()
+	}
+}
+
 // BuildFromConfig builds directly from `changes`, treating it as if it were the contents of a Dockerfile
 // It will:
 // - Call parse.Parse() to get an AST root for the concatenated Dockerfile entries.
@@ -28,6 +28,7 @@ import (
 	"github.com/moby/buildkit/frontend/dockerfile/instructions"
 	"github.com/moby/buildkit/frontend/dockerfile/parser"
 	"github.com/moby/buildkit/frontend/dockerfile/shell"
+	digest "github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 )
@@ -138,7 +139,7 @@ func (d *dispatchRequest) getImageMount(imageRefOrID string) (*imageMount, error
 	}
 
 	var localOnly bool
-	stage, err := d.stages.get(imageRefOrID)
+	stage, err := d.stages.get(imageRefOrID, d.state.stageIndex)
 	if err != nil {
 		return nil, err
 	}
@@ -272,6 +273,9 @@ func (d *dispatchRequest) getImageOrStage(name string, platform *specs.Platform)
 	if err != nil {
 		return nil, err
 	}
+	if !localOnly {
+		d.builder.provenance.AddBaseImage(name, digest.Digest(imageMount.Image().ImageID()))
+	}
 	return imageMount.Image(), nil
 }
 func (d *dispatchRequest) getFromImage(shlex *shell.Lex, basename string, platform *specs.Platform) (builder.Image, error) {
@@ -357,8 +361,11 @@ func dispatchRun(d dispatchRequest, c *instructions.RunCommand) error {
 	buildArgs := d.state.buildArgs.FilterAllowed(stateRunConfig.Env)
 
 	saveCmd := cmdFromArgs
+	if mountsKey := runMountsCacheKey(c); len(mountsKey) > 0 {
+		saveCmd = append(mountsKey, saveCmd...)
+	}
 	if len(buildArgs) > 0 {
-		saveCmd = prependEnvOnCmd(d.state.buildArgs, buildArgs, cmdFromArgs)
+		saveCmd = prependEnvOnCmd(d.state.buildArgs, buildArgs, saveCmd)
 	}
 
 	runConfigForCacheProbe := copyRunConfig(stateRunConfig,
@@ -377,7 +384,13 @@ func dispatchRun(d dispatchRequest, c *instructions.RunCommand) error {
 	// set config as already being escaped, this prevents double escaping on windows
 	runConfig.ArgsEscaped = true
 
-	cID, err := d.builder.create(runConfig)
+	mounts, releaseMounts, err := d.setupRunMounts(c)
+	if err != nil {
+		return err
+	}
+	defer releaseMounts()
+
+	cID, err := d.builder.create(runConfig, mounts)
 	if err != nil {
 		return err
 	}
@@ -36,6 +36,7 @@ func newBuilderWithMockBackend() *Builder {
 		}),
 		imageProber:      newImageProber(mockBackend, nil, false),
 		containerManager: newContainerManager(mockBackend),
+		provenance:       builder.NewProvenanceRecorder("classic", opts),
 	}
 	return b
 }
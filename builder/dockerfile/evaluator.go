@@ -24,6 +24,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/builder"
@@ -111,6 +112,7 @@ type dispatchState struct {
 	imageID         string
 	baseImage       builder.Image
 	stageName       string
+	stageIndex      int
 	buildArgs       *BuildArgs
 	operatingSystem string
 }
@@ -122,6 +124,7 @@ func newDispatchState(baseArgs *BuildArgs) *dispatchState {
 }
 
 type stagesBuildResults struct {
+	mu      sync.Mutex
 	flat    []*container.Config
 	indexed map[string]*container.Config
 }
@@ -133,21 +136,28 @@ func newStagesBuildResults() *stagesBuildResults {
 }
 
 func (r *stagesBuildResults) getByName(name string) (*container.Config, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	c, ok := r.indexed[strings.ToLower(name)]
 	return c, ok
 }
 
-func (r *stagesBuildResults) validateIndex(i int) error {
-	if i == len(r.flat) {
+// validateIndex checks that stage i can be referenced by stage current.
+// Stages that run concurrently or are skipped may leave holes in flat.
+func (r *stagesBuildResults) validateIndex(i, current int) error {
+	if i == current {
 		return errors.New("refers to current build stage")
 	}
-	if i < 0 || i > len(r.flat) {
+	if i < 0 || i > current || i >= len(r.flat) {
 		return errors.New("index out of bounds")
 	}
+	if r.flat[i] == nil {
+		return errors.New("refers to a build stage that was not built")
+	}
 	return nil
 }
 
-func (r *stagesBuildResults) get(nameOrIndex string) (*container.Config, error) {
+func (r *stagesBuildResults) get(nameOrIndex string, current int) (*container.Config, error) {
 	if c, ok := r.getByName(nameOrIndex); ok {
 		return c, nil
 	}
@@ -155,7 +165,9 @@ func (r *stagesBuildResults) get(nameOrIndex string) (*container.Config, error)
 	if err != nil {
 		return nil, nil
 	}
-	if err := r.validateIndex(int(ix)); err != nil {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.validateIndex(int(ix), current); err != nil {
 		return nil, err
 	}
 	return r.flat[ix], nil
@@ -170,19 +182,24 @@ func (r *stagesBuildResults) checkStageNameAvailable(name string) error {
 	return nil
 }
 
-func (r *stagesBuildResults) commitStage(name string, config *container.Config) error {
+func (r *stagesBuildResults) commitStage(index int, name string, config *container.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if name != "" {
-		if _, ok := r.getByName(name); ok {
+		if _, ok := r.indexed[strings.ToLower(name)]; ok {
 			return errors.Errorf("%s stage name already used", name)
 		}
 		r.indexed[strings.ToLower(name)] = config
 	}
-	r.flat = append(r.flat, config)
+	for len(r.flat) <= index {
+		r.flat = append(r.flat, nil)
+	}
+	r.flat[index] = config
 	return nil
 }
 
 func commitStage(state *dispatchState, stages *stagesBuildResults) error {
-	return stages.commitStage(state.stageName, state.runConfig)
+	return stages.commitStage(state.stageIndex, state.stageName, state.runConfig)
 }
 
 type dispatchRequest struct {
@@ -0,0 +1,90 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"context"
+
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/builder/fscache"
+	"github.com/docker/docker/builder/remotecontext/git"
+	"github.com/moby/buildkit/session/filesync"
+	"github.com/pkg/errors"
+)
+
+// gitRemote is the identifier of the transport for git contexts.
+const gitRemote = "git"
+
+// GitTransport is a transport for copying git contexts, at the commit their
+// ref resolved to, from the mirrors of their repositories.
+type GitTransport struct {
+	mirrors *git.Mirrors
+}
+
+// NewGitTransport returns new GitTransport instance
+func NewGitTransport(mirrors *git.Mirrors) *GitTransport {
+	return &GitTransport{mirrors: mirrors}
+}
+
+// Copy data from a remote to a destination directory.
+func (gt *GitTransport) Copy(ctx context.Context, id fscache.RemoteIdentifier, dest string, cu filesync.CacheUpdater) error {
+	gsi, ok := id.(*GitSourceIdentifier)
+	if !ok {
+		return errors.New("invalid identifier for git context")
+	}
+	return gt.mirrors.Checkout(gsi.resolvedURL, dest)
+}
+
+// DiskUsage returns the size of the mirrors of the git repositories.
+func (gt *GitTransport) DiskUsage(ctx context.Context) (int64, error) {
+	return gt.mirrors.DiskUsage(ctx)
+}
+
+// Prune removes the mirrors of the git repositories that are not in use.
+func (gt *GitTransport) Prune(ctx context.Context) (uint64, error) {
+	return gt.mirrors.Prune(ctx)
+}
+
+// GitSourceIdentifier is an identifier that can be used for requesting
+// the context of a git URL at the commit its ref resolved to.
+type GitSourceIdentifier struct {
+	resolvedURL string
+	commit      string
+}
+
+// NewGitSourceIdentifier fetches the ref of gitURL in the mirror of its
+// repository, and returns a GitSourceIdentifier for the commit it resolved to.
+func NewGitSourceIdentifier(mirrors *git.Mirrors, gitURL string) (*GitSourceIdentifier, error) {
+	resolvedURL, commit, err := mirrors.Resolve(gitURL)
+	if err != nil {
+		return nil, err
+	}
+	return &GitSourceIdentifier{resolvedURL: resolvedURL, commit: commit}, nil
+}
+
+// Transport returns transport identifier for remote identifier
+func (gsi *GitSourceIdentifier) Transport() string {
+	return gitRemote
+}
+
+// SharedKey returns shared key for remote identifier. Contexts of different
+// commits are never rebased on each other, as they are checked out from
+// scratch.
+func (gsi *GitSourceIdentifier) SharedKey() string {
+	return ""
+}
+
+// Key returns unique key for remote identifier. Requests with same key return
+// same data.
+func (gsi *GitSourceIdentifier) Key() string {
+	return gsi.resolvedURL
+}
+
+// gitSource is a git context synced to the cache.
+type gitSource struct {
+	builder.Source
+	commit string
+}
+
+// GitCommit returns the commit the context was checked out at.
+func (s *gitSource) GitCommit() string {
+	return s.commit
+}
@@ -3,6 +3,7 @@ package dockerfile // import "github.com/docker/docker/builder/dockerfile"
 import (
 	"context"
 	"runtime"
+	"sync"
 
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/builder"
@@ -17,6 +18,7 @@ type getAndMountFunc func(string, bool, *specs.Platform) (builder.Image, builder
 // imageSources mounts images and provides a cache for mounted images. It tracks
 // all images so they can be unmounted at the end of the build.
 type imageSources struct {
+	mu        sync.Mutex
 	byImageID map[string]*imageMount
 	mounts    []*imageMount
 	getImage  getAndMountFunc
@@ -47,7 +49,10 @@ func newImageSources(ctx context.Context, options builderOptions) *imageSources
 }
 
 func (m *imageSources) Get(idOrRef string, localOnly bool, platform *specs.Platform) (*imageMount, error) {
-	if im, ok := m.byImageID[idOrRef]; ok {
+	m.mu.Lock()
+	im, ok := m.byImageID[idOrRef]
+	m.mu.Unlock()
+	if ok {
 		return im, nil
 	}
 
@@ -55,12 +60,14 @@ func (m *imageSources) Get(idOrRef string, localOnly bool, platform *specs.Platf
 	if err != nil {
 		return nil, err
 	}
-	im := newImageMount(image, layer)
+	im = newImageMount(image, layer)
 	m.Add(im)
 	return im, nil
 }
 
 func (m *imageSources) Unmount() (retErr error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	for _, im := range m.mounts {
 		if err := im.unmount(); err != nil {
 			logrus.Error(err)
@@ -71,6 +78,8 @@ func (m *imageSources) Unmount() (retErr error) {
 }
 
 func (m *imageSources) Add(im *imageMount) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	switch im.image {
 	case nil:
 		// set the OS for scratch images
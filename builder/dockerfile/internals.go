@@ -13,10 +13,12 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/archive"
@@ -104,6 +106,7 @@ func (b *Builder) commitContainer(dispatchState *dispatchState, id string, conta
 		Config:          copyRunConfig(dispatchState.runConfig),
 		ContainerConfig: containerConfig,
 		ContainerID:     id,
+		SourceDateEpoch: b.sourceDateEpoch,
 	}
 
 	imageID, err := b.docker.CommitBuildStep(commitCfg)
@@ -112,7 +115,7 @@ func (b *Builder) commitContainer(dispatchState *dispatchState, id string, conta
 }
 
 func (b *Builder) exportImage(state *dispatchState, layer builder.RWLayer, parent builder.Image, runConfig *container.Config) error {
-	newLayer, err := layer.Commit()
+	newLayer, err := layer.Commit(b.sourceDateEpoch)
 	if err != nil {
 		return err
 	}
@@ -126,11 +129,16 @@ func (b *Builder) exportImage(state *dispatchState, layer builder.RWLayer, paren
 		return errors.Errorf("unexpected image type")
 	}
 
+	var created time.Time
+	if b.sourceDateEpoch != nil {
+		created = builder.ClampTime(time.Now().UTC(), b.sourceDateEpoch)
+	}
 	newImage := image.NewChildImage(parentImage, image.ChildConfig{
 		Author:          state.maintainer,
 		ContainerConfig: runConfig,
 		DiffID:          newLayer.DiffID(),
 		Config:          copyRunConfig(state.runConfig),
+		Created:         created,
 	}, parentImage.OS)
 
 	// TODO: it seems strange to marshal this here instead of just passing in the
@@ -416,6 +424,9 @@ func (b *Builder) probeCache(dispatchState *dispatchState, runConfig *container.
 		return false, err
 	}
 	fmt.Fprint(b.Stdout, " ---> Using cache\n")
+	if b.step != nil {
+		b.step.Cached = true
+	}
 
 	dispatchState.imageID = cachedID
 	return true, nil
@@ -427,14 +438,15 @@ func (b *Builder) probeAndCreate(dispatchState *dispatchState, runConfig *contai
 	if hit, err := b.probeCache(dispatchState, runConfig); err != nil || hit {
 		return "", err
 	}
-	return b.create(runConfig)
+	return b.create(runConfig, nil)
 }
 
-func (b *Builder) create(runConfig *container.Config) (string, error) {
+func (b *Builder) create(runConfig *container.Config, mounts []mount.Mount) (string, error) {
 	logrus.Debugf("[BUILDER] Command to be executed: %v", runConfig.Cmd)
 
 	isWCOW := runtime.GOOS == "windows" && b.platform != nil && b.platform.OS == "windows"
 	hostConfig := hostConfigFromOptions(b.options, isWCOW)
+	hostConfig.Mounts = mounts
 	container, err := b.containerManager.Create(runConfig, hostConfig)
 	if err != nil {
 		return "", err
@@ -444,6 +456,9 @@ func (b *Builder) create(runConfig *container.Config) (string, error) {
 		fmt.Fprintf(b.Stdout, " ---> [Warning] %s\n", warning)
 	}
 	fmt.Fprintf(b.Stdout, " ---> Running in %s\n", stringid.TruncateID(container.ID))
+	if b.step != nil {
+		b.step.ContainerID = container.ID
+	}
 	return container.ID, nil
 }
 
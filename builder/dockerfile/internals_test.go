@@ -84,7 +84,7 @@ func readAndCheckDockerfile(t *testing.T, testName, contextDir, dockerfilePath,
 		Options: &types.ImageBuildOptions{Dockerfile: dockerfilePath},
 		Source:  tarStream,
 	}
-	_, _, err = remotecontext.Detect(config)
+	_, _, _, err = remotecontext.Detect(config)
 	assert.Check(t, is.ErrorContains(err, expectedError))
 }
 
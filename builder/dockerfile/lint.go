@@ -0,0 +1,269 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/pkg/errors"
+)
+
+// Lint rules, and the severity of their findings unless set otherwise.
+const (
+	lintInvalidInstruction = "invalid-instruction"
+	lintUndefinedStage     = "undefined-stage"
+	lintUnusedArg          = "unused-arg"
+	lintLatestTag          = "latest-tag"
+	lintMissingHealthcheck = "missing-healthcheck"
+)
+
+var defaultLintSeverities = map[string]string{
+	lintInvalidInstruction: "error",
+	lintUndefinedStage:     "error",
+	lintUnusedArg:          "warning",
+	lintLatestTag:          "warning",
+	lintMissingHealthcheck: "info",
+}
+
+var validLintSeverities = map[string]bool{
+	"error":   true,
+	"warning": true,
+	"info":    true,
+	"off":     true,
+}
+
+// lintInstruction is an instruction of the Dockerfile being linted, with its
+// source and the line it starts at.
+type lintInstruction struct {
+	line     int
+	original string
+	cmd      interface{}
+}
+
+type lintStage struct {
+	lintInstruction
+	stage    *instructions.Stage
+	commands []lintInstruction
+}
+
+type linter struct {
+	severities map[string]string
+	findings   []types.DockerfileLintFinding
+}
+
+// Lint checks the Dockerfile read from r without building it, and returns
+// the problems found by the lint rules. It parses the Dockerfile the same way
+// the builder does, but reports every invalid instruction instead of only the
+// first one.
+func Lint(r io.Reader, options types.DockerfileLintOptions) (*types.DockerfileLintReport, error) {
+	l := &linter{severities: make(map[string]string)}
+	for rule, severity := range defaultLintSeverities {
+		l.severities[rule] = severity
+	}
+	for rule, severity := range options.Rules {
+		if _, ok := defaultLintSeverities[rule]; !ok {
+			return nil, errdefs.InvalidParameter(errors.Errorf("unknown lint rule %q", rule))
+		}
+		if !validLintSeverities[severity] {
+			return nil, errdefs.InvalidParameter(errors.Errorf("invalid severity %q for lint rule %q", severity, rule))
+		}
+		l.severities[rule] = severity
+	}
+
+	res, err := parser.Parse(r)
+	if err != nil {
+		l.report(lintInvalidInstruction, 0, err.Error())
+		return l.result(), nil
+	}
+
+	var (
+		metaArgs []lintInstruction
+		stages   []*lintStage
+	)
+	for _, n := range res.AST.Children {
+		cmd, err := instructions.ParseInstruction(n)
+		if err != nil {
+			l.report(lintInvalidInstruction, n.StartLine, err.Error())
+			continue
+		}
+		inst := lintInstruction{line: n.StartLine, original: n.Original, cmd: cmd}
+		switch c := cmd.(type) {
+		case *instructions.Stage:
+			stages = append(stages, &lintStage{lintInstruction: inst, stage: c})
+		case *instructions.ArgCommand:
+			if len(stages) == 0 {
+				metaArgs = append(metaArgs, inst)
+				continue
+			}
+			stages[len(stages)-1].commands = append(stages[len(stages)-1].commands, inst)
+		default:
+			if len(stages) == 0 {
+				l.report(lintInvalidInstruction, n.StartLine, "no build stage in current context")
+				continue
+			}
+			stages[len(stages)-1].commands = append(stages[len(stages)-1].commands, inst)
+		}
+	}
+
+	l.checkStageReferences(stages)
+	l.checkUnusedArgs(metaArgs, stages)
+	l.checkLatestTags(stages)
+	l.checkHealthcheck(stages)
+	return l.result(), nil
+}
+
+func (l *linter) report(rule string, line int, format string, args ...interface{}) {
+	severity := l.severities[rule]
+	if severity == "off" {
+		return
+	}
+	l.findings = append(l.findings, types.DockerfileLintFinding{
+		Rule:     rule,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+		Line:     line,
+	})
+}
+
+func (l *linter) result() *types.DockerfileLintReport {
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return l.findings[i].Line < l.findings[j].Line
+	})
+	return &types.DockerfileLintReport{Findings: l.findings}
+}
+
+// stageIndex returns the index of the stage named name, or -1.
+func stageIndex(stages []*lintStage, name string) int {
+	for i, s := range stages {
+		if s.stage.Name != "" && strings.EqualFold(s.stage.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkStageReferences reports COPY --from flags that refer to the stage they
+// are in, or to a stage that is not defined before it.
+func (l *linter) checkStageReferences(stages []*lintStage) {
+	for i, s := range stages {
+		for _, inst := range s.commands {
+			c, ok := inst.cmd.(*instructions.CopyCommand)
+			if !ok || c.From == "" {
+				continue
+			}
+			if index, err := strconv.Atoi(c.From); err == nil {
+				if index < 0 || index >= i {
+					l.report(lintUndefinedStage, inst.line, "COPY --from=%s refers to a stage that is not defined before stage %d", c.From, i)
+				}
+				continue
+			}
+			if j := stageIndex(stages, c.From); j >= i {
+				l.report(lintUndefinedStage, inst.line, "COPY --from=%s refers to stage %q before it is defined", c.From, c.From)
+			}
+		}
+	}
+}
+
+func argReference(name string) *regexp.Regexp {
+	name = regexp.QuoteMeta(name)
+	return regexp.MustCompile(`\$(` + name + `\b|\{` + name + `[}:])`)
+}
+
+// checkUnusedArgs reports ARGs that nothing uses. Global ARGs are used by the
+// FROM instructions that refer to them, or by the stages that declare them
+// again. ARGs of a stage are used by the instructions of the stage that refer
+// to them, or by RUN instructions, which get them as environment variables.
+func (l *linter) checkUnusedArgs(metaArgs []lintInstruction, stages []*lintStage) {
+	for _, inst := range metaArgs {
+		arg := inst.cmd.(*instructions.ArgCommand)
+		ref := argReference(arg.Key)
+		used := false
+		for _, s := range stages {
+			if ref.MatchString(s.stage.BaseName) || ref.MatchString(s.stage.Platform) {
+				used = true
+				break
+			}
+			for _, c := range s.commands {
+				if a, ok := c.cmd.(*instructions.ArgCommand); ok && a.Key == arg.Key && a.Value == nil {
+					used = true
+					break
+				}
+			}
+		}
+		if !used {
+			l.report(lintUnusedArg, inst.line, "ARG %s is not used by any FROM instruction or stage", arg.Key)
+		}
+	}
+
+	for _, s := range stages {
+		for i, inst := range s.commands {
+			arg, ok := inst.cmd.(*instructions.ArgCommand)
+			if !ok {
+				continue
+			}
+			ref := argReference(arg.Key)
+			used := false
+			for _, c := range s.commands[i+1:] {
+				if _, ok := c.cmd.(*instructions.RunCommand); ok {
+					used = true
+					break
+				}
+				if ref.MatchString(c.original) {
+					used = true
+					break
+				}
+			}
+			if !used {
+				l.report(lintUnusedArg, inst.line, "ARG %s is not used by any instruction of its stage", arg.Key)
+			}
+		}
+	}
+}
+
+// checkLatestTags reports FROM instructions whose base image is not pinned
+// to a tag other than latest, or to a digest.
+func (l *linter) checkLatestTags(stages []*lintStage) {
+	for i, s := range stages {
+		base := s.stage.BaseName
+		if strings.Contains(base, "$") || strings.EqualFold(base, "scratch") {
+			continue
+		}
+		if j := stageIndex(stages, base); j >= 0 && j < i {
+			continue
+		}
+		ref, err := reference.ParseNormalizedNamed(base)
+		if err != nil {
+			l.report(lintInvalidInstruction, s.line, "invalid base image %q: %v", base, err)
+			continue
+		}
+		if _, ok := ref.(reference.Digested); ok {
+			continue
+		}
+		if tagged, ok := ref.(reference.Tagged); ok && tagged.Tag() != "latest" {
+			continue
+		}
+		l.report(lintLatestTag, s.line, "base image %s is not pinned to a tag other than latest, or to a digest", base)
+	}
+}
+
+// checkHealthcheck reports a final stage without HEALTHCHECK instruction.
+func (l *linter) checkHealthcheck(stages []*lintStage) {
+	if len(stages) == 0 {
+		return
+	}
+	last := stages[len(stages)-1]
+	for _, inst := range last.commands {
+		if _, ok := inst.cmd.(*instructions.HealthCheckCommand); ok {
+			return
+		}
+	}
+	l.report(lintMissingHealthcheck, last.line, "the final stage has no HEALTHCHECK instruction")
+}
@@ -0,0 +1,85 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestLint(t *testing.T) {
+	dockerfile := `ARG VERSION=1.30
+ARG UNUSED
+FROM busybox:${VERSION} AS build
+ARG TARGET
+ARG NAME=app
+WORKDIR /src/$NAME
+COPY --from=final /app /app
+FROM busybox
+COPY --from=build /src /src
+COPY --from=3 /other /other
+COPY /only-source
+FROM build AS final
+ARG RUNTIME
+RUN echo hello
+`
+	report, err := Lint(strings.NewReader(dockerfile), types.DockerfileLintOptions{})
+	assert.NilError(t, err)
+
+	type finding struct {
+		Rule string
+		Line int
+	}
+	var findings []finding
+	for _, f := range report.Findings {
+		assert.Check(t, is.Equal(defaultLintSeverities[f.Rule], f.Severity))
+		findings = append(findings, finding{f.Rule, f.Line})
+	}
+	assert.Check(t, is.DeepEqual([]finding{
+		{lintUnusedArg, 2},
+		{lintUnusedArg, 4},
+		{lintUndefinedStage, 7},
+		{lintLatestTag, 8},
+		{lintUndefinedStage, 10},
+		{lintInvalidInstruction, 11},
+		{lintMissingHealthcheck, 12},
+	}, findings))
+}
+
+func TestLintRules(t *testing.T) {
+	dockerfile := "FROM busybox:latest\nHEALTHCHECK CMD true\n"
+	report, err := Lint(strings.NewReader(dockerfile), types.DockerfileLintOptions{
+		Rules: map[string]string{lintLatestTag: "error"},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(report.Findings, 1))
+	assert.Check(t, is.Equal(lintLatestTag, report.Findings[0].Rule))
+	assert.Check(t, is.Equal("error", report.Findings[0].Severity))
+
+	report, err = Lint(strings.NewReader(dockerfile), types.DockerfileLintOptions{
+		Rules: map[string]string{lintLatestTag: "off"},
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(report.Findings, 0))
+
+	_, err = Lint(strings.NewReader(dockerfile), types.DockerfileLintOptions{
+		Rules: map[string]string{"no-such-rule": "error"},
+	})
+	assert.Check(t, errdefs.IsInvalidParameter(err))
+
+	_, err = Lint(strings.NewReader(dockerfile), types.DockerfileLintOptions{
+		Rules: map[string]string{lintLatestTag: "fatal"},
+	})
+	assert.Check(t, errdefs.IsInvalidParameter(err))
+}
+
+func TestLintInstructionBeforeFrom(t *testing.T) {
+	report, err := Lint(strings.NewReader("RUN true\nFROM scratch\nHEALTHCHECK NONE\n"), types.DockerfileLintOptions{})
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(report.Findings, 1))
+	assert.Check(t, is.Equal(lintInvalidInstruction, report.Findings[0].Rule))
+	assert.Check(t, is.Equal(1, report.Findings[0].Line))
+}
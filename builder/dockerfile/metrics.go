@@ -7,6 +7,8 @@ import (
 var (
 	buildsTriggered metrics.Counter
 	buildsFailed    metrics.LabeledCounter
+	buildsQueued    metrics.LabeledGauge
+	buildsQueueWait metrics.LabeledTimer
 )
 
 // Build metrics prometheus messages, these values must be initialized before
@@ -27,6 +29,8 @@ func init() {
 
 	buildsTriggered = buildMetrics.NewCounter("builds_triggered", "Number of triggered image builds")
 	buildsFailed = buildMetrics.NewLabeledCounter("builds_failed", "Number of failed image builds", "reason")
+	buildsQueued = buildMetrics.NewLabeledGauge("builds_queued", "Number of image builds waiting for other builds to complete", metrics.Unit("builds"), "builder")
+	buildsQueueWait = buildMetrics.NewLabeledTimer("builds_queue_wait", "Time image builds waited for other builds to complete", "builder")
 	for _, r := range []string{
 		metricsDockerfileSyntaxError,
 		metricsDockerfileEmptyError,
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"io"
 	"runtime"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
@@ -118,7 +119,9 @@ func (mic *mockImageCache) GetCache(parentID string, cfg *container.Config) (str
 	return "", nil
 }
 
-type mockLayer struct{}
+type mockLayer struct {
+	diffSize int64
+}
 
 func (l *mockLayer) Release() error {
 	return nil
@@ -132,6 +135,10 @@ func (l *mockLayer) DiffID() layer.DiffID {
 	return layer.DiffID("abcdef")
 }
 
+func (l *mockLayer) DiffSize() (int64, error) {
+	return l.diffSize, nil
+}
+
 type mockRWLayer struct {
 }
 
@@ -139,7 +146,7 @@ func (l *mockRWLayer) Release() error {
 	return nil
 }
 
-func (l *mockRWLayer) Commit() (builder.ROLayer, error) {
+func (l *mockRWLayer) Commit(sourceDateEpoch *time.Time) (builder.ROLayer, error) {
 	return nil, nil
 }
 
@@ -0,0 +1,68 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/image"
+)
+
+// buildStepAuxID is the ID of the aux messages with the progress of the steps
+// of a build.
+const buildStepAuxID = "moby.build.step"
+
+// startStep starts recording the progress of a step of the build, if progress
+// events were requested. The cache result and container of the step are
+// recorded as the step runs, and the event is emitted by finishStep.
+func (b *Builder) startStep(step, total int, stage string, instruction string) {
+	if !b.options.ProgressEvents || b.Aux == nil {
+		return
+	}
+	b.step = &types.BuildStep{
+		Step:        step,
+		Total:       total,
+		Stage:       stage,
+		Instruction: instruction,
+		Started:     time.Now().UTC(),
+	}
+}
+
+// finishStep emits the progress of the step started by startStep, which
+// resulted in the image of state, or failed with stepErr.
+func (b *Builder) finishStep(state *dispatchState, parentID string, stepErr error) error {
+	step := b.step
+	if step == nil {
+		return nil
+	}
+	b.step = nil
+
+	step.Completed = time.Now().UTC()
+	step.ImageID = state.imageID
+	if stepErr != nil {
+		step.Error = stepErr.Error()
+	} else if !step.Cached && state.imageID != "" && state.imageID != parentID {
+		size, err := b.layerSize(state.imageID)
+		if err != nil {
+			return err
+		}
+		step.LayerSize = size
+	}
+	return b.Aux.Emit(buildStepAuxID, step)
+}
+
+// layerSize returns the size of the layer added by the image imageID to its
+// parent, or 0 if it did not add one.
+func (b *Builder) layerSize(imageID string) (int64, error) {
+	img, l, err := b.docker.GetImageAndReleasableLayer(b.clientCtx, imageID, backend.GetImageAndLayerOptions{PullOption: backend.PullOptionNoPull})
+	if err != nil {
+		return 0, err
+	}
+	defer l.Release()
+	if img, ok := img.(*image.Image); ok {
+		if len(img.History) > 0 && img.History[len(img.History)-1].EmptyLayer {
+			return 0, nil
+		}
+	}
+	return l.DiffSize()
+}
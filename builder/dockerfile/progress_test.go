@@ -0,0 +1,79 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/streamformatter"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestBuildStepEvents(t *testing.T) {
+	b := newBuilderWithMockBackend()
+	b.options.ProgressEvents = true
+	mockBackend := b.docker.(*MockBackend)
+	mockBackend.containerCreateFunc = func(config types.ContainerCreateConfig) (container.ContainerCreateCreatedBody, error) {
+		return container.ContainerCreateCreatedBody{ID: "containerid"}, nil
+	}
+	mockBackend.getImageFunc = func(refOrID string) (builder.Image, builder.ROLayer, error) {
+		return &mockImage{id: refOrID}, &mockLayer{diffSize: 42}, nil
+	}
+	aux := new(bytes.Buffer)
+	b.Aux = &streamformatter.AuxFormatter{Writer: aux}
+
+	state := &dispatchState{imageID: "parent"}
+	b.startStep(2, 3, "build", "RUN make")
+	_, err := b.create(&container.Config{}, nil)
+	assert.NilError(t, err)
+	state.imageID = "child"
+	assert.NilError(t, b.finishStep(state, "parent", nil))
+
+	b.startStep(3, 3, "build", "RUN make test")
+	assert.NilError(t, b.finishStep(state, "child", errors.New("failed")))
+
+	// no event is emitted for steps that were not started
+	assert.NilError(t, b.finishStep(state, "child", nil))
+
+	dec := json.NewDecoder(aux)
+	var steps []types.BuildStep
+	for dec.More() {
+		var msg jsonmessage.JSONMessage
+		assert.NilError(t, dec.Decode(&msg))
+		assert.Check(t, is.Equal(buildStepAuxID, msg.ID))
+		var step types.BuildStep
+		assert.NilError(t, json.Unmarshal(*msg.Aux, &step))
+		steps = append(steps, step)
+	}
+	assert.Assert(t, is.Len(steps, 2))
+
+	assert.Check(t, is.Equal(2, steps[0].Step))
+	assert.Check(t, is.Equal(3, steps[0].Total))
+	assert.Check(t, is.Equal("build", steps[0].Stage))
+	assert.Check(t, is.Equal("RUN make", steps[0].Instruction))
+	assert.Check(t, !steps[0].Cached)
+	assert.Check(t, is.Equal("containerid", steps[0].ContainerID))
+	assert.Check(t, is.Equal("child", steps[0].ImageID))
+	assert.Check(t, is.Equal(int64(42), steps[0].LayerSize))
+	assert.Check(t, !steps[0].Completed.Before(steps[0].Started))
+
+	assert.Check(t, is.Equal("RUN make test", steps[1].Instruction))
+	assert.Check(t, is.Equal("failed", steps[1].Error))
+	assert.Check(t, is.Equal(int64(0), steps[1].LayerSize))
+}
+
+func TestBuildStepEventsDisabled(t *testing.T) {
+	b := newBuilderWithMockBackend()
+	aux := new(bytes.Buffer)
+	b.Aux = &streamformatter.AuxFormatter{Writer: aux}
+
+	b.startStep(1, 1, "build", "RUN make")
+	assert.NilError(t, b.finishStep(&dispatchState{imageID: "child"}, "parent", nil))
+	assert.Check(t, is.Equal(0, aux.Len()))
+}
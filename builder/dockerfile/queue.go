@@ -0,0 +1,160 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/backend"
+)
+
+// buildQueueAuxID is the ID of the aux messages with the position of a
+// queued build.
+const buildQueueAuxID = "moby.build.queue"
+
+// BuildQueue limits the number of builds that run at the same time. Builds
+// over the limit wait in a queue, and are started in the order they arrived.
+type BuildQueue struct {
+	name    string // for metrics
+	max     int
+	mu      sync.Mutex
+	running int
+	waiting []*queuedBuild
+}
+
+type queuedBuild struct {
+	ready chan struct{} // closed once the build may run
+	moved chan struct{} // signalled when the build moves up in the queue
+}
+
+// NewBuildQueue returns a BuildQueue that runs at most max builds at the same
+// time, or any number of builds if max is not positive. The queue depth and
+// wait time of the queue are reported in metrics labelled with name.
+func NewBuildQueue(name string, max int) *BuildQueue {
+	buildsQueued.WithValues(name).Set(0)
+	return &BuildQueue{name: name, max: max}
+}
+
+// Acquire waits until a build can run, and returns a function to call once
+// it is done. While the build waits, position is called with the position of
+// the build in the queue, starting at 1, each time it changes. Canceling ctx
+// removes the build from the queue.
+func (q *BuildQueue) Acquire(ctx context.Context, position func(int)) (release func(), err error) {
+	if q == nil || q.max <= 0 {
+		return func() {}, nil
+	}
+	start := time.Now()
+	defer func() {
+		if err == nil {
+			buildsQueueWait.WithValues(q.name).UpdateSince(start)
+		}
+	}()
+
+	q.mu.Lock()
+	if q.running < q.max && len(q.waiting) == 0 {
+		q.running++
+		q.mu.Unlock()
+		return q.releaseFunc(), nil
+	}
+	qb := &queuedBuild{ready: make(chan struct{}), moved: make(chan struct{}, 1)}
+	q.waiting = append(q.waiting, qb)
+	buildsQueued.WithValues(q.name).Set(float64(len(q.waiting)))
+	pos := len(q.waiting)
+	q.mu.Unlock()
+
+	position(pos)
+	for {
+		select {
+		case <-qb.ready:
+			return q.releaseFunc(), nil
+		case <-qb.moved:
+			q.mu.Lock()
+			pos := q.position(qb)
+			q.mu.Unlock()
+			if pos > 0 {
+				position(pos)
+			}
+		case <-ctx.Done():
+			q.mu.Lock()
+			removed := q.remove(qb)
+			q.mu.Unlock()
+			if !removed {
+				// the build was started concurrently, pass its turn on
+				q.releaseFunc()()
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (q *BuildQueue) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(q.release)
+	}
+}
+
+// release starts the next queued build, if any, in place of a build that
+// is done.
+func (q *BuildQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiting) == 0 {
+		q.running--
+		return
+	}
+	next := q.waiting[0]
+	q.waiting = q.waiting[1:]
+	close(next.ready)
+	q.notifyMoved(0)
+}
+
+// position returns the position of qb in the queue, or 0 if it is not queued.
+// It must be called with q.mu held.
+func (q *BuildQueue) position(qb *queuedBuild) int {
+	for i, w := range q.waiting {
+		if w == qb {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// remove removes qb from the queue, and returns whether it was queued. It
+// must be called with q.mu held.
+func (q *BuildQueue) remove(qb *queuedBuild) bool {
+	i := q.position(qb) - 1
+	if i < 0 {
+		return false
+	}
+	q.waiting = append(q.waiting[:i], q.waiting[i+1:]...)
+	q.notifyMoved(i)
+	return true
+}
+
+// notifyMoved signals the builds from index i of the queue that they moved
+// up. It must be called with q.mu held.
+func (q *BuildQueue) notifyMoved(i int) {
+	buildsQueued.WithValues(q.name).Set(float64(len(q.waiting)))
+	for _, w := range q.waiting[i:] {
+		select {
+		case w.moved <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// QueuePositionReporter returns a function that reports the position of a
+// queued build to the progress stream of the build.
+func QueuePositionReporter(pw backend.ProgressWriter) func(int) {
+	return func(position int) {
+		if pw.StdoutFormatter != nil {
+			fmt.Fprintf(pw.StdoutFormatter, "Waiting for other builds to complete, position in queue: %d\n", position)
+		}
+		if pw.AuxFormatter != nil {
+			pw.AuxFormatter.Emit(buildQueueAuxID, types.BuildQueueStatus{Position: position})
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestBuildQueue(t *testing.T) {
+	q := NewBuildQueue("test", 1)
+
+	release, err := q.Acquire(context.Background(), func(int) { t.Fatal("first build should not be queued") })
+	assert.NilError(t, err)
+
+	type result struct {
+		release func()
+		err     error
+	}
+	queue := func(ctx context.Context) (chan int, chan result) {
+		positions := make(chan int, 10)
+		done := make(chan result, 1)
+		go func() {
+			release, err := q.Acquire(ctx, func(p int) { positions <- p })
+			done <- result{release, err}
+		}()
+		return positions, done
+	}
+	waitPosition := func(positions chan int, expected int) {
+		select {
+		case p := <-positions:
+			assert.Check(t, is.Equal(expected, p))
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for position %d", expected)
+		}
+	}
+	waitDone := func(done chan result) result {
+		select {
+		case r := <-done:
+			return r
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for build to start")
+		}
+		return result{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	positions2, done2 := queue(ctx)
+	waitPosition(positions2, 1)
+	positions3, done3 := queue(context.Background())
+	waitPosition(positions3, 2)
+
+	// canceling a queued build moves the following builds up
+	cancel()
+	assert.Check(t, is.ErrorContains(waitDone(done2).err, "context canceled"))
+	waitPosition(positions3, 1)
+
+	select {
+	case <-done3:
+		t.Fatal("build should be queued until the running build is done")
+	default:
+	}
+	release()
+	r := waitDone(done3)
+	assert.NilError(t, r.err)
+
+	// releasing twice does not start more builds than the limit
+	release()
+	_, done4 := queue(context.Background())
+	select {
+	case <-done4:
+		t.Fatal("build should be queued until the running build is done")
+	case <-time.After(100 * time.Millisecond):
+	}
+	r.release()
+	assert.NilError(t, waitDone(done4).err)
+}
+
+func TestBuildQueueUnlimited(t *testing.T) {
+	q := NewBuildQueue("test", 0)
+	for i := 0; i < 3; i++ {
+		_, err := q.Acquire(context.Background(), func(int) { t.Fatal("build should not be queued") })
+		assert.NilError(t, err)
+	}
+}
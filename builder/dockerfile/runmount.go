@@ -0,0 +1,261 @@
+// +build dfrunmount
+
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/errdefs"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/session/secrets"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// runMounts returns the mounts of a RUN instruction. RunCommands that were
+// not created by the Dockerfile parser have no mount state, which makes
+// instructions.GetMounts panic.
+func runMounts(c *instructions.RunCommand) (mounts []*instructions.Mount) {
+	defer func() {
+		if recover() != nil {
+			mounts = nil
+		}
+	}()
+	return instructions.GetMounts(c)
+}
+
+// runMountsFrom returns the stages and images the mounts of a RUN instruction
+// are taken from.
+func runMountsFrom(c *instructions.RunCommand) []string {
+	var from []string
+	for _, m := range runMounts(c) {
+		if m.From != "" {
+			from = append(from, m.From)
+		}
+	}
+	return from
+}
+
+// runMountsCacheKey returns the mounts of a RUN instruction in the form
+// they are stored in the build cache. Only the mount options are part of
+// the key, not the content of the mounted cache directories and secrets.
+func runMountsCacheKey(c *instructions.RunCommand) []string {
+	var key []string
+	for _, m := range runMounts(c) {
+		s := "--mount=type=" + m.Type
+		if m.From != "" {
+			s += ",from=" + m.From
+		}
+		if m.Source != "" {
+			s += ",source=" + m.Source
+		}
+		if m.Target != "" {
+			s += ",target=" + m.Target
+		}
+		if m.CacheID != "" {
+			s += ",id=" + m.CacheID
+		}
+		if m.CacheSharing != "" {
+			s += ",sharing=" + m.CacheSharing
+		}
+		s += ",ro=" + strconv.FormatBool(m.ReadOnly)
+		key = append(key, s)
+	}
+	return key
+}
+
+// setupRunMounts prepares the mounts of a RUN instruction for the container
+// the instruction runs in. Mounts are not part of the container filesystem,
+// so their content is not committed. The returned function releases the
+// resources held by the mounts once the container is committed.
+func (d dispatchRequest) setupRunMounts(c *instructions.RunCommand) (mounts []mount.Mount, release func(), retErr error) {
+	var releasers []func()
+	release = func() {
+		for i := len(releasers) - 1; i >= 0; i-- {
+			releasers[i]()
+		}
+	}
+	defer func() {
+		if retErr != nil {
+			release()
+		}
+	}()
+
+	for _, m := range runMounts(c) {
+		var (
+			src string
+			rel func()
+			err error
+		)
+		target := m.Target
+		switch m.Type {
+		case instructions.MountTypeBind:
+			src, rel, err = d.bindMountSource(m)
+		case instructions.MountTypeCache:
+			src, rel, err = d.cacheMountSource(m)
+		case instructions.MountTypeSecret:
+			var id string
+			id, src, rel, err = d.secretMountSource(m)
+			if target == "" {
+				target = "/run/secrets/" + id
+			}
+		default:
+			err = errdefs.InvalidParameter(errors.Errorf("mount type %q is not supported by the classic builder", m.Type))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if rel != nil {
+			releasers = append(releasers, rel)
+		}
+		if src == "" {
+			// optional secret that was not provided
+			continue
+		}
+		if target == "" {
+			return nil, nil, errdefs.InvalidParameter(errors.Errorf("%s mount requires a target", m.Type))
+		}
+		if !path.IsAbs(target) {
+			target = path.Join(d.state.runConfig.WorkingDir, target)
+		}
+		mounts = append(mounts, mount.Mount{
+			Type:     mount.TypeBind,
+			Source:   src,
+			Target:   target,
+			ReadOnly: m.ReadOnly,
+		})
+	}
+	return mounts, release, nil
+}
+
+// bindMountSource returns the host path of the source of a bind mount, in the
+// build stage or image named by the from option, or in the build context.
+func (d dispatchRequest) bindMountSource(m *instructions.Mount) (string, func(), error) {
+	source := m.Source
+	if source == "" {
+		source = "/"
+	}
+
+	if m.From == "" {
+		if !m.ReadOnly {
+			return "", nil, errdefs.InvalidParameter(errors.New("read-write bind mounts of the build context are not supported"))
+		}
+		if d.source == nil {
+			return "", nil, errdefs.InvalidParameter(errors.New("bind mounts of the build context require a build context"))
+		}
+		p, err := d.source.Root().ResolveScopedPath(source, true)
+		if err != nil {
+			return "", nil, err
+		}
+		return p, nil, nil
+	}
+
+	im, err := d.getImageMount(m.From)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "invalid from flag value %s", m.From)
+	}
+	// changes made through the mount are written to a layer that is
+	// discarded once the instruction completes
+	rwLayer, err := im.NewRWLayer()
+	if err != nil {
+		return "", nil, err
+	}
+	release := func() {
+		if err := rwLayer.Release(); err != nil {
+			logrus.WithError(err).Warn("failed to release bind mount layer")
+		}
+	}
+	p, err := rwLayer.Root().ResolveScopedPath(source, true)
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+	return p, release, nil
+}
+
+// cacheMountSource returns the host path of the persistent directory of a
+// cache mount.
+func (d dispatchRequest) cacheMountSource(m *instructions.Mount) (string, func(), error) {
+	if d.builder.mountCache == nil {
+		return "", nil, errdefs.NotImplemented(errors.New("cache mounts are not supported by this daemon"))
+	}
+	if m.Target == "" {
+		return "", nil, errdefs.InvalidParameter(errors.New("cache mount requires a target"))
+	}
+	if m.From != "" {
+		return "", nil, errdefs.InvalidParameter(errors.New("cache mounts with a from option are not supported by the classic builder"))
+	}
+	id := m.CacheID
+	if id == "" {
+		id = m.Target
+	}
+	return d.builder.mountCache.Get(d.builder.clientCtx, id, m.CacheSharing)
+}
+
+// secretMountSource fetches a secret from the build session and writes it to
+// a temporary file, which is removed once the instruction completes. An empty
+// path is returned if the secret is optional and was not provided.
+func (d dispatchRequest) secretMountSource(m *instructions.Mount) (string, string, func(), error) {
+	id := m.CacheID
+	if id == "" {
+		id = m.Source
+	}
+	if id == "" {
+		id = path.Base(m.Target)
+	}
+
+	data, err := d.builder.getSecret(id)
+	if err != nil {
+		if errors.Cause(err) == secrets.ErrNotFound && !m.Required {
+			return id, "", nil, nil
+		}
+		return id, "", nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "docker-build-secret")
+	if err != nil {
+		return id, "", nil, err
+	}
+	release := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logrus.WithError(err).Warn("failed to remove build secret")
+		}
+	}
+	p := filepath.Join(dir, "secret")
+	if err := ioutil.WriteFile(p, data, 0400); err != nil {
+		release()
+		return id, "", nil, err
+	}
+	if d.builder.idMapping != nil {
+		rootIdentity := d.builder.idMapping.RootPair()
+		for _, fp := range []string{dir, p} {
+			if err := os.Chown(fp, rootIdentity.UID, rootIdentity.GID); err != nil {
+				release()
+				return id, "", nil, err
+			}
+		}
+	}
+	return id, p, release, nil
+}
+
+// getSecret fetches the secret id from the session the build was started
+// with.
+func (b *Builder) getSecret(id string) ([]byte, error) {
+	if b.options.SessionID == "" || b.sessionGetter == nil {
+		return nil, errors.Wrapf(secrets.ErrNotFound, "secret %s not found: build has no session", id)
+	}
+	ctx, cancel := context.WithTimeout(b.clientCtx, 5*time.Second)
+	defer cancel()
+	caller, err := b.sessionGetter.Get(ctx, b.options.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	return secrets.GetSecret(b.clientCtx, caller, id)
+}
@@ -0,0 +1,20 @@
+// +build !dfrunmount
+
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"github.com/docker/docker/api/types/mount"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+)
+
+func runMountsFrom(c *instructions.RunCommand) []string {
+	return nil
+}
+
+func runMountsCacheKey(c *instructions.RunCommand) []string {
+	return nil
+}
+
+func (d dispatchRequest) setupRunMounts(c *instructions.RunCommand) ([]mount.Mount, func(), error) {
+	return nil, func() {}, nil
+}
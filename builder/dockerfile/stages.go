@@ -0,0 +1,235 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/pkg/streamformatter"
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
+	"github.com/pkg/errors"
+)
+
+// stageDependencies returns, for each stage, the indexes of the earlier stages
+// it uses through its FROM instruction, COPY --from or RUN --mount from=.
+// metaArgs are the values of the ARGs declared before the first FROM, which
+// may be used in the base name of stages.
+func stageDependencies(stages []instructions.Stage, shlex *shell.Lex, metaArgs map[string]string) ([][]int, error) {
+	substitutionArgs := []string{}
+	for key, value := range metaArgs {
+		substitutionArgs = append(substitutionArgs, key+"="+value)
+	}
+
+	byName := make(map[string]int)
+	deps := make([][]int, len(stages))
+	for i, stage := range stages {
+		seen := make(map[int]bool)
+		addDep := func(nameOrIndex string) {
+			j, ok := byName[strings.ToLower(nameOrIndex)]
+			if !ok {
+				ix, err := strconv.Atoi(nameOrIndex)
+				if err != nil || ix < 0 || ix >= i {
+					// an image, or an invalid reference that fails
+					// when the stage is dispatched
+					return
+				}
+				j = ix
+			}
+			if !seen[j] {
+				seen[j] = true
+				deps[i] = append(deps[i], j)
+			}
+		}
+
+		// a base name that cannot be expanded fails when the stage is
+		// dispatched
+		if name, err := shlex.ProcessWord(stage.BaseName, substitutionArgs); err == nil {
+			if j, ok := byName[strings.ToLower(name)]; ok {
+				addDep(strconv.Itoa(j))
+			}
+		}
+		for _, cmd := range stage.Commands {
+			switch c := cmd.(type) {
+			case *instructions.CopyCommand:
+				if c.From != "" {
+					addDep(c.From)
+				}
+			case *instructions.RunCommand:
+				for _, from := range runMountsFrom(c) {
+					addDep(from)
+				}
+			}
+		}
+
+		if stage.Name != "" {
+			name := strings.ToLower(stage.Name)
+			if _, ok := byName[name]; ok {
+				return nil, errors.Errorf("%s stage name already used", stage.Name)
+			}
+			byName[name] = i
+		}
+	}
+	return deps, nil
+}
+
+// neededStages returns which stages the stage target depends on, directly
+// or through other stages. The target itself is always needed.
+func neededStages(deps [][]int, target int) []bool {
+	needed := make([]bool, len(deps))
+	var visit func(int)
+	visit = func(i int) {
+		if needed[i] {
+			return
+		}
+		needed[i] = true
+		for _, j := range deps[i] {
+			visit(j)
+		}
+	}
+	visit(target)
+	return needed
+}
+
+// hasConcurrentStages returns whether any two needed stages are independent
+// of each other, so that they can run at the same time.
+func hasConcurrentStages(deps [][]int, needed []bool) bool {
+	// depth is the length of the longest dependency chain of a stage. Two
+	// stages at the same depth can never depend on each other.
+	depth := make([]int, len(deps))
+	count := make(map[int]int)
+	for i := range deps {
+		if !needed[i] {
+			continue
+		}
+		for _, j := range deps[i] {
+			if depth[j]+1 > depth[i] {
+				depth[i] = depth[j] + 1
+			}
+		}
+		count[depth[i]]++
+		if count[depth[i]] > 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// stageName returns the name stage i is referred to by in the output of the
+// build.
+func stageName(stage instructions.Stage, i int) string {
+	if stage.Name != "" {
+		return stage.Name
+	}
+	return "stage-" + strconv.Itoa(i)
+}
+
+// stepCounter numbers the steps of a build, which may be printed by stages
+// running concurrently.
+type stepCounter struct {
+	mu      sync.Mutex
+	current int
+	total   int
+}
+
+// print prints cmd as the next step of the build, and returns the index of
+// the step.
+func (s *stepCounter) print(out io.Writer, cmd interface{}) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	step := s.current
+	s.current = printCommand(out, s.current, s.total, cmd)
+	return step
+}
+
+// syncWriter serializes the writes of the stages of a build to the output of
+// the build.
+type syncWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (sw *syncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p)
+}
+
+// syncProgressWriter returns a copy of pw whose writers can be used by stages
+// running concurrently. They all write to the same output, so their writes
+// are serialized with a single lock.
+func syncProgressWriter(pw backend.ProgressWriter) backend.ProgressWriter {
+	mu := &sync.Mutex{}
+	if pw.Output != nil {
+		pw.Output = &syncWriter{mu: mu, w: pw.Output}
+	}
+	if pw.StdoutFormatter != nil {
+		pw.StdoutFormatter = &syncWriter{mu: mu, w: pw.StdoutFormatter}
+	}
+	if pw.StderrFormatter != nil {
+		pw.StderrFormatter = &syncWriter{mu: mu, w: pw.StderrFormatter}
+	}
+	if pw.AuxFormatter != nil {
+		pw.AuxFormatter = &streamformatter.AuxFormatter{Writer: &syncWriter{mu: mu, w: pw.AuxFormatter.Writer}}
+	}
+	return pw
+}
+
+// prefixWriter prefixes every line written to it, so that the output of
+// stages running concurrently can be told apart. Incomplete lines are held
+// back until they are terminated or the writer is flushed, so that lines of
+// different stages are not interleaved.
+type prefixWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+func newPrefixWriter(w io.Writer, name string) *prefixWriter {
+	return &prefixWriter{w: w, prefix: []byte(fmt.Sprintf("[%s] ", name))}
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.buf = append(pw.buf, p...)
+	i := bytes.LastIndexByte(pw.buf, '\n')
+	if i == -1 {
+		return len(p), nil
+	}
+	err := pw.writeLines(pw.buf[:i+1])
+	pw.buf = append(pw.buf[:0], pw.buf[i+1:]...)
+	return len(p), err
+}
+
+// Flush writes the incomplete line held back, if any.
+func (pw *prefixWriter) Flush() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if len(pw.buf) == 0 {
+		return nil
+	}
+	err := pw.writeLines(append(pw.buf, '\n'))
+	pw.buf = pw.buf[:0]
+	return err
+}
+
+func (pw *prefixWriter) writeLines(lines []byte) error {
+	var out []byte
+	for _, line := range bytes.SplitAfter(lines, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		out = append(out, pw.prefix...)
+		out = append(out, line...)
+	}
+	_, err := pw.w.Write(out)
+	return err
+}
@@ -0,0 +1,93 @@
+package dockerfile // import "github.com/docker/docker/builder/dockerfile"
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/moby/buildkit/frontend/dockerfile/instructions"
+	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/moby/buildkit/frontend/dockerfile/shell"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func parseStages(t *testing.T, dockerfile string) []instructions.Stage {
+	t.Helper()
+	result, err := parser.Parse(strings.NewReader(dockerfile))
+	assert.NilError(t, err)
+	stages, _, err := instructions.Parse(result.AST)
+	assert.NilError(t, err)
+	return stages
+}
+
+func TestStageDependencies(t *testing.T) {
+	stages := parseStages(t, `
+FROM busybox AS base
+FROM golang AS build
+COPY --from=base /etc/passwd /
+FROM ${BASE} AS unused
+FROM alpine
+COPY --from=1 /go/bin/app /
+COPY --from=nginx /etc/nginx /etc/nginx
+`)
+	deps, err := stageDependencies(stages, shell.NewLex('\\'), map[string]string{"BASE": "base"})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(deps, [][]int{nil, {0}, {0}, {1}}))
+
+	needed := neededStages(deps, len(stages)-1)
+	assert.Check(t, is.DeepEqual(needed, []bool{true, true, false, true}))
+	assert.Check(t, !hasConcurrentStages(deps, needed))
+
+	needed = neededStages(deps, 2)
+	assert.Check(t, is.DeepEqual(needed, []bool{true, false, true, false}))
+}
+
+func TestStageDependenciesConcurrent(t *testing.T) {
+	stages := parseStages(t, `
+FROM busybox AS a
+FROM busybox AS b
+FROM busybox
+COPY --from=a /a /
+COPY --from=b /b /
+`)
+	deps, err := stageDependencies(stages, shell.NewLex('\\'), nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(deps, [][]int{nil, nil, {0, 1}}))
+	assert.Check(t, hasConcurrentStages(deps, neededStages(deps, 2)))
+	assert.Check(t, !hasConcurrentStages(deps, neededStages(deps, 1)))
+}
+
+func TestStageDependenciesDuplicateName(t *testing.T) {
+	stages := parseStages(t, `
+FROM busybox AS a
+FROM busybox AS A
+`)
+	_, err := stageDependencies(stages, shell.NewLex('\\'), nil)
+	assert.Check(t, is.Error(err, "a stage name already used"))
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	pw := newPrefixWriter(&out, "build")
+
+	fmt.Fprint(pw, "one\ntw")
+	assert.Check(t, is.Equal(out.String(), "[build] one\n"))
+	fmt.Fprint(pw, "o\nthree\nfour")
+	assert.Check(t, is.Equal(out.String(), "[build] one\n[build] two\n[build] three\n"))
+	assert.NilError(t, pw.Flush())
+	assert.Check(t, is.Equal(out.String(), "[build] one\n[build] two\n[build] three\n[build] four\n"))
+}
+
+func TestStagesBuildResultsIndex(t *testing.T) {
+	results := newStagesBuildResults()
+	assert.NilError(t, results.commitStage(1, "", nil))
+
+	_, err := results.get("0", 2)
+	assert.Check(t, is.Error(err, "refers to a build stage that was not built"))
+	_, err = results.get("2", 2)
+	assert.Check(t, is.Error(err, "refers to current build stage"))
+	_, err = results.get("3", 2)
+	assert.Check(t, is.Error(err, "index out of bounds"))
+}
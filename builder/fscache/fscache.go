@@ -77,6 +77,14 @@ type Transport interface {
 	Copy(ctx context.Context, id RemoteIdentifier, dest string, cs filesync.CacheUpdater) error
 }
 
+// storageTransport is implemented by transports that keep data of their own
+// besides the synced sources, so that it is accounted for and pruned along
+// with the cache.
+type storageTransport interface {
+	DiskUsage(ctx context.Context) (int64, error)
+	Prune(ctx context.Context) (uint64, error)
+}
+
 // RemoteIdentifier identifies a transfer request
 type RemoteIdentifier interface {
 	Key() string
@@ -156,12 +164,46 @@ func (fsc *FSCache) SyncFrom(ctx context.Context, id RemoteIdentifier) (builder.
 
 // DiskUsage reports how much data is allocated by the cache
 func (fsc *FSCache) DiskUsage(ctx context.Context) (int64, error) {
-	return fsc.store.DiskUsage(ctx)
+	total, err := fsc.store.DiskUsage(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, st := range fsc.storageTransports() {
+		size, err := st.DiskUsage(ctx)
+		if err != nil {
+			return 0, err
+		}
+		total += size
+	}
+	return total, nil
 }
 
 // Prune allows manually cleaning up the cache
 func (fsc *FSCache) Prune(ctx context.Context) (uint64, error) {
-	return fsc.store.Prune(ctx)
+	total, err := fsc.store.Prune(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, st := range fsc.storageTransports() {
+		size, err := st.Prune(ctx)
+		total += size
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (fsc *FSCache) storageTransports() []storageTransport {
+	fsc.mu.Lock()
+	defer fsc.mu.Unlock()
+	var sts []storageTransport
+	for _, t := range fsc.transports {
+		if st, ok := t.(storageTransport); ok {
+			sts = append(sts, st)
+		}
+	}
+	return sts
 }
 
 // Close stops the gc and closes the persistent db
@@ -107,6 +107,38 @@ func TestFSCache(t *testing.T) {
 	assert.Check(t, is.Equal(s, int64(0)))
 }
 
+func TestFSCacheStorageTransport(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "fscache")
+	assert.Check(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	fscache, err := NewFSCache(Opt{
+		Root:    tmpDir,
+		Backend: NewNaiveCacheBackend(filepath.Join(tmpDir, "backend")),
+	})
+	assert.NilError(t, err)
+	defer fscache.Close()
+
+	st := &testStorageTransport{size: 3}
+	assert.NilError(t, fscache.RegisterTransport("test", st))
+
+	src, err := fscache.SyncFrom(context.TODO(), &testIdentifier{"foo", "data", ""})
+	assert.NilError(t, err)
+	assert.Check(t, src.Close())
+
+	s, err := fscache.DiskUsage(context.TODO())
+	assert.Check(t, err)
+	assert.Check(t, is.Equal(s, int64(7)))
+
+	released, err := fscache.Prune(context.TODO())
+	assert.Check(t, err)
+	assert.Check(t, is.Equal(released, uint64(7)))
+
+	s, err = fscache.DiskUsage(context.TODO())
+	assert.Check(t, err)
+	assert.Check(t, is.Equal(s, int64(0)))
+}
+
 type testTransport struct {
 }
 
@@ -115,6 +147,21 @@ func (t *testTransport) Copy(ctx context.Context, id RemoteIdentifier, dest stri
 	return ioutil.WriteFile(filepath.Join(dest, testid.filename), []byte(testid.data), 0600)
 }
 
+type testStorageTransport struct {
+	testTransport
+	size int64
+}
+
+func (t *testStorageTransport) DiskUsage(ctx context.Context) (int64, error) {
+	return t.size, nil
+}
+
+func (t *testStorageTransport) Prune(ctx context.Context) (uint64, error) {
+	size := t.size
+	t.size = 0
+	return uint64(size), nil
+}
+
 type testIdentifier struct {
 	filename  string
 	data      string
@@ -0,0 +1,198 @@
+package fscache // import "github.com/docker/docker/builder/fscache"
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/pkg/directory"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Sharing modes of cache mounts, matching the values of the sharing option
+// of RUN --mount=type=cache.
+const (
+	MountSharingShared  = "shared"
+	MountSharingPrivate = "private"
+	MountSharingLocked  = "locked"
+)
+
+const privateMountSuffix = "-private-"
+
+// MountCache manages the persistent directories that back the cache mounts
+// of RUN instructions in the classic builder.
+type MountCache struct {
+	root     string
+	identity idtools.Identity
+
+	mu    sync.Mutex
+	users map[string]int
+	locks map[string]*sync.Mutex
+}
+
+// NewMountCache returns a MountCache that keeps its directories in root.
+// Directories are created owned by identity.
+func NewMountCache(root string, identity idtools.Identity) (*MountCache, error) {
+	if err := idtools.MkdirAllAndChown(root, 0700, identity); err != nil {
+		return nil, errors.Wrap(err, "failed to create cache mount root")
+	}
+	// private directories are not reused across daemon restarts
+	dirs, err := ioutil.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dirs {
+		if strings.Contains(d.Name(), privateMountSuffix) {
+			if err := os.RemoveAll(filepath.Join(root, d.Name())); err != nil {
+				logrus.WithError(err).Warnf("failed to remove cache mount %s", d.Name())
+			}
+		}
+	}
+	return &MountCache{
+		root:     root,
+		identity: identity,
+		users:    make(map[string]int),
+		locks:    make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// Get returns the path of the cache directory for id, creating it if needed.
+// With the locked sharing mode, Get blocks until no other build uses the
+// directory. With the private sharing mode, a new empty directory is returned
+// if the directory is already in use. The release function must be called
+// once the directory is no longer used.
+func (mc *MountCache) Get(ctx context.Context, id, sharing string) (string, func(), error) {
+	key := mountCacheKey(id)
+	p := filepath.Join(mc.root, key)
+
+	switch sharing {
+	case "", MountSharingShared:
+	case MountSharingLocked:
+		mc.mu.Lock()
+		l, ok := mc.locks[key]
+		if !ok {
+			l = &sync.Mutex{}
+			mc.locks[key] = l
+		}
+		mc.mu.Unlock()
+		locked := make(chan struct{})
+		go func() {
+			l.Lock()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+		case <-ctx.Done():
+			go func() {
+				<-locked
+				l.Unlock()
+			}()
+			return "", nil, ctx.Err()
+		}
+		release, err := mc.acquire(key, p)
+		if err != nil {
+			l.Unlock()
+			return "", nil, err
+		}
+		return p, func() {
+			release()
+			l.Unlock()
+		}, nil
+	case MountSharingPrivate:
+		mc.mu.Lock()
+		inUse := mc.users[key] > 0
+		mc.mu.Unlock()
+		if inUse {
+			dir, err := ioutil.TempDir(mc.root, key+privateMountSuffix)
+			if err != nil {
+				return "", nil, err
+			}
+			if err := os.Chown(dir, mc.identity.UID, mc.identity.GID); err != nil {
+				os.RemoveAll(dir)
+				return "", nil, err
+			}
+			return dir, func() {
+				if err := os.RemoveAll(dir); err != nil {
+					logrus.WithError(err).Warnf("failed to remove cache mount %s", dir)
+				}
+			}, nil
+		}
+	default:
+		return "", nil, errors.Errorf("invalid cache mount sharing mode %q", sharing)
+	}
+
+	release, err := mc.acquire(key, p)
+	if err != nil {
+		return "", nil, err
+	}
+	return p, release, nil
+}
+
+func (mc *MountCache) acquire(key, p string) (func(), error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if err := idtools.MkdirAndChown(p, 0755, mc.identity); err != nil && !os.IsExist(err) {
+		return nil, errors.Wrap(err, "failed to create cache mount")
+	}
+	mc.users[key]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			mc.mu.Lock()
+			defer mc.mu.Unlock()
+			if mc.users[key]--; mc.users[key] == 0 {
+				delete(mc.users, key)
+			}
+		})
+	}, nil
+}
+
+// DiskUsage returns the space used by the cache mounts.
+func (mc *MountCache) DiskUsage(ctx context.Context) (int64, error) {
+	return directory.Size(ctx, mc.root)
+}
+
+// Prune removes the cache directories that are not in use and returns the
+// space reclaimed.
+func (mc *MountCache) Prune(ctx context.Context) (uint64, error) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	dirs, err := ioutil.ReadDir(mc.root)
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, d := range dirs {
+		if ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+		if !d.IsDir() || strings.Contains(d.Name(), privateMountSuffix) || mc.users[d.Name()] > 0 {
+			continue
+		}
+		p := filepath.Join(mc.root, d.Name())
+		size, err := directory.Size(ctx, p)
+		if err != nil {
+			return total, err
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return total, err
+		}
+		total += uint64(size)
+	}
+	return total, nil
+}
+
+// mountCacheKey returns the name of the directory of the cache mount id, which
+// may contain characters that are not valid in paths.
+func mountCacheKey(id string) string {
+	h := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,85 @@
+package fscache // import "github.com/docker/docker/builder/fscache"
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/pkg/idtools"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestMountCache(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "mountcache")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	mc, err := NewMountCache(tmpDir, idtools.Identity{UID: os.Getuid(), GID: os.Getgid()})
+	assert.NilError(t, err)
+
+	p1, release1, err := mc.Get(context.TODO(), "/root/.m2", MountSharingShared)
+	assert.NilError(t, err)
+	err = ioutil.WriteFile(filepath.Join(p1, "foo"), []byte("data"), 0600)
+	assert.NilError(t, err)
+
+	// shared directories are reused while in use
+	p2, release2, err := mc.Get(context.TODO(), "/root/.m2", "")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(p1, p2))
+
+	// private directories are not
+	p3, release3, err := mc.Get(context.TODO(), "/root/.m2", MountSharingPrivate)
+	assert.NilError(t, err)
+	assert.Check(t, p1 != p3)
+	release3()
+	_, err = os.Stat(p3)
+	assert.Check(t, os.IsNotExist(err))
+
+	// directories in use are not pruned
+	size, err := mc.Prune(context.TODO())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(size, uint64(0)))
+
+	release1()
+	release2()
+
+	// released private mounts reuse the shared directory
+	p4, release4, err := mc.Get(context.TODO(), "/root/.m2", MountSharingPrivate)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(p1, p4))
+	release4()
+
+	size, err = mc.Prune(context.TODO())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(size, uint64(4)))
+	_, err = os.Stat(p1)
+	assert.Check(t, os.IsNotExist(err))
+
+	_, _, err = mc.Get(context.TODO(), "/root/.m2", "invalid")
+	assert.Check(t, is.ErrorContains(err, "invalid cache mount sharing mode"))
+}
+
+func TestMountCacheLocked(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "mountcache")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	mc, err := NewMountCache(tmpDir, idtools.Identity{UID: os.Getuid(), GID: os.Getgid()})
+	assert.NilError(t, err)
+
+	_, release, err := mc.Get(context.TODO(), "apt", MountSharingLocked)
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, _, err = mc.Get(ctx, "apt", MountSharingLocked)
+	assert.Check(t, is.Equal(err, context.Canceled))
+
+	release()
+	_, release, err = mc.Get(context.TODO(), "apt", MountSharingLocked)
+	assert.NilError(t, err)
+	release()
+}
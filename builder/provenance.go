@@ -0,0 +1,91 @@
+package builder // import "github.com/docker/docker/builder"
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/pkg/urlutil"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// proxyBuildArgs are the predefined build args that are left out of the
+// provenance of builds, as proxy URLs may contain credentials.
+var proxyBuildArgs = map[string]bool{
+	"HTTP_PROXY":  true,
+	"HTTPS_PROXY": true,
+	"FTP_PROXY":   true,
+	"NO_PROXY":    true,
+}
+
+// ProvenanceRecorder records the provenance of a build. It is safe for
+// concurrent use by the stages of the build.
+type ProvenanceRecorder struct {
+	mu         sync.Mutex
+	provenance imagetypes.Provenance
+}
+
+// NewProvenanceRecorder starts recording the provenance of a build run by
+// the builder name with options.
+func NewProvenanceRecorder(name string, options *types.ImageBuildOptions) *ProvenanceRecorder {
+	p := imagetypes.Provenance{
+		Builder:    name,
+		Dockerfile: options.Dockerfile,
+		Target:     options.Target,
+		Context:    provenanceContext(options.RemoteContext),
+		StartedAt:  time.Now().UTC(),
+	}
+	for k, v := range options.BuildArgs {
+		if v == nil || proxyBuildArgs[strings.ToUpper(k)] {
+			continue
+		}
+		if p.BuildArgs == nil {
+			p.BuildArgs = make(map[string]string)
+		}
+		p.BuildArgs[k] = *v
+	}
+	return &ProvenanceRecorder{provenance: p}
+}
+
+func provenanceContext(remoteContext string) *imagetypes.ProvenanceContext {
+	switch {
+	case remoteContext == "":
+		return &imagetypes.ProvenanceContext{Type: "archive"}
+	case remoteContext == "client-session":
+		return &imagetypes.ProvenanceContext{Type: "session"}
+	case urlutil.IsGitURL(remoteContext):
+		return &imagetypes.ProvenanceContext{Type: "git", URL: remoteContext}
+	default:
+		return &imagetypes.ProvenanceContext{Type: "url", URL: remoteContext}
+	}
+}
+
+// AddBaseImage records that the build used the image name, whose config has
+// the digest dgst, as a base image.
+func (r *ProvenanceRecorder) AddBaseImage(name string, dgst digest.Digest) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, base := range r.provenance.BaseImages {
+		if base.Name == name && base.Digest == dgst.String() {
+			return
+		}
+	}
+	r.provenance.BaseImages = append(r.provenance.BaseImages, imagetypes.ProvenanceBaseImage{Name: name, Digest: dgst.String()})
+}
+
+// Provenance returns the provenance recorded so far, with the build marked
+// as finished now.
+func (r *ProvenanceRecorder) Provenance() *imagetypes.Provenance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p := r.provenance
+	p.BaseImages = append([]imagetypes.ProvenanceBaseImage(nil), r.provenance.BaseImages...)
+	if r.provenance.Context != nil {
+		c := *r.provenance.Context
+		p.Context = &c
+	}
+	p.FinishedAt = time.Now().UTC()
+	return &p
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/docker/docker/pkg/fileutils"
 	"github.com/docker/docker/pkg/urlutil"
 	"github.com/moby/buildkit/frontend/dockerfile/parser"
+	"github.com/opencontainers/go-digest"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -23,134 +24,195 @@ import (
 const ClientSessionRemote = "client-session"
 
 // Detect returns a context and dockerfile from remote location or local
-// archive.
-func Detect(config backend.BuildConfig) (remote builder.Source, dockerfile *parser.Result, err error) {
+// archive, along with the digest of the content of the dockerfile.
+func Detect(config backend.BuildConfig) (remote builder.Source, dockerfile *parser.Result, dgst digest.Digest, err error) {
 	remoteURL := config.Options.RemoteContext
 	dockerfilePath := config.Options.Dockerfile
 
 	switch {
 	case remoteURL == "":
-		remote, dockerfile, err = newArchiveRemote(config.Source, dockerfilePath)
+		remote, dockerfile, dgst, err = newArchiveRemote(config.Source, dockerfilePath)
 	case remoteURL == ClientSessionRemote:
-		res, err := parser.Parse(config.Source)
+		res, dgst, err := parseDockerfile(config.Source)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, "", err
 		}
-		return nil, res, nil
+		return nil, res, dgst, nil
 	case urlutil.IsGitURL(remoteURL):
-		remote, dockerfile, err = newGitRemote(remoteURL, dockerfilePath)
+		remote, dockerfile, dgst, err = newGitRemote(remoteURL, dockerfilePath)
 	case urlutil.IsURL(remoteURL):
-		remote, dockerfile, err = newURLRemote(remoteURL, dockerfilePath, config.ProgressWriter.ProgressReaderFunc)
+		remote, dockerfile, dgst, err = newURLRemote(remoteURL, dockerfilePath, config.ProgressWriter.ProgressReaderFunc)
 	default:
 		err = fmt.Errorf("remoteURL (%s) could not be recognized as URL", remoteURL)
 	}
 	return
 }
 
-func newArchiveRemote(rc io.ReadCloser, dockerfilePath string) (builder.Source, *parser.Result, error) {
+func newArchiveRemote(rc io.ReadCloser, dockerfilePath string) (builder.Source, *parser.Result, digest.Digest, error) {
 	defer rc.Close()
 	c, err := FromArchive(rc)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 
 	return withDockerfileFromContext(c.(modifiableContext), dockerfilePath)
 }
 
-func withDockerfileFromContext(c modifiableContext, dockerfilePath string) (builder.Source, *parser.Result, error) {
+func withDockerfileFromContext(c modifiableContext, dockerfilePath string) (builder.Source, *parser.Result, digest.Digest, error) {
+	res, dgst, dockerfilePath, err := readDockerfile(c, dockerfilePath)
+	if err != nil {
+		c.Close()
+		return nil, nil, "", err
+	}
+
+	if err := removeDockerfile(c, dockerfilePath); err != nil {
+		c.Close()
+		return nil, nil, "", err
+	}
+
+	return c, res, dgst, nil
+}
+
+// ErrDockerfileIgnored is returned by DockerfileFromSource if the
+// .dockerignore file of the context excludes the Dockerfile or itself.
+var ErrDockerfileIgnored = errors.New("Dockerfile or .dockerignore is excluded by .dockerignore")
+
+// DockerfileFromSource reads and parses the Dockerfile at dockerfilePath in the
+// context c, along with the digest of its content. Unlike Detect, it does not
+// modify c, so it returns ErrDockerfileIgnored if files would have to be
+// removed from c.
+func DockerfileFromSource(c builder.Source, dockerfilePath string) (*parser.Result, digest.Digest, error) {
+	res, dgst, dockerfilePath, err := readDockerfile(c, dockerfilePath)
+	if err != nil {
+		return nil, "", err
+	}
+	ignored, err := ignoredFiles(c, dockerfilePath)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(ignored) > 0 {
+		return nil, "", ErrDockerfileIgnored
+	}
+	return res, dgst, nil
+}
+
+// readDockerfile reads and parses the Dockerfile at dockerfilePath in c, and
+// returns the path it was found at.
+func readDockerfile(c builder.Source, dockerfilePath string) (*parser.Result, digest.Digest, string, error) {
 	df, err := openAt(c, dockerfilePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			if dockerfilePath == builder.DefaultDockerfileName {
 				lowercase := strings.ToLower(dockerfilePath)
 				if _, err := StatAt(c, lowercase); err == nil {
-					return withDockerfileFromContext(c, lowercase)
+					return readDockerfile(c, lowercase)
 				}
 			}
-			return nil, nil, errors.Errorf("Cannot locate specified Dockerfile: %s", dockerfilePath) // backwards compatible error
+			return nil, "", "", errors.Errorf("Cannot locate specified Dockerfile: %s", dockerfilePath) // backwards compatible error
 		}
-		c.Close()
-		return nil, nil, err
+		return nil, "", "", err
 	}
+	defer df.Close()
 
-	res, err := readAndParseDockerfile(dockerfilePath, df)
+	res, dgst, err := readAndParseDockerfile(dockerfilePath, df)
 	if err != nil {
-		return nil, nil, err
-	}
-
-	df.Close()
-
-	if err := removeDockerfile(c, dockerfilePath); err != nil {
-		c.Close()
-		return nil, nil, err
+		return nil, "", "", err
 	}
-
-	return c, res, nil
+	return res, dgst, dockerfilePath, nil
 }
 
-func newGitRemote(gitURL string, dockerfilePath string) (builder.Source, *parser.Result, error) {
+func newGitRemote(gitURL string, dockerfilePath string) (builder.Source, *parser.Result, digest.Digest, error) {
 	c, err := MakeGitContext(gitURL) // TODO: change this to NewLazySource
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 	return withDockerfileFromContext(c.(modifiableContext), dockerfilePath)
 }
 
-func newURLRemote(url string, dockerfilePath string, progressReader func(in io.ReadCloser) io.ReadCloser) (builder.Source, *parser.Result, error) {
+func newURLRemote(url string, dockerfilePath string, progressReader func(in io.ReadCloser) io.ReadCloser) (builder.Source, *parser.Result, digest.Digest, error) {
 	contentType, content, err := downloadRemote(url)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 	defer content.Close()
 
 	switch contentType {
 	case mimeTypes.TextPlain:
-		res, err := parser.Parse(progressReader(content))
-		return nil, res, err
+		res, dgst, err := parseDockerfile(progressReader(content))
+		return nil, res, dgst, err
 	default:
 		source, err := FromArchive(progressReader(content))
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, "", err
 		}
 		return withDockerfileFromContext(source.(modifiableContext), dockerfilePath)
 	}
 }
 
 func removeDockerfile(c modifiableContext, filesToRemove ...string) error {
+	ignored, err := ignoredFiles(c, filesToRemove...)
+	if err != nil {
+		return err
+	}
+	for _, fileToRemove := range ignored {
+		if err := c.Remove(fileToRemove); err != nil {
+			logrus.Errorf("failed to remove %s: %v", fileToRemove, err)
+		}
+	}
+	return nil
+}
+
+// ignoredFiles returns which of .dockerignore and files are excluded by the
+// .dockerignore file of c.
+func ignoredFiles(c builder.Source, files ...string) ([]string, error) {
 	f, err := openAt(c, ".dockerignore")
 	// Note that a missing .dockerignore file isn't treated as an error
 	switch {
 	case os.IsNotExist(err):
-		return nil
+		return nil, nil
 	case err != nil:
-		return err
+		return nil, err
 	}
 	excludes, err := dockerignore.ReadAll(f)
 	if err != nil {
 		f.Close()
-		return err
+		return nil, err
 	}
 	f.Close()
-	filesToRemove = append([]string{".dockerignore"}, filesToRemove...)
-	for _, fileToRemove := range filesToRemove {
-		if rm, _ := fileutils.Matches(fileToRemove, excludes); rm {
-			if err := c.Remove(fileToRemove); err != nil {
-				logrus.Errorf("failed to remove %s: %v", fileToRemove, err)
-			}
+	var ignored []string
+	for _, file := range append([]string{".dockerignore"}, files...) {
+		if rm, _ := fileutils.Matches(file, excludes); rm {
+			ignored = append(ignored, file)
 		}
 	}
-	return nil
+	return ignored, nil
 }
 
-func readAndParseDockerfile(name string, rc io.Reader) (*parser.Result, error) {
+func readAndParseDockerfile(name string, rc io.Reader) (*parser.Result, digest.Digest, error) {
 	br := bufio.NewReader(rc)
 	if _, err := br.Peek(1); err != nil {
 		if err == io.EOF {
-			return nil, errors.Errorf("the Dockerfile (%s) cannot be empty", name)
+			return nil, "", errors.Errorf("the Dockerfile (%s) cannot be empty", name)
 		}
-		return nil, errors.Wrap(err, "unexpected error reading Dockerfile")
+		return nil, "", errors.Wrap(err, "unexpected error reading Dockerfile")
+	}
+	return parseDockerfile(br)
+}
+
+// parseDockerfile parses the Dockerfile read from r and returns the digest of
+// its content.
+func parseDockerfile(r io.Reader) (*parser.Result, digest.Digest, error) {
+	digester := digest.Canonical.Digester()
+	res, err := parser.Parse(io.TeeReader(r, digester.Hash()))
+	if err != nil {
+		return nil, "", err
+	}
+	// read what the parser left, if anything, so that the digest covers
+	// the whole content
+	if _, err := io.Copy(digester.Hash(), r); err != nil {
+		return nil, "", errors.Wrap(err, "unexpected error reading Dockerfile")
 	}
-	return parser.Parse(br)
+	return res, digester.Digest(), nil
 }
 
 func openAt(remote builder.Source, path string) (driver.File, error) {
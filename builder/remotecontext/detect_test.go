@@ -6,10 +6,12 @@ import (
 	"log"
 	"os"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/pkg/containerfs"
+	"github.com/opencontainers/go-digest"
 )
 
 const (
@@ -103,6 +105,44 @@ func TestProcessShouldLeaveAllFiles(t *testing.T) {
 
 }
 
+func TestParseDockerfileDigest(t *testing.T) {
+	content := dockerfileContents + "\n# trailing comment\n"
+	res, dgst, err := parseDockerfile(strings.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.AST.Children) != 1 {
+		t.Fatalf("expected 1 instruction, got %d", len(res.AST.Children))
+	}
+	if expected := digest.FromString(content); dgst != expected {
+		t.Fatalf("expected digest %s, got %s", expected, dgst)
+	}
+}
+
+func TestDockerfileFromSource(t *testing.T) {
+	contextDir, cleanup := createTestTempDir(t, "", "builder-dockerfile-from-source-test")
+	defer cleanup()
+
+	createTestTempFile(t, contextDir, shouldStayFilename, testfileContents, 0777)
+	createTestTempFile(t, contextDir, builder.DefaultDockerfileName, dockerfileContents, 0777)
+	createTestTempFile(t, contextDir, dockerignoreFilename, "input1\ninput2", 0777)
+
+	src := &stubRemote{root: containerfs.NewLocalContainerFS(contextDir)}
+	_, dgst, err := DockerfileFromSource(src, builder.DefaultDockerfileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := digest.FromString(dockerfileContents); dgst != expected {
+		t.Fatalf("expected digest %s, got %s", expected, dgst)
+	}
+
+	createTestTempFile(t, contextDir, dockerignoreFilename, "Dockerfile", 0777)
+	if _, _, err := DockerfileFromSource(src, builder.DefaultDockerfileName); err != ErrDockerfileIgnored {
+		t.Fatalf("expected %v, got %v", ErrDockerfileIgnored, err)
+	}
+	checkDirectory(t, contextDir, []string{shouldStayFilename, builder.DefaultDockerfileName, dockerignoreFilename})
+}
+
 // TODO: remove after moving to a separate pkg
 type stubRemote struct {
 	root containerfs.ContainerFS
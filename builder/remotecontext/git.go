@@ -16,6 +16,11 @@ func MakeGitContext(gitURL string) (builder.Source, error) {
 		return nil, err
 	}
 
+	commit, err := git.Commit(root)
+	if err != nil {
+		return nil, err
+	}
+
 	c, err := archive.Tar(root, archive.Uncompressed)
 	if err != nil {
 		return nil, err
@@ -31,5 +36,20 @@ func MakeGitContext(gitURL string) (builder.Source, error) {
 			logrus.WithField("action", "MakeGitContext").WithField("module", "builder").WithField("url", gitURL).WithError(err).Error("error while removing path and children of root")
 		}
 	}()
-	return FromArchive(c)
+	source, err := FromArchive(c)
+	if err != nil {
+		return nil, err
+	}
+	return &gitSource{modifiableContext: source.(modifiableContext), commit: commit}, nil
+}
+
+// gitSource is a context cloned from a git repository.
+type gitSource struct {
+	modifiableContext
+	commit string
+}
+
+// GitCommit returns the commit the context was cloned at.
+func (s *gitSource) GitCommit() string {
+	return s.commit
 }
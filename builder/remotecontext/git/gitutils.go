@@ -32,6 +32,15 @@ func Clone(remoteURL string) (string, error) {
 	return cloneGitRepo(repo)
 }
 
+// Commit returns the commit checked out in dir, a directory returned by Clone.
+func Commit(dir string) (string, error) {
+	out, err := git("-C", dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get commit of %s: %s", dir, out)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 func cloneGitRepo(repo gitRepo) (checkoutDir string, err error) {
 	fetch := fetchArgs(repo.remote, repo.ref)
 
@@ -0,0 +1,247 @@
+package git // import "github.com/docker/docker/builder/remotecontext/git"
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/pkg/directory"
+	"github.com/docker/docker/pkg/symlink"
+	"github.com/pkg/errors"
+)
+
+// Mirrors keeps bare mirrors of remote repositories, so that repeated
+// builds of a repository only fetch the objects they do not have yet.
+type Mirrors struct {
+	root    string
+	mu      sync.Mutex
+	mirrors map[string]*mirror
+}
+
+type mirror struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewMirrors returns a Mirrors that keeps its mirrors in root.
+func NewMirrors(root string) (*Mirrors, error) {
+	// remove the checkouts left over by a previous run of the daemon
+	if err := os.RemoveAll(filepath.Join(root, "tmp")); err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{"mirrors", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0700); err != nil {
+			return nil, err
+		}
+	}
+	return &Mirrors{root: root, mirrors: make(map[string]*mirror)}, nil
+}
+
+// Resolve fetches the ref of remoteURL in the mirror of its repository, and
+// returns remoteURL with the ref replaced by the commit it resolved to, along
+// with that commit.
+func (m *Mirrors) Resolve(remoteURL string) (resolvedURL string, commit string, err error) {
+	repo, err := parseRemoteURL(remoteURL)
+	if err != nil {
+		return "", "", err
+	}
+	commit, err = m.resolve(repo)
+	if err != nil {
+		return "", "", err
+	}
+	resolvedURL = repo.remote + "#" + commit
+	if repo.subdir != "" {
+		resolvedURL += ":" + repo.subdir
+	}
+	return resolvedURL, commit, nil
+}
+
+// Checkout writes the context of resolvedURL, as returned by Resolve, to dest.
+func (m *Mirrors) Checkout(resolvedURL, dest string) error {
+	repo, err := parseRemoteURL(resolvedURL)
+	if err != nil {
+		return err
+	}
+	return m.checkout(repo, dest)
+}
+
+// DiskUsage returns the size of the mirrors.
+func (m *Mirrors) DiskUsage(ctx context.Context) (int64, error) {
+	return directory.Size(ctx, filepath.Join(m.root, "mirrors"))
+}
+
+// Prune removes the mirrors that are not in use, and returns the size of the
+// removed mirrors.
+func (m *Mirrors) Prune(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dirs, err := ioutil.ReadDir(filepath.Join(m.root, "mirrors"))
+	if err != nil {
+		return 0, err
+	}
+	var pruned uint64
+	for _, d := range dirs {
+		select {
+		case <-ctx.Done():
+			return pruned, ctx.Err()
+		default:
+		}
+		if _, ok := m.mirrors[d.Name()]; ok {
+			continue
+		}
+		dir := filepath.Join(m.root, "mirrors", d.Name())
+		size, err := directory.Size(ctx, dir)
+		if err != nil {
+			return pruned, err
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return pruned, errors.WithStack(err)
+		}
+		pruned += uint64(size)
+	}
+	return pruned, nil
+}
+
+// lock locks the mirror of repo against concurrent fetches and pruning, and
+// returns its directory along with a function to unlock it.
+func (m *Mirrors) lock(repo gitRepo) (string, func()) {
+	h := sha256.Sum256([]byte(repo.remote))
+	id := hex.EncodeToString(h[:])
+
+	m.mu.Lock()
+	mr, ok := m.mirrors[id]
+	if !ok {
+		mr = &mirror{}
+		m.mirrors[id] = mr
+	}
+	mr.refs++
+	m.mu.Unlock()
+
+	mr.mu.Lock()
+	return filepath.Join(m.root, "mirrors", id), func() {
+		mr.mu.Unlock()
+		m.mu.Lock()
+		mr.refs--
+		if mr.refs == 0 {
+			delete(m.mirrors, id)
+		}
+		m.mu.Unlock()
+	}
+}
+
+func (m *Mirrors) resolve(repo gitRepo) (string, error) {
+	dir, unlock := m.lock(repo)
+	defer unlock()
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := initMirror(dir, repo.remote); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+
+	if out, err := git("--git-dir", dir, "fetch", "--force", "origin", repo.ref); err != nil {
+		// the ref may be a commit that the remote does not let us fetch
+		// directly, but that a previous fetch brought in
+		if commit, err2 := revParse(dir, repo.ref+"^{commit}"); err2 == nil {
+			return commit, nil
+		}
+		return "", errors.Wrapf(err, "error fetching: %s", out)
+	}
+	commit, err := revParse(dir, "FETCH_HEAD^{commit}")
+	if err != nil {
+		return "", err
+	}
+	// keep the commit referenced until the ref is fetched again, so that
+	// it is not garbage collected from the mirror
+	h := sha256.Sum256([]byte(repo.ref))
+	if out, err := git("--git-dir", dir, "update-ref", "refs/docker/"+hex.EncodeToString(h[:]), commit); err != nil {
+		return "", errors.Wrapf(err, "failed to reference %s: %s", commit, out)
+	}
+	return commit, nil
+}
+
+func initMirror(dir, remote string) error {
+	if out, err := git("init", "--bare", dir); err != nil {
+		return errors.Wrapf(err, "failed to init mirror at %s: %s", dir, out)
+	}
+	if out, err := git("--git-dir", dir, "remote", "add", "origin", remote); err != nil {
+		return errors.Wrapf(err, "failed add origin repo at %s: %s", remote, out)
+	}
+	// let checkouts fetch commits that no branch of the mirror points to
+	if out, err := git("--git-dir", dir, "config", "uploadpack.allowAnySHA1InWant", "true"); err != nil {
+		return errors.Wrapf(err, "failed to configure mirror at %s: %s", dir, out)
+	}
+	return nil
+}
+
+func revParse(dir, rev string) (string, error) {
+	out, err := git("--git-dir", dir, "rev-parse", "--verify", "--quiet", rev)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to resolve %s: %s", rev, out)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkout writes the context of repo, whose ref is a commit in its mirror,
+// to dest. Like Clone, it makes a shallow clone, with the remote of repo as
+// origin, so that the context does not depend on the mirror.
+func (m *Mirrors) checkout(repo gitRepo, dest string) error {
+	root, err := ioutil.TempDir(filepath.Join(m.root, "tmp"), "docker-build-git")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(root)
+
+	if out, err := gitWithinDir(root, "init"); err != nil {
+		return errors.Wrapf(err, "failed to init repo at %s: %s", root, out)
+	}
+	if out, err := gitWithinDir(root, "remote", "add", "origin", repo.remote); err != nil {
+		return errors.Wrapf(err, "failed add origin repo at %s: %s", repo.remote, out)
+	}
+
+	dir, unlock := m.lock(repo)
+	out, err := gitWithinDir(root, "fetch", "--depth", "1", "file://"+filepath.ToSlash(dir), repo.ref)
+	unlock()
+	if err != nil {
+		return errors.Wrapf(err, "error fetching %s from mirror: %s", repo.ref, out)
+	}
+
+	if out, err := gitWithinDir(root, "checkout", "FETCH_HEAD"); err != nil {
+		return errors.Wrapf(err, "error checking out %s: %s", repo.ref, out)
+	}
+	if out, err := git("-C", root, "submodule", "update", "--init", "--recursive", "--depth=1"); err != nil {
+		return errors.Wrapf(err, "error initializing submodules: %s", out)
+	}
+
+	src := root
+	if repo.subdir != "" {
+		if src, err = symlink.FollowSymlinkInScope(filepath.Join(root, repo.subdir), root); err != nil {
+			return errors.Wrapf(err, "error setting git context, %q not within git root", repo.subdir)
+		}
+		fi, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return errors.Errorf("error setting git context, not a directory: %s", src)
+		}
+	}
+
+	files, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, fi := range files {
+		if err := os.Rename(filepath.Join(src, fi.Name()), filepath.Join(dest, fi.Name())); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
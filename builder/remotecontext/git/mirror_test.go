@@ -0,0 +1,79 @@
+package git // import "github.com/docker/docker/builder/remotecontext/git"
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestMirrors(t *testing.T) {
+	root, err := ioutil.TempDir("", "docker-build-git-mirrors")
+	assert.NilError(t, err)
+	defer os.RemoveAll(root)
+
+	gitDir := filepath.Join(root, "repo")
+	_, err = git("init", gitDir)
+	assert.NilError(t, err)
+	_, err = gitWithinDir(gitDir, "config", "user.email", "test@docker.com")
+	assert.NilError(t, err)
+	_, err = gitWithinDir(gitDir, "config", "user.name", "Docker test")
+	assert.NilError(t, err)
+	_, err = gitWithinDir(gitDir, "checkout", "-b", "master")
+	assert.NilError(t, err)
+
+	commit := func(dockerfile string) string {
+		assert.NilError(t, os.MkdirAll(filepath.Join(gitDir, "subdir"), 0755))
+		err := ioutil.WriteFile(filepath.Join(gitDir, "subdir", "Dockerfile"), []byte(dockerfile), 0644)
+		assert.NilError(t, err)
+		_, err = gitWithinDir(gitDir, "add", "-A")
+		assert.NilError(t, err)
+		_, err = gitWithinDir(gitDir, "commit", "-m", dockerfile)
+		assert.NilError(t, err)
+		out, err := gitWithinDir(gitDir, "rev-parse", "HEAD")
+		assert.NilError(t, err)
+		return string(out[:len(out)-1])
+	}
+
+	m, err := NewMirrors(filepath.Join(root, "mirrors"))
+	assert.NilError(t, err)
+
+	checkout := func(ref, expected string) {
+		repo := gitRepo{remote: gitDir, ref: ref, subdir: "subdir"}
+		commit, err := m.resolve(repo)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(expected, commit))
+
+		dest, err := ioutil.TempDir(root, "dest")
+		assert.NilError(t, err)
+		repo.ref = commit
+		assert.NilError(t, m.checkout(repo, dest))
+		b, err := ioutil.ReadFile(filepath.Join(dest, "Dockerfile"))
+		assert.NilError(t, err)
+		out, err := gitWithinDir(gitDir, "show", commit+":subdir/Dockerfile")
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(string(out), string(b)))
+	}
+
+	first := commit("FROM scratch")
+	checkout("master", first)
+	second := commit("FROM busybox")
+	checkout("master", second)
+	checkout(first, first)
+
+	size, err := m.DiskUsage(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, size > 0)
+
+	pruned, err := m.Prune(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(uint64(size), pruned))
+
+	size, err = m.DiskUsage(context.Background())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(int64(0), size))
+}
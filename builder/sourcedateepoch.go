@@ -0,0 +1,39 @@
+package builder // import "github.com/docker/docker/builder"
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// SourceDateEpochBuildArg is the build arg that makes builds reproducible by
+// clamping the timestamps of the images they produce to its value, a number
+// of seconds since the Unix epoch.
+// See https://reproducible-builds.org/specs/source-date-epoch/.
+const SourceDateEpochBuildArg = "SOURCE_DATE_EPOCH"
+
+// SourceDateEpoch returns the time set with the SOURCE_DATE_EPOCH build arg
+// of a build, or nil if it is not set.
+func SourceDateEpoch(options *types.ImageBuildOptions) (*time.Time, error) {
+	v, ok := options.BuildArgs[SourceDateEpochBuildArg]
+	if !ok || v == nil || *v == "" {
+		return nil, nil
+	}
+	sec, err := strconv.ParseInt(*v, 10, 64)
+	if err != nil || sec < 0 {
+		return nil, errdefs.InvalidParameter(errors.Errorf("invalid %s build arg: %q", SourceDateEpochBuildArg, *v))
+	}
+	epoch := time.Unix(sec, 0).UTC()
+	return &epoch, nil
+}
+
+// ClampTime returns the earliest of t and epoch, or t if epoch is nil.
+func ClampTime(t time.Time, epoch *time.Time) time.Time {
+	if epoch != nil && t.After(*epoch) {
+		return *epoch
+	}
+	return t
+}
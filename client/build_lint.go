@@ -0,0 +1,38 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/docker/docker/api/types"
+)
+
+// DockerfileLint requests the daemon to check the Dockerfile read from
+// dockerfile without building it
+func (cli *Client) DockerfileLint(ctx context.Context, dockerfile io.Reader, options types.DockerfileLintOptions) (types.DockerfileLintReport, error) {
+	var report types.DockerfileLintReport
+	if err := cli.NewVersionError("1.40", "Dockerfile lint"); err != nil {
+		return report, err
+	}
+
+	query := url.Values{}
+	if len(options.Rules) > 0 {
+		rulesJSON, err := json.Marshal(options.Rules)
+		if err != nil {
+			return report, err
+		}
+		query.Set("rules", string(rulesJSON))
+	}
+
+	headers := map[string][]string{"Content-Type": {"text/plain"}}
+	serverResp, err := cli.postRaw(ctx, "/build/lint", query, dockerfile, headers)
+	if err != nil {
+		return report, err
+	}
+	defer ensureReaderClosed(serverResp)
+
+	err = json.NewDecoder(serverResp.body).Decode(&report)
+	return report, err
+}
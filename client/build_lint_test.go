@@ -0,0 +1,79 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func TestDockerfileLintError(t *testing.T) {
+	client := &Client{
+		client:  newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+		version: "1.40",
+	}
+	_, err := client.DockerfileLint(context.Background(), strings.NewReader("FROM busybox"), types.DockerfileLintOptions{})
+	assert.Check(t, is.Error(err, "Error response from daemon: Server error"))
+}
+
+func TestDockerfileLintVersion(t *testing.T) {
+	client := &Client{
+		client:  newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+		version: "1.39",
+	}
+	_, err := client.DockerfileLint(context.Background(), strings.NewReader("FROM busybox"), types.DockerfileLintOptions{})
+	assert.Check(t, is.Error(err, `"Dockerfile lint" requires API version 1.40, but the Docker daemon API version is 1.39`))
+}
+
+func TestDockerfileLint(t *testing.T) {
+	expectedURL := "/v1.40/build/lint"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			if rules := req.URL.Query().Get("rules"); rules != `{"latest-tag":"off"}` {
+				return nil, fmt.Errorf("rules not set in URL query properly. Expected '{\"latest-tag\":\"off\"}', got %s", rules)
+			}
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			if string(body) != "FROM busybox" {
+				return nil, fmt.Errorf("expected Dockerfile in body, got %q", body)
+			}
+			content, err := json.Marshal(types.DockerfileLintReport{
+				Findings: []types.DockerfileLintFinding{
+					{Rule: "missing-healthcheck", Severity: "info", Message: "no healthcheck", Line: 1},
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(content)),
+			}, nil
+		}),
+		version: "1.40",
+	}
+
+	report, err := client.DockerfileLint(context.Background(), strings.NewReader("FROM busybox"), types.DockerfileLintOptions{
+		Rules: map[string]string{"latest-tag": "off"},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(report.Findings, 1))
+	assert.Check(t, is.Equal("missing-healthcheck", report.Findings[0].Rule))
+	assert.Check(t, is.Equal(1, report.Findings[0].Line))
+}
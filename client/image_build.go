@@ -121,6 +121,26 @@ func (cli *Client) imageBuildOptionsToQuery(options types.ImageBuildOptions) (ur
 		return query, err
 	}
 	query.Set("cachefrom", string(cacheFromJSON))
+	if len(options.CacheTo) > 0 {
+		if err := cli.NewVersionError("1.40", "cache-to"); err != nil {
+			return query, err
+		}
+		cacheToJSON, err := json.Marshal(options.CacheTo)
+		if err != nil {
+			return query, err
+		}
+		query.Set("cacheto", string(cacheToJSON))
+	}
+	if len(options.Outputs) > 0 {
+		if err := cli.NewVersionError("1.40", "outputs"); err != nil {
+			return query, err
+		}
+		outputsJSON, err := json.Marshal(options.Outputs)
+		if err != nil {
+			return query, err
+		}
+		query.Set("outputs", string(outputsJSON))
+	}
 	if options.SessionID != "" {
 		query.Set("session", options.SessionID)
 	}
@@ -133,6 +153,12 @@ func (cli *Client) imageBuildOptionsToQuery(options types.ImageBuildOptions) (ur
 	if options.BuildID != "" {
 		query.Set("buildid", options.BuildID)
 	}
+	if options.ProgressEvents {
+		if err := cli.NewVersionError("1.40", "progress events"); err != nil {
+			return query, err
+		}
+		query.Set("progressevents", "1")
+	}
 	query.Set("version", string(options.Version))
 	return query, nil
 }
@@ -155,6 +155,38 @@ func TestImageBuild(t *testing.T) {
 			expectedTags:           []string{},
 			expectedRegistryConfig: "eyJodHRwczovL2luZGV4LmRvY2tlci5pby92MS8iOnsiYXV0aCI6ImRHOTBid289In19",
 		},
+		{
+			buildOptions: types.ImageBuildOptions{
+				CacheTo: []string{"type=registry,ref=example.com/foo:cache,mode=max"},
+			},
+			expectedQueryParams: map[string]string{
+				"cacheto": `["type=registry,ref=example.com/foo:cache,mode=max"]`,
+			},
+			expectedTags:           []string{},
+			expectedRegistryConfig: emptyRegistryConfig,
+		},
+		{
+			buildOptions: types.ImageBuildOptions{
+				Outputs: []types.ImageBuildOutput{
+					{Type: "local", Attrs: map[string]string{"dest": "out"}},
+				},
+			},
+			expectedQueryParams: map[string]string{
+				"outputs": `[{"Type":"local","Attrs":{"dest":"out"}}]`,
+			},
+			expectedTags:           []string{},
+			expectedRegistryConfig: emptyRegistryConfig,
+		},
+		{
+			buildOptions: types.ImageBuildOptions{
+				ProgressEvents: true,
+			},
+			expectedQueryParams: map[string]string{
+				"progressevents": "1",
+			},
+			expectedTags:           []string{},
+			expectedRegistryConfig: emptyRegistryConfig,
+		},
 	}
 	for _, buildCase := range buildCases {
 		expectedURL := "/build"
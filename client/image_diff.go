@@ -0,0 +1,27 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+// ImageDiff returns the paths that were added, modified or deleted in the
+// filesystem of image to, compared to image from.
+func (cli *Client) ImageDiff(ctx context.Context, from, to string) ([]image.DiffResponseItem, error) {
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+
+	var diff []image.DiffResponseItem
+	serverResp, err := cli.get(ctx, "/images/diff", query, nil)
+	if err != nil {
+		return diff, err
+	}
+
+	err = json.NewDecoder(serverResp.body).Decode(&diff)
+	ensureReaderClosed(serverResp)
+	return diff, err
+}
@@ -0,0 +1,69 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+func TestImageDiffError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+	_, err := client.ImageDiff(context.Background(), "from", "to")
+	if err == nil || err.Error() != "Error response from daemon: Server error" {
+		t.Fatalf("expected a Server error, got %v", err)
+	}
+}
+
+func TestImageDiff(t *testing.T) {
+	expectedURL := "/images/diff"
+	client := &Client{
+		client: newMockClient(func(r *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(r.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, r.URL)
+			}
+			query := r.URL.Query()
+			if from := query.Get("from"); from != "image_from" {
+				return nil, fmt.Errorf("from not set in URL query properly. Expected 'image_from', got %s", from)
+			}
+			if to := query.Get("to"); to != "image_to" {
+				return nil, fmt.Errorf("to not set in URL query properly. Expected 'image_to', got %s", to)
+			}
+			b, err := json.Marshal([]image.DiffResponseItem{
+				{
+					Kind:   0,
+					Path:   "/etc/hosts",
+					Size:   12,
+					Digest: "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+				},
+				{
+					Kind: 2,
+					Path: "/tmp",
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+	diff, err := client.ImageDiff(context.Background(), "image_from", "image_to")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff) != 2 {
+		t.Fatalf("expected 2 changes, got %v", diff)
+	}
+}
@@ -0,0 +1,27 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+// ImageFiles lists the directory at path in the filesystem of an image.
+func (cli *Client) ImageFiles(ctx context.Context, imageID, path string) ([]image.FileInfo, error) {
+	query := url.Values{}
+	if path != "" {
+		query.Set("path", path)
+	}
+
+	var files []image.FileInfo
+	serverResp, err := cli.get(ctx, "/images/"+imageID+"/files", query, nil)
+	if err != nil {
+		return files, err
+	}
+
+	err = json.NewDecoder(serverResp.body).Decode(&files)
+	ensureReaderClosed(serverResp)
+	return files, err
+}
@@ -0,0 +1,69 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/image"
+)
+
+func TestImageFilesError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+	_, err := client.ImageFiles(context.Background(), "nothing", "/")
+	if err == nil || err.Error() != "Error response from daemon: Server error" {
+		t.Fatalf("expected a Server error, got %v", err)
+	}
+}
+
+func TestImageFiles(t *testing.T) {
+	expectedURL := "/images/image_id/files"
+	client := &Client{
+		client: newMockClient(func(r *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(r.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, r.URL)
+			}
+			if path := r.URL.Query().Get("path"); path != "/etc" {
+				return nil, fmt.Errorf("path not set in URL query properly. Expected '/etc', got %s", path)
+			}
+			b, err := json.Marshal([]image.FileInfo{
+				{
+					Name: "hosts",
+					Size: 12,
+					Mode: 0644,
+				},
+				{
+					Name:       "mtab",
+					Mode:       os.ModeSymlink | 0777,
+					LinkTarget: "/proc/mounts",
+				},
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+	files, err := client.ImageFiles(context.Background(), "image_id", "/etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+	if files[1].Mode&os.ModeSymlink == 0 || files[1].LinkTarget != "/proc/mounts" {
+		t.Fatalf("expected mtab to be a symlink to /proc/mounts, got %v", files[1])
+	}
+}
@@ -0,0 +1,27 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ImageRebase creates a new image from an image, replacing the layers and
+// history of its base image with those of another base image.
+func (cli *Client) ImageRebase(ctx context.Context, image string, options types.ImageRebaseOptions) (types.IDResponse, error) {
+	query := url.Values{}
+	query.Set("old-base", options.OldBase)
+	query.Set("new-base", options.NewBase)
+
+	var response types.IDResponse
+	resp, err := cli.post(ctx, "/images/"+image+"/rebase", query, nil, nil)
+	if err != nil {
+		return response, err
+	}
+
+	err = json.NewDecoder(resp.body).Decode(&response)
+	ensureReaderClosed(resp)
+	return response, err
+}
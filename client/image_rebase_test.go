@@ -0,0 +1,66 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestImageRebaseError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+	_, err := client.ImageRebase(context.Background(), "image_id", types.ImageRebaseOptions{})
+	if err == nil || err.Error() != "Error response from daemon: Server error" {
+		t.Fatalf("expected a Server Error, got %v", err)
+	}
+}
+
+func TestImageRebase(t *testing.T) {
+	expectedURL := "/images/image_id/rebase"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			query := req.URL.Query()
+			if oldBase := query.Get("old-base"); oldBase != "base:1" {
+				return nil, fmt.Errorf("old-base not set in URL query properly. Expected 'base:1', got %s", oldBase)
+			}
+			if newBase := query.Get("new-base"); newBase != "base:2" {
+				return nil, fmt.Errorf("new-base not set in URL query properly. Expected 'base:2', got %s", newBase)
+			}
+			b, err := json.Marshal(types.IDResponse{
+				ID: "rebased_image_id",
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+
+	r, err := client.ImageRebase(context.Background(), "image_id", types.ImageRebaseOptions{
+		OldBase: "base:1",
+		NewBase: "base:2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "rebased_image_id" {
+		t.Fatalf("expected `rebased_image_id`, got %s", r.ID)
+	}
+}
@@ -0,0 +1,29 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+)
+
+// ImageSquash creates a new image from an image, merging all its layers, or
+// the layers from options.From upwards, into a single layer.
+func (cli *Client) ImageSquash(ctx context.Context, image string, options types.ImageSquashOptions) (types.IDResponse, error) {
+	query := url.Values{}
+	if options.From != 0 {
+		query.Set("from", strconv.Itoa(options.From))
+	}
+
+	var response types.IDResponse
+	resp, err := cli.post(ctx, "/images/"+image+"/squash", query, nil, nil)
+	if err != nil {
+		return response, err
+	}
+
+	err = json.NewDecoder(resp.body).Decode(&response)
+	ensureReaderClosed(resp)
+	return response, err
+}
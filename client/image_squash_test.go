@@ -0,0 +1,61 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestImageSquashError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+	_, err := client.ImageSquash(context.Background(), "image_id", types.ImageSquashOptions{})
+	if err == nil || err.Error() != "Error response from daemon: Server error" {
+		t.Fatalf("expected a Server Error, got %v", err)
+	}
+}
+
+func TestImageSquash(t *testing.T) {
+	expectedURL := "/images/image_id/squash"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != "POST" {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			if from := req.URL.Query().Get("from"); from != "2" {
+				return nil, fmt.Errorf("from not set in URL query properly. Expected '2', got %s", from)
+			}
+			b, err := json.Marshal(types.IDResponse{
+				ID: "squashed_image_id",
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+
+	r, err := client.ImageSquash(context.Background(), "image_id", types.ImageSquashOptions{
+		From: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != "squashed_image_id" {
+		t.Fatalf("expected `squashed_image_id`, got %s", r.ID)
+	}
+}
@@ -88,7 +88,10 @@ type ImageAPIClient interface {
 	ImageBuild(ctx context.Context, context io.Reader, options types.ImageBuildOptions) (types.ImageBuildResponse, error)
 	BuildCachePrune(ctx context.Context, opts types.BuildCachePruneOptions) (*types.BuildCachePruneReport, error)
 	BuildCancel(ctx context.Context, id string) error
+	DockerfileLint(ctx context.Context, dockerfile io.Reader, options types.DockerfileLintOptions) (types.DockerfileLintReport, error)
 	ImageCreate(ctx context.Context, parentReference string, options types.ImageCreateOptions) (io.ReadCloser, error)
+	ImageDiff(ctx context.Context, from, to string) ([]image.DiffResponseItem, error)
+	ImageFiles(ctx context.Context, imageID, path string) ([]image.FileInfo, error)
 	ImageHistory(ctx context.Context, image string) ([]image.HistoryResponseItem, error)
 	ImageImport(ctx context.Context, source types.ImageImportSource, ref string, options types.ImageImportOptions) (io.ReadCloser, error)
 	ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error)
@@ -96,8 +99,10 @@ type ImageAPIClient interface {
 	ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error)
 	ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error)
 	ImagePush(ctx context.Context, ref string, options types.ImagePushOptions) (io.ReadCloser, error)
+	ImageRebase(ctx context.Context, image string, options types.ImageRebaseOptions) (types.IDResponse, error)
 	ImageRemove(ctx context.Context, image string, options types.ImageRemoveOptions) ([]types.ImageDeleteResponseItem, error)
 	ImageSearch(ctx context.Context, term string, options types.ImageSearchOptions) ([]registry.SearchResult, error)
+	ImageSquash(ctx context.Context, image string, options types.ImageSquashOptions) (types.IDResponse, error)
 	ImageSave(ctx context.Context, images []string) (io.ReadCloser, error)
 	ImageTag(ctx context.Context, image, ref string) error
 	ImagesPrune(ctx context.Context, pruneFilter filters.Args) (types.ImagesPruneReport, error)
@@ -168,6 +173,7 @@ type SystemAPIClient interface {
 	RegistryLogin(ctx context.Context, auth types.AuthConfig) (registry.AuthenticateOKBody, error)
 	DiskUsage(ctx context.Context) (types.DiskUsage, error)
 	Ping(ctx context.Context) (types.Ping, error)
+	SystemFsck(ctx context.Context, options types.FsckOptions) (types.FsckReport, error)
 }
 
 // VolumeAPIClient defines API client methods for the volumes
@@ -178,6 +184,9 @@ type VolumeAPIClient interface {
 	VolumeList(ctx context.Context, filter filters.Args) (volumetypes.VolumeListOKBody, error)
 	VolumeRemove(ctx context.Context, volumeID string, force bool) error
 	VolumesPrune(ctx context.Context, pruneFilter filters.Args) (types.VolumesPruneReport, error)
+	VolumeExport(ctx context.Context, volumeID string) (io.ReadCloser, error)
+	VolumeImport(ctx context.Context, volumeID string, input io.Reader) error
+	VolumeClone(ctx context.Context, volumeID, target string) (types.Volume, error)
 }
 
 // SecretAPIClient defines API client methods for secrets
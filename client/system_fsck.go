@@ -0,0 +1,31 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/docker/docker/api/types"
+)
+
+// SystemFsck requests the daemon to check the integrity of its layer store
+func (cli *Client) SystemFsck(ctx context.Context, options types.FsckOptions) (types.FsckReport, error) {
+	var report types.FsckReport
+	if err := cli.NewVersionError("1.40", "system fsck"); err != nil {
+		return report, err
+	}
+
+	query := url.Values{}
+	if options.Quarantine {
+		query.Set("quarantine", "1")
+	}
+
+	serverResp, err := cli.post(ctx, "/system/fsck", query, nil, nil)
+	if err != nil {
+		return report, err
+	}
+	defer ensureReaderClosed(serverResp)
+
+	err = json.NewDecoder(serverResp.body).Decode(&report)
+	return report, err
+}
@@ -0,0 +1,63 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestSystemFsckError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+	_, err := client.SystemFsck(context.Background(), types.FsckOptions{})
+	if err == nil || err.Error() != "Error response from daemon: Server error" {
+		t.Fatalf("expected a Server Error, got %v", err)
+	}
+}
+
+func TestSystemFsck(t *testing.T) {
+	expectedURL := "/system/fsck"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != http.MethodPost {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			if quarantine := req.URL.Query().Get("quarantine"); quarantine != "1" {
+				return nil, fmt.Errorf("quarantine not set in URL query properly. Expected '1', got %s", quarantine)
+			}
+
+			report := types.FsckReport{
+				LayersChecked:     2,
+				Problems:          []types.FsckProblem{{Type: "corrupt-layer", ID: "sha256:abc", Message: "corrupt"}},
+				QuarantinedLayers: []string{"sha256:abc"},
+			}
+			b, err := json.Marshal(report)
+			if err != nil {
+				return nil, err
+			}
+
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       ioutil.NopCloser(bytes.NewReader(b)),
+			}, nil
+		}),
+	}
+	report, err := client.SystemFsck(context.Background(), types.FsckOptions{Quarantine: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.LayersChecked != 2 || len(report.Problems) != 1 || len(report.QuarantinedLayers) != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
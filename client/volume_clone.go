@@ -0,0 +1,27 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/docker/docker/api/types"
+)
+
+// VolumeClone creates the volume target with the driver, driver options and
+// a copy of the contents of the volume volumeID.
+func (cli *Client) VolumeClone(ctx context.Context, volumeID, target string) (types.Volume, error) {
+	var volume types.Volume
+	if err := cli.NewVersionError("1.40", "volume clone"); err != nil {
+		return volume, err
+	}
+	query := url.Values{}
+	query.Set("target", target)
+	resp, err := cli.post(ctx, "/volumes/"+volumeID+"/clone", query, nil, nil)
+	defer ensureReaderClosed(resp)
+	if err != nil {
+		return volume, wrapResponseError(err, resp, "volume", volumeID)
+	}
+	err = json.NewDecoder(resp.body).Decode(&volume)
+	return volume, err
+}
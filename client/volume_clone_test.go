@@ -0,0 +1,73 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+)
+
+func TestVolumeCloneError(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusInternalServerError, "Server error")),
+	}
+	_, err := client.VolumeClone(context.Background(), "volume_id", "target")
+	if err == nil || err.Error() != "Error response from daemon: Server error" {
+		t.Fatalf("expected a Server Error, got %v", err)
+	}
+}
+
+func TestVolumeCloneNotFound(t *testing.T) {
+	client := &Client{
+		client: newMockClient(errorMock(http.StatusNotFound, "Server error")),
+	}
+	_, err := client.VolumeClone(context.Background(), "unknown", "target")
+	if !IsErrNotFound(err) {
+		t.Fatalf("expected a volume not found error, got %v", err)
+	}
+}
+
+func TestVolumeClone(t *testing.T) {
+	expectedURL := "/volumes/volume_id/clone"
+	client := &Client{
+		client: newMockClient(func(req *http.Request) (*http.Response, error) {
+			if !strings.HasPrefix(req.URL.Path, expectedURL) {
+				return nil, fmt.Errorf("Expected URL '%s', got '%s'", expectedURL, req.URL)
+			}
+			if req.Method != http.MethodPost {
+				return nil, fmt.Errorf("expected POST method, got %s", req.Method)
+			}
+			if target := req.URL.Query().Get("target"); target != "target" {
+				return nil, fmt.Errorf("target not set in URL query properly. Expected 'target', got %s", target)
+			}
+			content, err := json.Marshal(types.Volume{
+				Name:       "target",
+				Driver:     "local",
+				Mountpoint: "mountpoint",
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &http.Response{
+				StatusCode: http.StatusCreated,
+				Body:       ioutil.NopCloser(bytes.NewReader(content)),
+			}, nil
+		}),
+	}
+	volume, err := client.VolumeClone(context.Background(), "volume_id", "target")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if volume.Name != "target" {
+		t.Fatalf("expected volume name to be 'target', got %s", volume.Name)
+	}
+	if volume.Driver != "local" {
+		t.Fatalf("expected volume driver to be 'local', got %s", volume.Driver)
+	}
+}
@@ -0,0 +1,22 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"io"
+	"net/url"
+)
+
+// VolumeExport retrieves the contents of a volume as a tar archive
+// and returns them as an io.ReadCloser. It's up to the caller
+// to close the stream.
+func (cli *Client) VolumeExport(ctx context.Context, volumeID string) (io.ReadCloser, error) {
+	if err := cli.NewVersionError("1.40", "volume export"); err != nil {
+		return nil, err
+	}
+	serverResp, err := cli.get(ctx, "/volumes/"+volumeID+"/export", url.Values{}, nil)
+	if err != nil {
+		return nil, wrapResponseError(err, serverResp, "volume", volumeID)
+	}
+
+	return serverResp.body, nil
+}
@@ -0,0 +1,18 @@
+package client // import "github.com/docker/docker/client"
+
+import (
+	"context"
+	"io"
+)
+
+// VolumeImport populates a volume with the contents of the tar archive
+// read from input.
+func (cli *Client) VolumeImport(ctx context.Context, volumeID string, input io.Reader) error {
+	if err := cli.NewVersionError("1.40", "volume import"); err != nil {
+		return err
+	}
+	headers := map[string][]string{"Content-Type": {"application/x-tar"}}
+	resp, err := cli.postRaw(ctx, "/volumes/"+volumeID+"/import", nil, input, headers)
+	ensureReaderClosed(resp)
+	return wrapResponseError(err, resp, "volume", volumeID)
+}
@@ -31,6 +31,7 @@ import (
 	buildkit "github.com/docker/docker/builder/builder-next"
 	"github.com/docker/docker/builder/dockerfile"
 	"github.com/docker/docker/builder/fscache"
+	"github.com/docker/docker/builder/remotecontext/git"
 	"github.com/docker/docker/cli/debug"
 	"github.com/docker/docker/daemon"
 	"github.com/docker/docker/daemon/cluster"
@@ -128,6 +129,10 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 		}()
 	}
 
+	if opts.migrateStorageDriver != "" {
+		return migrateStorage(cli.Config, opts)
+	}
+
 	serverConfig, err := newAPIServerConfig(cli)
 	if err != nil {
 		return errors.Wrap(err, "failed to create API server")
@@ -284,7 +289,17 @@ func newRouterOptions(config *config.Config, d *daemon.Daemon) (routerOptions, e
 		return opts, errors.Wrap(err, "failed to create fscache")
 	}
 
-	manager, err := dockerfile.NewBuildManager(d.BuilderBackend(), sm, buildCache, d.IdentityMapping())
+	mountCache, err := fscache.NewMountCache(filepath.Join(builderStateDir, "cachemounts"), d.IdentityMapping().RootPair())
+	if err != nil {
+		return opts, errors.Wrap(err, "failed to create cache mount store")
+	}
+
+	gitMirrors, err := git.NewMirrors(filepath.Join(builderStateDir, "git"))
+	if err != nil {
+		return opts, errors.Wrap(err, "failed to create git mirror store")
+	}
+
+	manager, err := dockerfile.NewBuildManager(d.BuilderBackend(), sm, buildCache, mountCache, gitMirrors, d.IdentityMapping(), config.Builder.MaxParallelism, config.Builder.MaxConcurrentBuilds.Classic)
 	if err != nil {
 		return opts, err
 	}
@@ -302,7 +317,7 @@ func newRouterOptions(config *config.Config, d *daemon.Daemon) (routerOptions, e
 		return opts, err
 	}
 
-	bb, err := buildbackend.NewBackend(d.ImageService(), manager, buildCache, bk)
+	bb, err := buildbackend.NewBackend(d.ImageService(), manager, buildCache, mountCache, bk)
 	if err != nil {
 		return opts, errors.Wrap(err, "failed to create buildmanager")
 	}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/docker/docker/daemon/config"
+	"github.com/docker/docker/migrate/storagedriver"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// migrateStorage migrates the images and containers of the daemon to the
+// storage driver and data-root given by the migrate options, instead of
+// starting the daemon.
+func migrateStorage(conf *config.Config, opts *daemonOptions) error {
+	if opts.migrateDataRoot == "" {
+		return errors.New("--migrate-storage-driver requires --migrate-data-root")
+	}
+	if conf.RemappedRoot != "" {
+		return errors.New("storage driver migration is not supported with user namespace remapping")
+	}
+
+	logrus.Infof("Migrating %s to storage driver %s under %s", conf.Root, opts.migrateStorageDriver, opts.migrateDataRoot)
+	res, err := storagedriver.Migrate(storagedriver.Options{
+		Root:             conf.Root,
+		Driver:           conf.GraphDriver,
+		DriverOptions:    conf.GraphOptions,
+		NewRoot:          opts.migrateDataRoot,
+		NewDriver:        opts.migrateStorageDriver,
+		NewDriverOptions: opts.migrateStorageOpts,
+		DryRun:           opts.migrateDryRun,
+	})
+	if err != nil {
+		return errors.Wrap(err, "storage driver migration failed")
+	}
+
+	if opts.migrateDryRun {
+		logrus.Infof("Dry run: %d images, %d layers and %d containers can be migrated", res.Images, res.Layers, res.Containers)
+		return nil
+	}
+	logrus.Infof("Migrated %d images, %d layers and %d containers; start the daemon with --data-root %s --storage-driver %s to use them",
+		res.Images, res.Layers, res.Containers, opts.migrateDataRoot, opts.migrateStorageDriver)
+	return nil
+}
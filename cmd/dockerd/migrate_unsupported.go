@@ -0,0 +1,13 @@
+// +build !linux
+
+package main
+
+import (
+	"errors"
+
+	"github.com/docker/docker/daemon/config"
+)
+
+func migrateStorage(conf *config.Config, opts *daemonOptions) error {
+	return errors.New("storage driver migration is only supported on Linux")
+}
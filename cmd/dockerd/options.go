@@ -39,6 +39,11 @@ type daemonOptions struct {
 	TLS          bool
 	TLSVerify    bool
 	TLSOptions   *tlsconfig.Options
+
+	migrateStorageDriver string
+	migrateStorageOpts   []string
+	migrateDataRoot      string
+	migrateDryRun        bool
 }
 
 // newDaemonOptions returns a new daemonFlags
@@ -73,6 +78,11 @@ func (o *daemonOptions) InstallFlags(flags *pflag.FlagSet) {
 
 	hostOpt := opts.NewNamedListOptsRef("hosts", &o.Hosts, opts.ValidateHost)
 	flags.VarP(hostOpt, "host", "H", "Daemon socket(s) to connect to")
+
+	flags.StringVar(&o.migrateStorageDriver, "migrate-storage-driver", "", "Migrate images and containers to this storage driver under --migrate-data-root, then exit")
+	flags.Var(opts.NewNamedListOptsRef("migrate-storage-opts", &o.migrateStorageOpts, nil), "migrate-storage-opt", "Storage driver options for --migrate-storage-driver")
+	flags.StringVar(&o.migrateDataRoot, "migrate-data-root", "", "Root directory of persistent Docker state to migrate to")
+	flags.BoolVar(&o.migrateDryRun, "migrate-dry-run", false, "Verify that images and containers can be migrated, without migrating them")
 }
 
 // SetDefaultOptions sets default values for options after flag parsing is
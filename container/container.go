@@ -468,6 +468,12 @@ func (container *Container) AddMountPointWithVolume(destination string, vol volu
 func (container *Container) UnmountVolumes(volumeEventLog func(name, action string, attributes map[string]string)) error {
 	var errors []string
 	for _, volumeMount := range container.MountPoints {
+		if volumeMount.Layer != nil {
+			if err := volumeMount.Cleanup(); err != nil {
+				errors = append(errors, err.Error())
+			}
+			continue
+		}
 		if volumeMount.Volume == nil {
 			continue
 		}
@@ -489,6 +495,20 @@ func (container *Container) UnmountVolumes(volumeEventLog func(name, action stri
 	return nil
 }
 
+// UsesImage returns true if the container was created from the image imgID,
+// or mounts it with an image mount.
+func (container *Container) UsesImage(imgID image.ID) bool {
+	if container.ImageID == imgID {
+		return true
+	}
+	for _, m := range container.MountPoints {
+		if m.Type == mounttypes.TypeImage && m.Name == imgID.String() {
+			return true
+		}
+	}
+	return false
+}
+
 // IsDestinationMounted checks whether a path is mounted on the container or not.
 func (container *Container) IsDestinationMounted(destination string) bool {
 	return container.MountPoints[destination] != nil
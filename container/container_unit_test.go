@@ -8,9 +8,12 @@ import (
 	"testing"
 
 	"github.com/docker/docker/api/types/container"
+	mounttypes "github.com/docker/docker/api/types/mount"
 	swarmtypes "github.com/docker/docker/api/types/swarm"
 	"github.com/docker/docker/daemon/logger/jsonfilelog"
+	"github.com/docker/docker/image"
 	"github.com/docker/docker/pkg/signal"
+	volumemounts "github.com/docker/docker/volume/mounts"
 	"gotest.tools/assert"
 )
 
@@ -72,6 +75,21 @@ func TestContainerSecretReferenceDestTarget(t *testing.T) {
 	}
 }
 
+func TestContainerUsesImage(t *testing.T) {
+	imgID := image.ID("sha256:6f2b2a2cbbd4a1b4eef73a1c9d9e4b2c1bd9c1a8e5d3e4e3c8a0e6a2f0a1e2b3")
+	mountedID := image.ID("sha256:5e1a1b1cbbd4a1b4eef73a1c9d9e4b2c1bd9c1a8e5d3e4e3c8a0e6a2f0a1e2b3")
+	c := &Container{
+		ImageID: imgID,
+		MountPoints: map[string]*volumemounts.MountPoint{
+			"/data": {Type: mounttypes.TypeImage, Name: mountedID.String(), Destination: "/data"},
+			"/vol":  {Type: mounttypes.TypeVolume, Name: "vol", Destination: "/vol"},
+		},
+	}
+	assert.Check(t, c.UsesImage(imgID))
+	assert.Check(t, c.UsesImage(mountedID))
+	assert.Check(t, !c.UsesImage(image.ID("sha256:4d")))
+}
+
 func TestContainerLogPathSetForJSONFileLogger(t *testing.T) {
 	containerRoot, err := ioutil.TempDir("", "TestContainerLogPathSetForJSONFileLogger")
 	assert.NilError(t, err)
@@ -16,7 +16,19 @@ type BuilderGCConfig struct {
 	DefaultKeepStorage string          `json:",omitempty"`
 }
 
+// BuilderConcurrencyConfig contains the maximum number of builds each
+// builder runs at the same time. Builds over the limit are queued, and
+// started in the order they were received. A builder has no limit if unset.
+type BuilderConcurrencyConfig struct {
+	Classic  int `json:",omitempty"`
+	BuildKit int `json:",omitempty"`
+}
+
 // BuilderConfig contains config for the builder
 type BuilderConfig struct {
 	GC BuilderGCConfig `json:",omitempty"`
+	// MaxParallelism is the maximum number of stages of a build the classic
+	// builder runs at the same time. The number of CPUs is used if unset.
+	MaxParallelism      int                      `json:",omitempty"`
+	MaxConcurrentBuilds BuilderConcurrencyConfig `json:",omitempty"`
 }
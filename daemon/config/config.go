@@ -14,6 +14,7 @@ import (
 
 	daemondiscovery "github.com/docker/docker/daemon/discovery"
 	"github.com/docker/docker/opts"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/authorization"
 	"github.com/docker/docker/pkg/discovery"
 	"github.com/docker/docker/registry"
@@ -167,6 +168,18 @@ type CommonConfig struct {
 	// may take place at a time for each push.
 	MaxConcurrentUploads *int `json:"max-concurrent-uploads,omitempty"`
 
+	// PushCompression is the compression algorithm ("gzip" or "zstd")
+	// applied to layers when they are pushed.
+	PushCompression string `json:"push-compression,omitempty"`
+
+	// PushCompressionLevel is the compression level applied to layers
+	// when they are pushed. 0 selects the default level of the algorithm.
+	PushCompressionLevel int `json:"push-compression-level,omitempty"`
+
+	// FsckOnStartup checks the integrity of the layer store in the
+	// background when the daemon starts, and logs the problems found.
+	FsckOnStartup bool `json:"fsck-on-startup,omitempty"`
+
 	// ShutdownTimeout is the timeout value (in seconds) the daemon will wait for the container
 	// to stop when daemon is being shutdown
 	ShutdownTimeout int `json:"shutdown-timeout,omitempty"`
@@ -219,6 +232,11 @@ type CommonConfig struct {
 	// not starting it ourselves
 	ContainerdAddr string `json:"containerd,omitempty"`
 
+	// ContainerdSnapshotter is the name of the containerd snapshotter used
+	// to store layers instead of a graphdriver. Images are then stored in
+	// the containerd content store, and are visible to containerd.
+	ContainerdSnapshotter string `json:"containerd-snapshotter,omitempty"`
+
 	// CriContainerd determines whether a supervised containerd instance
 	// should be configured with the CRI plugin enabled. This allows using
 	// Docker's containerd instance directly with a Kubernetes kubelet.
@@ -560,6 +578,16 @@ func Validate(config *Config) error {
 		return fmt.Errorf("invalid max concurrent uploads: %d", *config.MaxConcurrentUploads)
 	}
 
+	// validate ContainerdSnapshotter
+	if config.ContainerdSnapshotter != "" && config.GraphDriver != "" {
+		return fmt.Errorf("storage-driver and containerd-snapshotter cannot be used together")
+	}
+
+	// validate PushCompression and PushCompressionLevel
+	if err := validatePushCompression(config.PushCompression, config.PushCompressionLevel); err != nil {
+		return err
+	}
+
 	// validate that "default" runtime is not reset
 	if runtimes := config.GetAllRuntimes(); len(runtimes) > 0 {
 		if _, ok := runtimes[StockRuntimeName]; ok {
@@ -582,6 +610,31 @@ func Validate(config *Config) error {
 	return config.ValidatePlatformConfig()
 }
 
+// GetPushCompression returns the compression algorithm applied to layers
+// when they are pushed.
+func (conf *Config) GetPushCompression() archive.Compression {
+	if conf.PushCompression == "zstd" {
+		return archive.Zstd
+	}
+	return archive.Gzip
+}
+
+func validatePushCompression(compression string, level int) error {
+	var maxLevel int
+	switch compression {
+	case "", "gzip":
+		maxLevel = 9
+	case "zstd":
+		maxLevel = 19
+	default:
+		return fmt.Errorf("invalid push compression: %s", compression)
+	}
+	if level < 0 || level > maxLevel {
+		return fmt.Errorf("invalid push compression level: %d", level)
+	}
+	return nil
+}
+
 // ModifiedDiscoverySettings returns whether the discovery configuration has been modified or not.
 func ModifiedDiscoverySettings(config *Config, backendType, advertise string, clusterOpts map[string]string) bool {
 	if config.ClusterStore != backendType || config.ClusterAdvertise != advertise {
@@ -298,6 +298,14 @@ func TestValidateConfigurationErrors(t *testing.T) {
 				},
 			},
 		},
+		{
+			config: &Config{
+				CommonConfig: CommonConfig{
+					GraphDriver:           "overlay2",
+					ContainerdSnapshotter: "overlayfs",
+				},
+			},
+		},
 		{
 			config: &Config{
 				CommonConfig: CommonConfig{
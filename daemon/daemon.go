@@ -46,9 +46,11 @@ import (
 	_ "github.com/docker/docker/daemon/graphdriver/register"
 	"github.com/docker/docker/daemon/stats"
 	dmetadata "github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/distribution/partial"
 	"github.com/docker/docker/dockerversion"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/layer/snapshotter"
 	"github.com/docker/docker/libcontainerd"
 	"github.com/docker/docker/migrate/v1"
 	"github.com/docker/docker/pkg/idtools"
@@ -72,6 +74,10 @@ import (
 // ContainersNamespace is the name of the namespace used for users containers
 const ContainersNamespace = "moby"
 
+// partialDownloadMaxAge is the age after which a partial layer download that
+// was not resumed is removed on daemon startup.
+const partialDownloadMaxAge = 7 * 24 * time.Hour
+
 var (
 	errSystemNotSupported = errors.New("the Docker daemon is not supported on this platform")
 )
@@ -119,6 +125,7 @@ type Daemon struct {
 
 	diskUsageRunning int32
 	pruneRunning     int32
+	fsckRunning      int32
 	hosts            map[string]bool // hosts stores the addresses the daemon is listening on
 	startupDone      chan struct{}
 
@@ -842,20 +849,40 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 		return nil, err
 	}
 
-	for operatingSystem, gd := range d.graphDrivers {
-		layerStores[operatingSystem], err = layer.NewStoreFromOptions(layer.StoreOptions{
-			Root:                      config.Root,
-			MetadataStorePathTemplate: filepath.Join(config.Root, "image", "%s", "layerdb"),
-			GraphDriver:               gd,
-			GraphDriverOptions:        config.GraphOptions,
-			IDMapping:                 idMapping,
-			PluginGetter:              d.PluginStore,
-			ExperimentalEnabled:       config.Experimental,
-			OS:                        operatingSystem,
+	if config.ContainerdSnapshotter != "" {
+		if d.containerdCli == nil || runtime.GOOS == "windows" {
+			return nil, errors.New("containerd-snapshotter requires containerd")
+		}
+		// Layers are stored in the containerd snapshotter instead of a
+		// graphdriver, so that they are visible to containerd.
+		layerStores[runtime.GOOS], err = snapshotter.NewStore(snapshotter.Options{
+			Root:            filepath.Join(config.Root, "image", config.ContainerdSnapshotter, "snapshots"),
+			Snapshotter:     d.containerdCli.SnapshotService(config.ContainerdSnapshotter),
+			SnapshotterName: config.ContainerdSnapshotter,
+			ContentStore:    d.containerdCli.ContentStore(),
+			Namespace:       ContainersNamespace,
+			IDMapping:       idMapping,
+			OS:              runtime.GOOS,
 		})
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		for operatingSystem, gd := range d.graphDrivers {
+			layerStores[operatingSystem], err = layer.NewStoreFromOptions(layer.StoreOptions{
+				Root:                      config.Root,
+				MetadataStorePathTemplate: filepath.Join(config.Root, "image", "%s", "layerdb"),
+				GraphDriver:               gd,
+				GraphDriverOptions:        config.GraphOptions,
+				IDMapping:                 idMapping,
+				PluginGetter:              d.PluginStore,
+				ExperimentalEnabled:       config.Experimental,
+				OS:                        operatingSystem,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// As layerstore initialization may set the driver
@@ -870,9 +897,14 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 	}
 
 	imageRoot := filepath.Join(config.Root, "image", d.graphDrivers[runtime.GOOS])
-	ifs, err := image.NewFSStoreBackend(filepath.Join(imageRoot, "imagedb"))
-	if err != nil {
-		return nil, err
+	var ifs image.StoreBackend
+	if config.ContainerdSnapshotter != "" {
+		ifs = image.NewContentStoreBackend(d.containerdCli.ContentStore(), ContainersNamespace)
+	} else {
+		ifs, err = image.NewFSStoreBackend(filepath.Join(imageRoot, "imagedb"))
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	lgrMap := make(map[string]image.LayerGetReleaser)
@@ -884,7 +916,7 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 		return nil, err
 	}
 
-	d.volumes, err = volumesservice.NewVolumeService(config.Root, d.PluginStore, rootIDs, d)
+	d.volumes, err = volumesservice.NewVolumeService(config.Root, d.PluginStore, idMapping, d)
 	if err != nil {
 		return nil, err
 	}
@@ -921,6 +953,14 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 		return nil, err
 	}
 
+	partialStore, err := partial.NewStore(filepath.Join(imageRoot, "partial"))
+	if err != nil {
+		return nil, err
+	}
+	if err := partialStore.Prune(partialDownloadMaxAge); err != nil {
+		logrus.Warnf("Failed to prune stale partial downloads: %v", err)
+	}
+
 	// No content-addressability migration on Windows as it never supported pre-CA
 	if runtime.GOOS != "windows" {
 		migrationStart := time.Now()
@@ -972,6 +1012,9 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 		LayerStores:               layerStores,
 		MaxConcurrentDownloads:    *config.MaxConcurrentDownloads,
 		MaxConcurrentUploads:      *config.MaxConcurrentUploads,
+		PartialStore:              partialStore,
+		PushCompression:           config.GetPushCompression(),
+		PushCompressionLevel:      config.PushCompressionLevel,
 		ReferenceStore:            rs,
 		RegistryService:           registryService,
 		TrustKey:                  trustKey,
@@ -989,6 +1032,10 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 	}
 	close(d.startupDone)
 
+	if config.FsckOnStartup {
+		go d.fsckOnStartup()
+	}
+
 	// FIXME: this method never returns an error
 	info, _ := d.SystemInfo()
 
@@ -1004,6 +1051,7 @@ func NewDaemon(ctx context.Context, config *config.Config, pluginStore *plugin.S
 	).Set(1)
 	engineCpus.Set(float64(info.NCPU))
 	engineMemory.Set(float64(info.MemTotal))
+	rwLayerUsageCtr.setDaemon(d)
 
 	gd := ""
 	for os, driver := range d.graphDrivers {
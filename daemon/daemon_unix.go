@@ -1400,6 +1400,10 @@ func (daemon *Daemon) stats(c *container.Container) (*types.StatsJSON, error) {
 		}
 	}
 
+	if usage, ok := daemon.imageService.GetContainerLayerUsage(c.ID); ok {
+		s.WritableLayerBytes = uint64(usage)
+	}
+
 	return s, nil
 }
 
@@ -0,0 +1,69 @@
+package daemon // import "github.com/docker/docker/daemon"
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/layer"
+	"github.com/sirupsen/logrus"
+)
+
+// SystemFsck checks the integrity of the layer store, and reports the images
+// and containers affected by the problems found.
+func (daemon *Daemon) SystemFsck(ctx context.Context, opts types.FsckOptions) (*types.FsckReport, error) {
+	if !atomic.CompareAndSwapInt32(&daemon.fsckRunning, 0, 1) {
+		return nil, fmt.Errorf("a layer store check is already running")
+	}
+	defer atomic.StoreInt32(&daemon.fsckRunning, 0)
+
+	report, err := daemon.imageService.CheckLayerStores(ctx, opts.Quarantine)
+	if err != nil {
+		return nil, err
+	}
+
+	affectedImages := make(map[string]bool)
+	for _, id := range report.AffectedImages {
+		affectedImages[id] = true
+	}
+	danglingMounts := make(map[string]bool)
+	for _, p := range report.Problems {
+		if p.Type == layer.ProblemDanglingMount {
+			danglingMounts[p.ID] = true
+		}
+	}
+	for _, c := range daemon.List() {
+		if affectedImages[c.ImageID.String()] || danglingMounts[c.ID] {
+			report.AffectedContainers = append(report.AffectedContainers, c.ID)
+		}
+	}
+	sort.Strings(report.AffectedContainers)
+
+	return report, nil
+}
+
+// fsckOnStartup checks the integrity of the layer store, and logs the
+// problems found. Corrupt layers are not moved to quarantine.
+func (daemon *Daemon) fsckOnStartup() {
+	logrus.Info("Checking integrity of the layer store")
+	report, err := daemon.SystemFsck(context.Background(), types.FsckOptions{})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to check integrity of the layer store")
+		return
+	}
+	for _, p := range report.Problems {
+		logrus.WithFields(logrus.Fields{
+			"type": p.Type,
+			"id":   p.ID,
+		}).Warn(p.Message)
+	}
+	if len(report.AffectedImages) > 0 {
+		logrus.Warnf("Images using corrupt layers: %v. Run a layer store check with quarantine to pull them again", report.AffectedImages)
+	}
+	logrus.WithFields(logrus.Fields{
+		"layers":   report.LayersChecked,
+		"problems": len(report.Problems),
+	}).Info("Layer store integrity check done")
+}
@@ -122,6 +122,25 @@ type CapabilityDriver interface {
 	Capabilities() Capabilities
 }
 
+// UsageDriver is the interface for layered file system drivers that can
+// report the disk usage of a layer from the accounting of the backing file
+// system, without walking the files of the layer.
+type UsageDriver interface {
+	// Usage returns the number of bytes used by the files of the layer id,
+	// not including its parents, and false if the usage of the layer is
+	// not accounted for.
+	Usage(id string) (usage int64, ok bool, err error)
+}
+
+// ListDriver is the interface for layered file system drivers that can list
+// the IDs of the layers they store.
+type ListDriver interface {
+	// List returns the IDs of the layers stored by the driver, including
+	// those not created by the layer store, or ErrNotSupported if the
+	// driver cannot list them.
+	List() ([]string, error)
+}
+
 // DiffGetterDriver is the interface for layered file system drivers that
 // provide a specialized function for getting file contents for tar-split.
 type DiffGetterDriver interface {
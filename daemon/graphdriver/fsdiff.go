@@ -173,3 +173,12 @@ func (gdw *NaiveDiffDriver) DiffSize(id, parent string) (size int64, err error)
 
 	return archive.ChangesSize(layerFs.Path(), changes), nil
 }
+
+// List returns the IDs of the layers stored by the wrapped driver, or
+// ErrNotSupported if it cannot list them.
+func (gdw *NaiveDiffDriver) List() ([]string, error) {
+	if ld, ok := gdw.ProtoDriver.(ListDriver); ok {
+		return ld.List()
+	}
+	return nil, ErrNotSupported
+}
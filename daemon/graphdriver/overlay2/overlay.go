@@ -217,16 +217,16 @@ func Init(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (grap
 
 	d.naiveDiff = graphdriver.NewNaiveDiffDriver(d, uidMaps, gidMaps)
 
-	if backingFs == "xfs" {
-		// Try to enable project quota support over xfs.
+	if backingFs == "xfs" || backingFs == "extfs" {
+		// Try to enable project quota support over xfs or ext4.
 		if d.quotaCtl, err = quota.NewControl(home); err == nil {
 			projectQuotaSupported = true
 		} else if opts.quota.Size > 0 {
 			return nil, fmt.Errorf("Storage option overlay2.size not supported. Filesystem does not support Project Quota: %v", err)
 		}
 	} else if opts.quota.Size > 0 {
-		// if xfs is not the backing fs then error out if the storage-opt overlay2.size is used.
-		return nil, fmt.Errorf("Storage Option overlay2.size only supported for backingFS XFS. Found %v", backingFs)
+		// if neither xfs nor ext4 is the backing fs then error out if the storage-opt overlay2.size is used.
+		return nil, fmt.Errorf("Storage Option overlay2.size only supported for backingFS XFS or ext4. Found %v", backingFs)
 	}
 
 	// figure out whether "index=off" option is recognized by the kernel
@@ -353,7 +353,7 @@ func (d *Driver) Cleanup() error {
 // file system.
 func (d *Driver) CreateReadWrite(id, parent string, opts *graphdriver.CreateOpts) error {
 	if opts != nil && len(opts.StorageOpt) != 0 && !projectQuotaSupported {
-		return fmt.Errorf("--storage-opt is supported only for overlay over xfs with 'pquota' mount option")
+		return fmt.Errorf("--storage-opt is supported only for overlay over xfs with 'pquota' mount option, or over ext4 with 'prjquota' mount option")
 	}
 
 	if opts == nil {
@@ -412,8 +412,10 @@ func (d *Driver) create(id, parent string, opts *graphdriver.CreateOpts) (retErr
 			return err
 		}
 
-		if driver.options.quota.Size > 0 {
-			// Set container disk quota limit
+		// Set container disk quota limit. Without a limit, a project id
+		// is still assigned when quotas are supported, so that the disk
+		// usage of the container is accounted for.
+		if driver.options.quota.Size > 0 || d.quotaCtl != nil {
 			if err := d.quotaCtl.SetQuota(dir, driver.options.quota); err != nil {
 				return err
 			}
@@ -685,6 +687,21 @@ func (d *Driver) Exists(id string) bool {
 	return err == nil
 }
 
+// List returns the IDs of the layers in the home directory of the driver.
+func (d *Driver) List() ([]string, error) {
+	fis, err := ioutil.ReadDir(d.home)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, fi := range fis {
+		if fi.IsDir() && fi.Name() != linkDir {
+			ids = append(ids, fi.Name())
+		}
+	}
+	return ids, nil
+}
+
 // isParent determines whether the given parent is the direct parent of the
 // given layer id
 func (d *Driver) isParent(id, parent string) bool {
@@ -745,6 +762,16 @@ func (d *Driver) DiffSize(id, parent string) (size int64, err error) {
 	return directory.Size(context.TODO(), d.getDiffPath(id))
 }
 
+// Usage returns the disk usage of the layer id from the project quota
+// accounting of the backing filesystem, if the layer has a project id.
+func (d *Driver) Usage(id string) (int64, bool, error) {
+	if d.quotaCtl == nil {
+		return 0, false, nil
+	}
+	usage, ok, err := d.quotaCtl.GetUsage(d.dir(id))
+	return int64(usage), ok, err
+}
+
 // Diff produces an archive of the changes between the specified
 // layer and its parent layer which may be "".
 func (d *Driver) Diff(id, parent string) (io.ReadCloser, error) {
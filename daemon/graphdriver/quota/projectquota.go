@@ -1,12 +1,12 @@
 // +build linux
 
 //
-// projectquota.go - implements XFS project quota controls
+// projectquota.go - implements XFS and ext4 project quota controls
 // for setting quota limits on a newly created directory.
-// It currently supports the legacy XFS specific ioctls.
-//
-// TODO: use generic quota control ioctl FS_IOC_FS{GET,SET}XATTR
-//       for both xfs/ext4 for kernel version >= v4.5
+// Project ids are set with the generic FS_IOC_FS{GET,SET}XATTR ioctls
+// (kernel version >= v4.5 for ext4). Quota limits are set with the
+// legacy XFS specific quotactl commands on XFS, and with the generic
+// quotactl commands on ext4.
 //
 
 package quota // import "github.com/docker/docker/daemon/graphdriver/quota"
@@ -49,6 +49,9 @@ struct fsxattr {
 #endif
 
 const int Q_XGETQSTAT_PRJQUOTA = QCMD(Q_XGETQSTAT, PRJQUOTA);
+const int Q_GETINFO_PRJQUOTA = QCMD(Q_GETINFO, PRJQUOTA);
+const int Q_SETQUOTA_PRJQUOTA = QCMD(Q_SETQUOTA, PRJQUOTA);
+const int Q_GETQUOTA_PRJQUOTA = QCMD(Q_GETQUOTA, PRJQUOTA);
 */
 import "C"
 import (
@@ -56,6 +59,7 @@ import (
 	"io/ioutil"
 	"path"
 	"path/filepath"
+	"sync"
 	"unsafe"
 
 	rsystem "github.com/opencontainers/runc/libcontainer/system"
@@ -63,15 +67,12 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-// Quota limit params - currently we only control blocks hard limit
-type Quota struct {
-	Size uint64
-}
-
 // Control - Context to be used by storage driver (e.g. overlay)
 // who wants to apply project quotas to container dirs
 type Control struct {
 	backingFsBlockDev string
+	genericQuota      bool
+	mu                sync.Mutex
 	nextProjectID     uint32
 	quotas            map[string]uint32
 }
@@ -83,13 +84,17 @@ type Control struct {
 // Returns nil (and error) if project quota is not supported.
 //
 // First get the project id of the home directory.
-// This test will fail if the backing fs is not xfs.
+// This test will fail if the backing fs is not xfs, or ext4 mounted with
+// the prjquota option.
 //
 // xfs_quota tool can be used to assign a project id to the driver home directory, e.g.:
 //    echo 999:/var/lib/docker/overlay2 >> /etc/projects
 //    echo docker:999 >> /etc/projid
 //    xfs_quota -x -c 'project -s docker' /<xfs mount point>
 //
+// or on ext4, chattr:
+//    chattr -p 999 +P /var/lib/docker/overlay2
+//
 // In that case, the home directory project id will be used as a "start offset"
 // and all containers will be assigned larger project ids (e.g. >= 1000).
 // This is a way to prevent xfs_quota management from conflicting with docker.
@@ -115,9 +120,16 @@ func NewControl(basePath string) (*Control, error) {
 		return nil, err
 	}
 
+	// ext4 quotas are controlled with the generic quotactl commands,
+	// xfs quotas with the xfs specific ones
+	genericQuota, err := usesGenericQuota(basePath)
+	if err != nil {
+		return nil, err
+	}
+
 	// check if we can call quotactl with project quotas
 	// as a mechanism to determine (early) if we have support
-	hasQuotaSupport, err := hasQuotaSupport(backingFsBlockDev)
+	hasQuotaSupport, err := hasQuotaSupport(backingFsBlockDev, genericQuota)
 	if err != nil {
 		return nil, err
 	}
@@ -141,12 +153,13 @@ func NewControl(basePath string) (*Control, error) {
 	quota := Quota{
 		Size: 0,
 	}
-	if err := setProjectQuota(backingFsBlockDev, minProjectID, quota); err != nil {
+	if err := setProjectQuota(backingFsBlockDev, genericQuota, minProjectID, quota); err != nil {
 		return nil, err
 	}
 
-	q := Control{
+	q := &Control{
 		backingFsBlockDev: backingFsBlockDev,
+		genericQuota:      genericQuota,
 		nextProjectID:     minProjectID + 1,
 		quotas:            make(map[string]uint32),
 	}
@@ -160,12 +173,14 @@ func NewControl(basePath string) (*Control, error) {
 	}
 
 	logrus.Debugf("NewControl(%s): nextProjectID = %d", basePath, q.nextProjectID)
-	return &q, nil
+	return q, nil
 }
 
 // SetQuota - assign a unique project id to directory and set the quota limits
 // for that project id
 func (q *Control) SetQuota(targetPath string, quota Quota) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 
 	projectID, ok := q.quotas[targetPath]
 	if !ok {
@@ -187,11 +202,15 @@ func (q *Control) SetQuota(targetPath string, quota Quota) error {
 	// set the quota limit for the container's project id
 	//
 	logrus.Debugf("SetQuota(%s, %d): projectID=%d", targetPath, quota.Size, projectID)
-	return setProjectQuota(q.backingFsBlockDev, projectID, quota)
+	return setProjectQuota(q.backingFsBlockDev, q.genericQuota, projectID, quota)
 }
 
-// setProjectQuota - set the quota for project id on xfs block device
-func setProjectQuota(backingFsBlockDev string, projectID uint32, quota Quota) error {
+// setProjectQuota - set the quota for project id on xfs or ext4 block device
+func setProjectQuota(backingFsBlockDev string, genericQuota bool, projectID uint32, quota Quota) error {
+	if genericQuota {
+		return setGenericProjectQuota(backingFsBlockDev, projectID, quota)
+	}
+
 	var d C.fs_disk_quota_t
 	d.d_version = C.FS_DQUOT_VERSION
 	d.d_id = C.__u32(projectID)
@@ -215,14 +234,40 @@ func setProjectQuota(backingFsBlockDev string, projectID uint32, quota Quota) er
 	return nil
 }
 
+// setGenericProjectQuota - set the quota for project id on ext4 block device
+func setGenericProjectQuota(backingFsBlockDev string, projectID uint32, quota Quota) error {
+	var d C.struct_if_dqblk
+	d.dqb_bhardlimit = C.__u64(quota.Size / C.QIF_DQBLKSIZE)
+	d.dqb_bsoftlimit = d.dqb_bhardlimit
+	d.dqb_valid = C.QIF_BLIMITS
+
+	var cs = C.CString(backingFsBlockDev)
+	defer C.free(unsafe.Pointer(cs))
+
+	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, uintptr(C.Q_SETQUOTA_PRJQUOTA),
+		uintptr(unsafe.Pointer(cs)), uintptr(projectID),
+		uintptr(unsafe.Pointer(&d)), 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("Failed to set quota limit for projid %d on %s: %v",
+			projectID, backingFsBlockDev, errno.Error())
+	}
+
+	return nil
+}
+
 // GetQuota - get the quota limits of a directory that was configured with SetQuota
 func (q *Control) GetQuota(targetPath string, quota *Quota) error {
-
+	q.mu.Lock()
 	projectID, ok := q.quotas[targetPath]
+	q.mu.Unlock()
 	if !ok {
 		return fmt.Errorf("quota not found for path : %s", targetPath)
 	}
 
+	if q.genericQuota {
+		return q.getGenericQuota(projectID, quota)
+	}
+
 	//
 	// get the quota limit for the container's project id
 	//
@@ -243,7 +288,63 @@ func (q *Control) GetQuota(targetPath string, quota *Quota) error {
 	return nil
 }
 
-// getProjectID - get the project id of path on xfs
+// getGenericQuota - get the quota limits of project id on ext4 block device
+func (q *Control) getGenericQuota(projectID uint32, quota *Quota) error {
+	var d C.struct_if_dqblk
+
+	var cs = C.CString(q.backingFsBlockDev)
+	defer C.free(unsafe.Pointer(cs))
+
+	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, uintptr(C.Q_GETQUOTA_PRJQUOTA),
+		uintptr(unsafe.Pointer(cs)), uintptr(projectID),
+		uintptr(unsafe.Pointer(&d)), 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("Failed to get quota limit for projid %d on %s: %v",
+			projectID, q.backingFsBlockDev, errno.Error())
+	}
+	quota.Size = uint64(d.dqb_bhardlimit) * C.QIF_DQBLKSIZE
+
+	return nil
+}
+
+// GetUsage - get the bytes used by a directory that was configured with
+// SetQuota, as accounted by the filesystem for its project id. ok is false
+// if the directory has no project id.
+func (q *Control) GetUsage(targetPath string) (usage uint64, ok bool, err error) {
+	q.mu.Lock()
+	projectID, ok := q.quotas[targetPath]
+	q.mu.Unlock()
+	if !ok {
+		return 0, false, nil
+	}
+
+	var cs = C.CString(q.backingFsBlockDev)
+	defer C.free(unsafe.Pointer(cs))
+
+	if q.genericQuota {
+		var d C.struct_if_dqblk
+		_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, uintptr(C.Q_GETQUOTA_PRJQUOTA),
+			uintptr(unsafe.Pointer(cs)), uintptr(projectID),
+			uintptr(unsafe.Pointer(&d)), 0, 0)
+		if errno != 0 {
+			return 0, false, fmt.Errorf("Failed to get quota usage for projid %d on %s: %v",
+				projectID, q.backingFsBlockDev, errno.Error())
+		}
+		return uint64(d.dqb_curspace), true, nil
+	}
+
+	var d C.fs_disk_quota_t
+	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, C.Q_XGETPQUOTA,
+		uintptr(unsafe.Pointer(cs)), uintptr(C.__u32(projectID)),
+		uintptr(unsafe.Pointer(&d)), 0, 0)
+	if errno != 0 {
+		return 0, false, fmt.Errorf("Failed to get quota usage for projid %d on %s: %v",
+			projectID, q.backingFsBlockDev, errno.Error())
+	}
+	return uint64(d.d_bcount) * 512, true, nil
+}
+
+// getProjectID - get the project id of path on xfs or ext4
 func getProjectID(targetPath string) (uint32, error) {
 	dir, err := openDir(targetPath)
 	if err != nil {
@@ -261,7 +362,7 @@ func getProjectID(targetPath string) (uint32, error) {
 	return uint32(fsx.fsx_projid), nil
 }
 
-// setProjectID - set the project id of path on xfs
+// setProjectID - set the project id of path on xfs or ext4
 func setProjectID(targetPath string, projectID uint32) error {
 	dir, err := openDir(targetPath)
 	if err != nil {
@@ -363,18 +464,40 @@ func makeBackingFsDev(home string) (string, error) {
 	}
 }
 
-func hasQuotaSupport(backingFsBlockDev string) (bool, error) {
+// usesGenericQuota returns whether the quotas of the filesystem of path are
+// controlled with the generic quotactl commands, which is the case of ext4,
+// rather than the xfs specific ones
+func usesGenericQuota(path string) (bool, error) {
+	var buf unix.Statfs_t
+	if err := unix.Statfs(path, &buf); err != nil {
+		return false, err
+	}
+	return buf.Type == unix.EXT4_SUPER_MAGIC, nil
+}
+
+func hasQuotaSupport(backingFsBlockDev string, genericQuota bool) (bool, error) {
 	var cs = C.CString(backingFsBlockDev)
 	defer free(cs)
-	var qstat C.fs_quota_stat_t
 
-	_, _, errno := unix.Syscall6(unix.SYS_QUOTACTL, uintptr(C.Q_XGETQSTAT_PRJQUOTA), uintptr(unsafe.Pointer(cs)), 0, uintptr(unsafe.Pointer(&qstat)), 0, 0)
-	if errno == 0 && qstat.qs_flags&C.FS_QUOTA_PDQ_ENFD > 0 && qstat.qs_flags&C.FS_QUOTA_PDQ_ACCT > 0 {
-		return true, nil
+	var errno unix.Errno
+	if genericQuota {
+		// Q_GETINFO fails with ESRCH unless project quotas are turned on,
+		// and ext4 enforces quotas as soon as they are turned on
+		var info C.struct_if_dqinfo
+		_, _, errno = unix.Syscall6(unix.SYS_QUOTACTL, uintptr(C.Q_GETINFO_PRJQUOTA), uintptr(unsafe.Pointer(cs)), 0, uintptr(unsafe.Pointer(&info)), 0, 0)
+		if errno == 0 {
+			return true, nil
+		}
+	} else {
+		var qstat C.fs_quota_stat_t
+		_, _, errno = unix.Syscall6(unix.SYS_QUOTACTL, uintptr(C.Q_XGETQSTAT_PRJQUOTA), uintptr(unsafe.Pointer(cs)), 0, uintptr(unsafe.Pointer(&qstat)), 0, 0)
+		if errno == 0 && qstat.qs_flags&C.FS_QUOTA_PDQ_ENFD > 0 && qstat.qs_flags&C.FS_QUOTA_PDQ_ACCT > 0 {
+			return true, nil
+		}
 	}
 
 	switch errno {
-	// These are the known fatal errors, consider all other errors (ENOTTY, etc.. not supporting quota)
+	// These are the known fatal errors, consider all other errors (ENOTTY, ESRCH, etc.. not supporting quota)
 	case unix.EFAULT, unix.ENOENT, unix.ENOTBLK, unix.EPERM:
 	default:
 		return false, nil
@@ -8,8 +8,10 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"testing"
 
+	"github.com/syndtr/gocapability/capability"
 	"golang.org/x/sys/unix"
 	"gotest.tools/assert"
 	is "gotest.tools/assert/cmp"
@@ -21,13 +23,23 @@ const testQuotaSize = 10 * 1024 * 1024
 const imageSize = 64 * 1024 * 1024
 
 func TestBlockDev(t *testing.T) {
-	mkfs, err := exec.LookPath("mkfs.xfs")
+	// The reason for disabling these options is sometimes people run with a newer userspace
+	// than kernelspace
+	testBlockDev(t, "xfs", "-m", "crc=0,finobt=0")
+}
+
+func TestBlockDevExt4(t *testing.T) {
+	testBlockDev(t, "ext4", "-O", "quota,project")
+}
+
+func testBlockDev(t *testing.T, fsName string, mkfsArgs ...string) {
+	mkfs, err := exec.LookPath("mkfs." + fsName)
 	if err != nil {
-		t.Skip("mkfs.xfs not found in PATH")
+		t.Skipf("mkfs.%s not found in PATH", fsName)
 	}
 
 	// create a sparse image
-	imageFile, err := ioutil.TempFile("", "xfs-image")
+	imageFile, err := ioutil.TempFile("", fsName+"-image")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,9 +55,7 @@ func TestBlockDev(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	// The reason for disabling these options is sometimes people run with a newer userspace
-	// than kernelspace
-	out, err := exec.Command(mkfs, "-m", "crc=0,finobt=0", imageFileName).CombinedOutput()
+	out, err := exec.Command(mkfs, append(mkfsArgs, imageFileName)...).CombinedOutput()
 	if len(out) > 0 {
 		t.Log(string(out))
 	}
@@ -53,14 +63,15 @@ func TestBlockDev(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	t.Run("testBlockDevQuotaDisabled", wrapMountTest(imageFileName, false, testBlockDevQuotaDisabled))
-	t.Run("testBlockDevQuotaEnabled", wrapMountTest(imageFileName, true, testBlockDevQuotaEnabled))
-	t.Run("testSmallerThanQuota", wrapMountTest(imageFileName, true, wrapQuotaTest(testSmallerThanQuota)))
-	t.Run("testBiggerThanQuota", wrapMountTest(imageFileName, true, wrapQuotaTest(testBiggerThanQuota)))
-	t.Run("testRetrieveQuota", wrapMountTest(imageFileName, true, wrapQuotaTest(testRetrieveQuota)))
+	t.Run("testBlockDevQuotaDisabled", wrapMountTest(imageFileName, fsName, false, testBlockDevQuotaDisabled))
+	t.Run("testBlockDevQuotaEnabled", wrapMountTest(imageFileName, fsName, true, testBlockDevQuotaEnabled))
+	t.Run("testSmallerThanQuota", wrapMountTest(imageFileName, fsName, true, wrapQuotaTest(testSmallerThanQuota)))
+	t.Run("testBiggerThanQuota", wrapMountTest(imageFileName, fsName, true, wrapQuotaTest(testBiggerThanQuota)))
+	t.Run("testRetrieveQuota", wrapMountTest(imageFileName, fsName, true, wrapQuotaTest(testRetrieveQuota)))
+	t.Run("testRetrieveUsage", wrapMountTest(imageFileName, fsName, true, wrapQuotaTest(testRetrieveUsage)))
 }
 
-func wrapMountTest(imageFileName string, enableQuota bool, testFunc func(t *testing.T, mountPoint, backingFsDev string)) func(*testing.T) {
+func wrapMountTest(imageFileName, fsName string, enableQuota bool, testFunc func(t *testing.T, mountPoint, backingFsDev string)) func(*testing.T) {
 	return func(t *testing.T) {
 		mountOptions := "loop"
 
@@ -68,15 +79,15 @@ func wrapMountTest(imageFileName string, enableQuota bool, testFunc func(t *test
 			mountOptions = mountOptions + ",prjquota"
 		}
 
-		mountPointDir := fs.NewDir(t, "xfs-mountPoint")
+		mountPointDir := fs.NewDir(t, fsName+"-mountPoint")
 		defer mountPointDir.Remove()
 		mountPoint := mountPointDir.Path()
 
 		out, err := exec.Command("mount", "-o", mountOptions, imageFileName, mountPoint).CombinedOutput()
 		if err != nil {
-			_, err := os.Stat("/proc/fs/xfs")
+			_, err := os.Stat("/proc/fs/" + fsName)
 			if os.IsNotExist(err) {
-				t.Skip("no /proc/fs/xfs")
+				t.Skipf("no /proc/fs/%s", fsName)
 			}
 		}
 
@@ -94,13 +105,17 @@ func wrapMountTest(imageFileName string, enableQuota bool, testFunc func(t *test
 }
 
 func testBlockDevQuotaDisabled(t *testing.T, mountPoint, backingFsDev string) {
-	hasSupport, err := hasQuotaSupport(backingFsDev)
+	genericQuota, err := usesGenericQuota(mountPoint)
+	assert.NilError(t, err)
+	hasSupport, err := hasQuotaSupport(backingFsDev, genericQuota)
 	assert.NilError(t, err)
 	assert.Check(t, !hasSupport)
 }
 
 func testBlockDevQuotaEnabled(t *testing.T, mountPoint, backingFsDev string) {
-	hasSupport, err := hasQuotaSupport(backingFsDev)
+	genericQuota, err := usesGenericQuota(mountPoint)
+	assert.NilError(t, err)
+	hasSupport, err := hasQuotaSupport(backingFsDev, genericQuota)
 	assert.NilError(t, err)
 	assert.Check(t, hasSupport)
 }
@@ -130,12 +145,10 @@ func testSmallerThanQuota(t *testing.T, ctrl *Control, homeDir, testDir, testSub
 
 func testBiggerThanQuota(t *testing.T, ctrl *Control, homeDir, testDir, testSubDir string) {
 	// Make sure the quota is being enforced
-	// TODO: When we implement this under EXT4, we need to shed CAP_SYS_RESOURCE, otherwise
-	// we're able to violate quota without issue
 	assert.NilError(t, ctrl.SetQuota(testSubDir, Quota{testQuotaSize}))
 
 	biggerThanQuotaFile := filepath.Join(testSubDir, "bigger-than-quota")
-	err := ioutil.WriteFile(biggerThanQuotaFile, make([]byte, testQuotaSize+1), 0644)
+	err := writeFileWithoutCapSysResource(biggerThanQuotaFile, make([]byte, testQuotaSize+1))
 	assert.Assert(t, is.ErrorContains(err, ""))
 	if err == io.ErrShortWrite {
 		assert.NilError(t, os.Remove(biggerThanQuotaFile))
@@ -150,3 +163,41 @@ func testRetrieveQuota(t *testing.T, ctrl *Control, homeDir, testDir, testSubDir
 	assert.NilError(t, ctrl.GetQuota(testSubDir, &q))
 	assert.Check(t, is.Equal(uint64(testQuotaSize), q.Size))
 }
+
+func testRetrieveUsage(t *testing.T, ctrl *Control, homeDir, testDir, testSubDir string) {
+	_, ok, err := ctrl.GetUsage(testSubDir)
+	assert.NilError(t, err)
+	assert.Check(t, !ok)
+
+	assert.NilError(t, ctrl.SetQuota(testSubDir, Quota{0}))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(testSubDir, "usage"), make([]byte, testQuotaSize/2), 0644))
+	unix.Sync()
+
+	usage, ok, err := ctrl.GetUsage(testSubDir)
+	assert.NilError(t, err)
+	assert.Check(t, ok)
+	assert.Check(t, usage >= testQuotaSize/2, "usage %d is less than the size of the file", usage)
+}
+
+// writeFileWithoutCapSysResource writes a file from a thread without
+// CAP_SYS_RESOURCE, with which ext4 lets quota limits be exceeded.
+func writeFileWithoutCapSysResource(filename string, data []byte) error {
+	errCh := make(chan error)
+	go func() {
+		// The thread is never unlocked, so that it exits with the goroutine
+		// rather than run other goroutines without the capability.
+		runtime.LockOSThread()
+		caps, err := capability.NewPid(0)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		caps.Unset(capability.EFFECTIVE, capability.CAP_SYS_RESOURCE)
+		if err := caps.Apply(capability.CAPS); err != nil {
+			errCh <- err
+			return
+		}
+		errCh <- ioutil.WriteFile(filename, data, 0644)
+	}()
+	return <-errCh
+}
@@ -0,0 +1,27 @@
+// +build !linux
+
+package quota // import "github.com/docker/docker/daemon/graphdriver/quota"
+
+// Control is not supported on this platform.
+type Control struct{}
+
+// NewControl returns ErrQuotaNotSupported, as project quotas are not
+// supported on this platform.
+func NewControl(basePath string) (*Control, error) {
+	return nil, ErrQuotaNotSupported
+}
+
+// SetQuota is not supported on this platform.
+func (q *Control) SetQuota(targetPath string, quota Quota) error {
+	return ErrQuotaNotSupported
+}
+
+// GetQuota is not supported on this platform.
+func (q *Control) GetQuota(targetPath string, quota *Quota) error {
+	return ErrQuotaNotSupported
+}
+
+// GetUsage is not supported on this platform.
+func (q *Control) GetUsage(targetPath string) (uint64, bool, error) {
+	return 0, false, ErrQuotaNotSupported
+}
@@ -0,0 +1,6 @@
+package quota // import "github.com/docker/docker/daemon/graphdriver/quota"
+
+// Quota limit params - currently we only control blocks hard limit
+type Quota struct {
+	Size uint64
+}
@@ -2,6 +2,7 @@ package vfs // import "github.com/docker/docker/daemon/graphdriver/vfs"
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -160,6 +161,22 @@ func (d *Driver) Put(id string) error {
 	return nil
 }
 
+// List returns the IDs of the layers stored by the driver.
+func (d *Driver) List() ([]string, error) {
+	fis, err := ioutil.ReadDir(filepath.Join(d.home, "dir"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ids := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		ids = append(ids, fi.Name())
+	}
+	return ids, nil
+}
+
 // Exists checks to see if the directory exists for the given id.
 func (d *Driver) Exists(id string) bool {
 	_, err := os.Stat(d.dir(id))
@@ -0,0 +1,65 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"context"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+)
+
+// CheckLayerStores checks the integrity of the layer stores, and reports the
+// images using corrupt layers.
+// called from fsck.go
+func (i *ImageService) CheckLayerStores(ctx context.Context, quarantine bool) (*types.FsckReport, error) {
+	report := &types.FsckReport{}
+	corrupt := make(map[layer.ChainID]bool)
+	for _, ls := range i.layerStores {
+		checker, ok := ls.(layer.Checker)
+		if !ok {
+			continue
+		}
+		res, err := checker.Check(ctx, quarantine)
+		if err != nil {
+			return nil, err
+		}
+		report.LayersChecked += res.Layers
+		for _, p := range res.Problems {
+			report.Problems = append(report.Problems, types.FsckProblem{
+				Type:    p.Kind,
+				ID:      p.ID,
+				Message: p.Message,
+			})
+			if p.Kind == layer.ProblemCorruptLayer {
+				corrupt[layer.ChainID(p.ID)] = true
+			}
+		}
+		for _, chainID := range res.Quarantined {
+			report.QuarantinedLayers = append(report.QuarantinedLayers, chainID.String())
+		}
+	}
+
+	if len(corrupt) > 0 {
+		for id, img := range i.imageStore.Map() {
+			if usesLayer(img, corrupt) {
+				report.AffectedImages = append(report.AffectedImages, id.String())
+			}
+		}
+		sort.Strings(report.AffectedImages)
+	}
+	return report, nil
+}
+
+// usesLayer returns true if one of the layers of img is in chainIDs.
+func usesLayer(img *image.Image, chainIDs map[layer.ChainID]bool) bool {
+	rootFS := *img.RootFS
+	rootFS.DiffIDs = nil
+	for _, diffID := range img.RootFS.DiffIDs {
+		rootFS.Append(diffID)
+		if chainIDs[rootFS.ChainID()] {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"runtime"
+	"time"
 
 	"github.com/docker/distribution/reference"
 	"github.com/docker/docker/api/types"
@@ -11,6 +12,7 @@ import (
 	"github.com/docker/docker/builder"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/containerfs"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/pkg/system"
@@ -32,6 +34,13 @@ func (l *roLayer) DiffID() layer.DiffID {
 	return l.roLayer.DiffID()
 }
 
+func (l *roLayer) DiffSize() (int64, error) {
+	if l.roLayer == nil {
+		return 0, nil
+	}
+	return l.roLayer.DiffSize()
+}
+
 func (l *roLayer) Release() error {
 	if l.released {
 		return nil
@@ -84,11 +93,14 @@ func (l *rwLayer) Root() containerfs.ContainerFS {
 	return l.fs
 }
 
-func (l *rwLayer) Commit() (builder.ROLayer, error) {
+func (l *rwLayer) Commit(sourceDateEpoch *time.Time) (builder.ROLayer, error) {
 	stream, err := l.rwLayer.TarStream()
 	if err != nil {
 		return nil, err
 	}
+	if sourceDateEpoch != nil {
+		stream = archive.ClampTimestampsTarWrapper(stream, *sourceDateEpoch)
+	}
 	defer stream.Close()
 
 	var chainID layer.ChainID
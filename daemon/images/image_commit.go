@@ -3,10 +3,13 @@ package images // import "github.com/docker/docker/daemon/images"
 import (
 	"encoding/json"
 	"io"
+	"time"
 
 	"github.com/docker/docker/api/types/backend"
+	"github.com/docker/docker/builder"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/system"
 	"github.com/pkg/errors"
@@ -22,6 +25,9 @@ func (i *ImageService) CommitImage(c backend.CommitConfig) (image.ID, error) {
 	if err != nil {
 		return "", err
 	}
+	if c.SourceDateEpoch != nil {
+		rwTar = archive.ClampTimestampsTarWrapper(rwTar, *c.SourceDateEpoch)
+	}
 	defer func() {
 		if rwTar != nil {
 			rwTar.Close()
@@ -53,6 +59,14 @@ func (i *ImageService) CommitImage(c backend.CommitConfig) (image.ID, error) {
 		Config:          c.Config,
 		DiffID:          l.DiffID(),
 	}
+	if c.SourceDateEpoch != nil {
+		// the container ID and hostname are random
+		containerConfig := *c.ContainerConfig
+		containerConfig.Hostname = ""
+		cc.ContainerID = ""
+		cc.ContainerConfig = &containerConfig
+		cc.Created = builder.ClampTime(time.Now().UTC(), c.SourceDateEpoch)
+	}
 	config, err := json.Marshal(image.NewChildImage(parent, cc, c.ContainerOS))
 	if err != nil {
 		return "", err
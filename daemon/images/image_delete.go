@@ -76,7 +76,7 @@ func (i *ImageService) ImageDelete(imageRef string, force, prune bool) ([]types.
 	repoRefs := i.referenceStore.References(imgID.Digest())
 
 	using := func(c *container.Container) bool {
-		return c.ImageID == imgID
+		return c.UsesImage(imgID)
 	}
 
 	var removedRepositoryRef bool
@@ -369,7 +369,7 @@ func (i *ImageService) checkImageDeleteConflict(imgID image.ID, mask conflictTyp
 	if mask&conflictRunningContainer != 0 {
 		// Check if any running container is using the image.
 		running := func(c *container.Container) bool {
-			return c.IsRunning() && c.ImageID == imgID
+			return c.IsRunning() && c.UsesImage(imgID)
 		}
 		if container := i.containers.First(running); container != nil {
 			return &imageDeleteConflict{
@@ -392,7 +392,7 @@ func (i *ImageService) checkImageDeleteConflict(imgID image.ID, mask conflictTyp
 	if mask&conflictStoppedContainer != 0 {
 		// Check if any stopped containers reference this image.
 		stopped := func(c *container.Container) bool {
-			return !c.IsRunning() && c.ImageID == imgID
+			return !c.IsRunning() && c.UsesImage(imgID)
 		}
 		if container := i.containers.First(stopped); container != nil {
 			return &imageDeleteConflict{
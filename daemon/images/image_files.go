@@ -0,0 +1,158 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/builder"
+	"github.com/docker/docker/errdefs"
+	imagepkg "github.com/docker/docker/image"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/system"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+)
+
+// ImageDiff returns the paths that were added, modified or deleted in the
+// filesystem of the image named to, compared to the image named from.
+func (i *ImageService) ImageDiff(from, to string) ([]image.DiffResponseItem, error) {
+	fromImg, err := i.GetImage(from)
+	if err != nil {
+		return nil, err
+	}
+	toImg, err := i.GetImage(to)
+	if err != nil {
+		return nil, err
+	}
+	if fromImg.OperatingSystem() != toImg.OperatingSystem() {
+		return nil, errdefs.InvalidParameter(errors.New("cannot compare images with different operating systems"))
+	}
+
+	fromLayer, err := i.mountImage(fromImg)
+	if err != nil {
+		return nil, err
+	}
+	defer fromLayer.Release()
+	toLayer, err := i.mountImage(toImg)
+	if err != nil {
+		return nil, err
+	}
+	defer toLayer.Release()
+
+	fromRoot, toRoot := fromLayer.Root().Path(), toLayer.Root().Path()
+	changes, err := archive.ChangesDirs(toRoot, fromRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := make([]image.DiffResponseItem, 0, len(changes))
+	for _, c := range changes {
+		root := toRoot
+		if c.Kind == archive.ChangeDelete {
+			root = fromRoot
+		}
+		size, dgst, err := fileDigest(filepath.Join(root, c.Path))
+		if err != nil {
+			return nil, err
+		}
+		diff = append(diff, image.DiffResponseItem{
+			Kind:   uint8(c.Kind),
+			Path:   c.Path,
+			Size:   size,
+			Digest: dgst.String(),
+		})
+	}
+	return diff, nil
+}
+
+// ImageFiles lists the directory at path in the filesystem of the image
+// named imageName. If path is not a directory, only path itself is listed.
+func (i *ImageService) ImageFiles(imageName, path string) ([]image.FileInfo, error) {
+	img, err := i.GetImage(imageName)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	rwLayer, err := i.mountImage(img)
+	if err != nil {
+		return nil, err
+	}
+	defer rwLayer.Release()
+
+	resolved, err := rwLayer.Root().ResolveScopedPath(path, true)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Lstat(resolved)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errdefs.NotFound(errors.Errorf("path %s does not exist in image %s", path, imageName))
+		}
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return []image.FileInfo{newFileInfo(resolved, fi)}, nil
+	}
+
+	entries, err := ioutil.ReadDir(resolved)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]image.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		files = append(files, newFileInfo(filepath.Join(resolved, e.Name()), e))
+	}
+	return files, nil
+}
+
+// mountImage mounts the filesystem of img on a new read-write layer. The
+// caller must release the returned layer.
+func (i *ImageService) mountImage(img *imagepkg.Image) (builder.RWLayer, error) {
+	operatingSystem := img.OperatingSystem()
+	if !system.IsOSSupported(operatingSystem) {
+		return nil, errdefs.InvalidParameter(system.ErrNotSupportedOperatingSystem)
+	}
+	roLayer, err := newROLayerForImage(img, i.layerStores[operatingSystem])
+	if err != nil {
+		return nil, err
+	}
+	defer roLayer.Release()
+	return roLayer.NewRWLayer()
+}
+
+// fileDigest returns the size and digest of the file at path if it is a
+// regular file.
+func fileDigest(path string) (int64, digest.Digest, error) {
+	fi, err := os.Lstat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return 0, "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	dgst, err := digest.FromReader(f)
+	if err != nil {
+		return 0, "", err
+	}
+	return fi.Size(), dgst, nil
+}
+
+func newFileInfo(path string, fi os.FileInfo) image.FileInfo {
+	info := image.FileInfo{
+		Name:    fi.Name(),
+		Size:    fi.Size(),
+		Mode:    fi.Mode(),
+		ModTime: fi.ModTime(),
+	}
+	if fi.Mode()&os.ModeSymlink != 0 {
+		info.LinkTarget, _ = os.Readlink(path)
+	}
+	return info
+}
@@ -63,6 +63,11 @@ func (i *ImageService) LookupImage(name string) (*types.ImageInspect, error) {
 		return nil, err
 	}
 
+	provenance, err := i.imageProvenance(img.ID())
+	if err != nil {
+		return nil, err
+	}
+
 	imageInspect := &types.ImageInspect{
 		ID:              img.ID().String(),
 		RepoTags:        repoTags,
@@ -84,6 +89,7 @@ func (i *ImageService) LookupImage(name string) (*types.ImageInspect, error) {
 		Metadata: types.ImageMetadata{
 			LastTagTime: lastUpdated,
 		},
+		Provenance: provenance,
 	}
 
 	imageInspect.GraphDriver.Name = i.layerStores[img.OperatingSystem()].DriverName()
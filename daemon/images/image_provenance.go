@@ -0,0 +1,32 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"encoding/json"
+
+	imagetypes "github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/image"
+	"github.com/pkg/errors"
+)
+
+// SetImageProvenance records how the image with the given ID was built.
+func (i *ImageService) SetImageProvenance(id image.ID, provenance *imagetypes.Provenance) error {
+	dt, err := json.Marshal(provenance)
+	if err != nil {
+		return err
+	}
+	return i.imageStore.SetProvenance(id, dt)
+}
+
+// imageProvenance returns how the image with the given ID was built, or nil
+// if it was not built by the daemon.
+func (i *ImageService) imageProvenance(id image.ID) (*imagetypes.Provenance, error) {
+	dt, err := i.imageStore.GetProvenance(id)
+	if err != nil || dt == nil {
+		return nil, err
+	}
+	var provenance imagetypes.Provenance
+	if err := json.Unmarshal(dt, &provenance); err != nil {
+		return nil, errors.Wrapf(err, "invalid provenance of image %s", id)
+	}
+	return &provenance, nil
+}
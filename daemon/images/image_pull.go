@@ -79,6 +79,7 @@ func (i *ImageService) pullImageWithReference(ctx context.Context, ref reference
 		DownloadManager: i.downloadManager,
 		Schema2Types:    distribution.ImageTypes,
 		Platform:        platform,
+		PartialStore:    i.partialStore,
 	}
 
 	err := distribution.Pull(ctx, ref, imagePullConfig)
@@ -52,10 +52,12 @@ func (i *ImageService) PushImage(ctx context.Context, image, tag string, metaHea
 			ImageStore:       distribution.NewImageConfigStoreFromStore(i.imageStore),
 			ReferenceStore:   i.referenceStore,
 		},
-		ConfigMediaType: schema2.MediaTypeImageConfig,
-		LayerStores:     distribution.NewLayerProvidersFromStores(i.layerStores),
-		TrustKey:        i.trustKey,
-		UploadManager:   i.uploadManager,
+		ConfigMediaType:       schema2.MediaTypeImageConfig,
+		LayerStores:           distribution.NewLayerProvidersFromStores(i.layerStores),
+		TrustKey:              i.trustKey,
+		UploadManager:         i.uploadManager,
+		LayerCompression:      i.pushCompression,
+		LayerCompressionLevel: i.pushCompressionLevel,
 	}
 
 	err = distribution.Push(ctx, ref, imagePushConfig)
@@ -0,0 +1,101 @@
+package images // import "github.com/docker/docker/daemon/images"
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/system"
+	"github.com/pkg/errors"
+)
+
+// RebaseImage creates a new image from the image named imageName, in which
+// the layers and history of the image oldBase are replaced by those of the
+// image newBase. The image must have been built on top of oldBase. The
+// layers the image adds on top of its base are registered again on top of
+// newBase from their tar streams, no container is run.
+func (i *ImageService) RebaseImage(imageName, oldBase, newBase string) (string, error) {
+	img, err := i.GetImage(imageName)
+	if err != nil {
+		return "", err
+	}
+	oldBaseImg, err := i.GetImage(oldBase)
+	if err != nil {
+		return "", err
+	}
+	newBaseImg, err := i.GetImage(newBase)
+	if err != nil {
+		return "", err
+	}
+
+	os := img.OperatingSystem()
+	if oldBaseImg.OperatingSystem() != os || newBaseImg.OperatingSystem() != os {
+		return "", errdefs.InvalidParameter(errors.New("image and base images must have the same operating system"))
+	}
+	if !system.IsOSSupported(os) {
+		return "", errdefs.InvalidParameter(system.ErrNotSupportedOperatingSystem)
+	}
+
+	oldDiffIDs := oldBaseImg.RootFS.DiffIDs
+	diffIDs := img.RootFS.DiffIDs
+	if len(diffIDs) < len(oldDiffIDs) || !reflect.DeepEqual(diffIDs[:len(oldDiffIDs)], oldDiffIDs) {
+		return "", errdefs.InvalidParameter(errors.Errorf("image %s is not based on %s", imageName, oldBase))
+	}
+	if len(img.History) < len(oldBaseImg.History) || !reflect.DeepEqual(img.History[:len(oldBaseImg.History)], oldBaseImg.History) {
+		return "", errdefs.InvalidParameter(errors.Errorf("history of image %s does not start with the history of %s", imageName, oldBase))
+	}
+
+	layerStore := i.layerStores[os]
+	rootFS := newBaseImg.RootFS.Clone()
+	for n := len(oldDiffIDs); n < len(diffIDs); n++ {
+		l, err := rebaseLayer(layerStore, layer.CreateChainID(diffIDs[:n+1]), rootFS.ChainID())
+		if err != nil {
+			return "", err
+		}
+		defer layer.ReleaseAndLog(layerStore, l)
+		rootFS.Append(l.DiffID())
+	}
+
+	rebased := *img
+	rebased.Parent = ""
+	rebased.RootFS = rootFS
+	rebased.History = append(append([]image.History{}, newBaseImg.History...), img.History[len(oldBaseImg.History):]...)
+
+	config, err := json.Marshal(&rebased)
+	if err != nil {
+		return "", err
+	}
+	id, err := i.imageStore.Create(config)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// rebaseLayer registers the content of the layer chainID on top of the layer
+// chain parent. The caller must release the returned layer.
+func rebaseLayer(layerStore layer.Store, chainID, parent layer.ChainID) (layer.Layer, error) {
+	l, err := layerStore.Get(chainID)
+	if err != nil {
+		return nil, err
+	}
+	defer layer.ReleaseAndLog(layerStore, l)
+
+	ts, err := l.TarStream()
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Close()
+
+	rebased, err := layerStore.Register(ts, parent)
+	if err != nil {
+		return nil, err
+	}
+	if rebased.DiffID() != l.DiffID() {
+		layer.ReleaseAndLog(layerStore, rebased)
+		return nil, errors.Errorf("layer %s changed content when rebased, got %s", l.DiffID(), rebased.DiffID())
+	}
+	return rebased, nil
+}
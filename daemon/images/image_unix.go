@@ -43,3 +43,22 @@ func (i *ImageService) GetContainerLayerSize(containerID string) (int64, int64)
 	}
 	return sizeRw, sizeRootfs
 }
+
+// GetContainerLayerUsage returns the disk usage of the writable layer of the
+// container, and false if the graphdriver cannot report it without walking
+// the files of the layer.
+func (i *ImageService) GetContainerLayerUsage(containerID string) (int64, bool) {
+	rwlayer, err := i.layerStores[runtime.GOOS].GetRWLayer(containerID)
+	if err != nil {
+		return 0, false
+	}
+	defer i.layerStores[runtime.GOOS].ReleaseRWLayer(rwlayer)
+
+	usage, ok, err := rwlayer.Usage()
+	if err != nil {
+		logrus.Debugf("Driver %s couldn't return disk usage of container %s: %s",
+			i.layerStores[runtime.GOOS].DriverName(), containerID, err)
+		return 0, false
+	}
+	return usage, ok
+}
@@ -13,6 +13,12 @@ func (i *ImageService) GetContainerLayerSize(containerID string) (int64, int64)
 	return 0, 0
 }
 
+// GetContainerLayerUsage returns the disk usage of the writable layer of the
+// container, which is not reported on Windows
+func (i *ImageService) GetContainerLayerUsage(containerID string) (int64, bool) {
+	return 0, false
+}
+
 // GetLayerFolders returns the layer folders from an image RootFS
 func (i *ImageService) GetLayerFolders(img *image.Image, rwLayer layer.RWLayer) ([]string, error) {
 	folders := []string{}
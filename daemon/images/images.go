@@ -12,6 +12,7 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/container"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
 	"github.com/docker/docker/pkg/system"
@@ -206,7 +207,7 @@ func (i *ImageService) Images(imageFilters filters.Args, all bool, withExtraAttr
 			// Get container count
 			newImage.Containers = 0
 			for _, c := range allContainers {
-				if c.ImageID == id {
+				if c.UsesImage(id) {
 					newImage.Containers++
 				}
 			}
@@ -271,25 +272,71 @@ func (i *ImageService) SquashImage(id, parent string) (string, error) {
 	}
 
 	var parentImg *image.Image
-	var parentChainID layer.ChainID
 	if len(parent) != 0 {
 		parentImg, err = i.imageStore.Get(image.ID(parent))
 		if err != nil {
 			return "", errors.Wrap(err, "error getting specified parent layer")
 		}
-		parentChainID = parentImg.RootFS.ChainID()
 	} else {
 		rootFS := image.NewRootFS()
 		parentImg = &image.Image{RootFS: rootFS}
 	}
+
+	var historyComment string
+	if len(parent) > 0 {
+		historyComment = fmt.Sprintf("merge %s to %s", id, parent)
+	} else {
+		historyComment = fmt.Sprintf("create new from %s", id)
+	}
+
+	return i.squashImage(img, parentImg.RootFS, len(parentImg.History), historyComment)
+}
+
+// SquashImageLayers creates a new image from the image named imageName, in
+// which the layers from index from upwards are merged into a single layer.
+// The layers below from are kept as they are. The config and the history of
+// the image are preserved, with the history of the merged layers marked as
+// empty layers.
+func (i *ImageService) SquashImageLayers(imageName string, from int) (string, error) {
+	img, err := i.GetImage(imageName)
+	if err != nil {
+		return "", err
+	}
+	if from < 0 || from >= len(img.RootFS.DiffIDs) {
+		return "", errdefs.InvalidParameter(errors.Errorf("layer index %d is out of range, image %s has %d layers", from, imageName, len(img.RootFS.DiffIDs)))
+	}
+
+	parentRootFS := img.RootFS.Clone()
+	parentRootFS.DiffIDs = parentRootFS.DiffIDs[:from]
+
+	// Find the history entries describing the layers which are kept.
+	parentHistoryLen := 0
+	for layers := 0; layers < from && parentHistoryLen < len(img.History); parentHistoryLen++ {
+		if !img.History[parentHistoryLen].EmptyLayer {
+			layers++
+		}
+	}
+
+	historyComment := fmt.Sprintf("squash layers %d to %d of %s", from, len(img.RootFS.DiffIDs)-1, img.ID())
+	return i.squashImage(img, parentRootFS, parentHistoryLen, historyComment)
+}
+
+// squashImage creates a new image from img, in which all layers above
+// parentRootFS are merged into a single layer. The history entries from
+// parentHistoryLen onwards are marked as empty layers, and an entry with
+// historyComment is added for the merged layer.
+func (i *ImageService) squashImage(img *image.Image, parentRootFS *image.RootFS, parentHistoryLen int, historyComment string) (string, error) {
 	if !system.IsOSSupported(img.OperatingSystem()) {
-		return "", errors.Wrap(err, system.ErrNotSupportedOperatingSystem.Error())
+		return "", system.ErrNotSupportedOperatingSystem
 	}
-	l, err := i.layerStores[img.OperatingSystem()].Get(img.RootFS.ChainID())
+	layerStore := i.layerStores[img.OperatingSystem()]
+	parentChainID := parentRootFS.ChainID()
+
+	l, err := layerStore.Get(img.RootFS.ChainID())
 	if err != nil {
 		return "", errors.Wrap(err, "error getting image layer")
 	}
-	defer i.layerStores[img.OperatingSystem()].Release(l)
+	defer layerStore.Release(l)
 
 	ts, err := l.TarStreamFrom(parentChainID)
 	if err != nil {
@@ -297,34 +344,27 @@ func (i *ImageService) SquashImage(id, parent string) (string, error) {
 	}
 	defer ts.Close()
 
-	newL, err := i.layerStores[img.OperatingSystem()].Register(ts, parentChainID)
+	newL, err := layerStore.Register(ts, parentChainID)
 	if err != nil {
 		return "", errors.Wrap(err, "error registering layer")
 	}
-	defer i.layerStores[img.OperatingSystem()].Release(newL)
+	defer layerStore.Release(newL)
 
 	newImage := *img
-	newImage.RootFS = nil
 
-	rootFS := *parentImg.RootFS
-	rootFS.DiffIDs = append(rootFS.DiffIDs, newL.DiffID())
-	newImage.RootFS = &rootFS
+	rootFS := parentRootFS.Clone()
+	rootFS.Append(newL.DiffID())
+	newImage.RootFS = rootFS
 
-	for i, hi := range newImage.History {
-		if i >= len(parentImg.History) {
+	newImage.History = make([]image.History, len(img.History))
+	for i, hi := range img.History {
+		if i >= parentHistoryLen {
 			hi.EmptyLayer = true
 		}
 		newImage.History[i] = hi
 	}
 
 	now := time.Now()
-	var historyComment string
-	if len(parent) > 0 {
-		historyComment = fmt.Sprintf("merge %s to %s", id, parent)
-	} else {
-		historyComment = fmt.Sprintf("create new from %s", id)
-	}
-
 	newImage.History = append(newImage.History, image.History{
 		Created: now,
 		Comment: historyComment,
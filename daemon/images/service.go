@@ -2,6 +2,7 @@ package images // import "github.com/docker/docker/daemon/images"
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"runtime"
 
@@ -9,9 +10,12 @@ import (
 	daemonevents "github.com/docker/docker/daemon/events"
 	"github.com/docker/docker/distribution"
 	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/distribution/partial"
 	"github.com/docker/docker/distribution/xfer"
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	dockerreference "github.com/docker/docker/reference"
 	"github.com/docker/docker/registry"
 	"github.com/docker/libtrust"
@@ -38,6 +42,9 @@ type ImageServiceConfig struct {
 	LayerStores               map[string]layer.Store
 	MaxConcurrentDownloads    int
 	MaxConcurrentUploads      int
+	PartialStore              *partial.Store
+	PushCompression           archive.Compression
+	PushCompressionLevel      int
 	ReferenceStore            dockerreference.Store
 	RegistryService           registry.Service
 	TrustKey                  libtrust.PrivateKey
@@ -54,6 +61,9 @@ func NewImageService(config ImageServiceConfig) *ImageService {
 		eventsService:             config.EventsService,
 		imageStore:                config.ImageStore,
 		layerStores:               config.LayerStores,
+		partialStore:              config.PartialStore,
+		pushCompression:           config.PushCompression,
+		pushCompressionLevel:      config.PushCompressionLevel,
 		referenceStore:            config.ReferenceStore,
 		registryService:           config.RegistryService,
 		trustKey:                  config.TrustKey,
@@ -69,7 +79,10 @@ type ImageService struct {
 	eventsService             *daemonevents.Events
 	imageStore                image.Store
 	layerStores               map[string]layer.Store // By operating system
+	partialStore              *partial.Store
 	pruneRunning              int32
+	pushCompression           archive.Compression
+	pushCompressionLevel      int
 	referenceStore            dockerreference.Store
 	registryService           registry.Service
 	trustKey                  libtrust.PrivateKey
@@ -133,6 +146,30 @@ func (i *ImageService) CreateLayer(container *container.Container, initFunc laye
 	return i.layerStores[container.OS].CreateRWLayer(container.ID, layerID, rwLayerOpts)
 }
 
+// CreateImageMountLayer creates a filesystem layer named name with the
+// contents of the image refOrID, to mount the image into a container. The
+// container holds a reference to the layers of the image until the returned
+// layer is released with ReleaseLayer.
+// called from volumes.go
+func (i *ImageService) CreateImageMountLayer(name, refOrID string, container *container.Container) (layer.RWLayer, image.ID, error) {
+	img, err := i.GetImage(refOrID)
+	if err != nil {
+		return nil, "", err
+	}
+	if img.OperatingSystem() != container.OS {
+		return nil, "", errdefs.InvalidParameter(fmt.Errorf("cannot mount image %s of operating system %q into a %q container", refOrID, img.OperatingSystem(), container.OS))
+	}
+
+	rwLayerOpts := &layer.CreateRWLayerOpts{
+		MountLabel: container.MountLabel,
+	}
+	rwLayer, err := i.layerStores[container.OS].CreateRWLayer(name, img.RootFS.ChainID(), rwLayerOpts)
+	if err != nil {
+		return nil, "", err
+	}
+	return rwLayer, img.ID(), nil
+}
+
 // GetLayerByID returns a layer by ID and operating system
 // called from daemon.go Daemon.restore(), and Daemon.containerExport()
 func (i *ImageService) GetLayerByID(cid string, os string) (layer.RWLayer, error) {
@@ -23,7 +23,8 @@ var (
 	healthChecksCounter       metrics.Counter
 	healthChecksFailedCounter metrics.Counter
 
-	stateCtr *stateCounter
+	stateCtr        *stateCounter
+	rwLayerUsageCtr *rwLayerUsageCollector
 )
 
 func init() {
@@ -57,6 +58,11 @@ func init() {
 	stateCtr = newStateCounter(ns.NewDesc("container_states", "The count of containers in various states", metrics.Unit("containers"), "state"))
 	ns.Add(stateCtr)
 
+	rwLayerUsageCtr = &rwLayerUsageCollector{
+		desc: ns.NewDesc("container_rw_layer", "The number of bytes used by the writable layer of each container, when the storage driver accounts for it", metrics.Bytes, "id"),
+	}
+	ns.Add(rwLayerUsageCtr)
+
 	metrics.Register(ns)
 }
 
@@ -111,6 +117,38 @@ func (ctr *stateCounter) Collect(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(ctr.desc, prometheus.GaugeValue, float64(stopped), "stopped")
 }
 
+// rwLayerUsageCollector reports the disk usage of the writable layers of
+// containers, read from the storage driver each time metrics are collected.
+type rwLayerUsageCollector struct {
+	mu     sync.Mutex
+	daemon *Daemon
+	desc   *prometheus.Desc
+}
+
+func (ctr *rwLayerUsageCollector) setDaemon(d *Daemon) {
+	ctr.mu.Lock()
+	ctr.daemon = d
+	ctr.mu.Unlock()
+}
+
+func (ctr *rwLayerUsageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ctr.desc
+}
+
+func (ctr *rwLayerUsageCollector) Collect(ch chan<- prometheus.Metric) {
+	ctr.mu.Lock()
+	d := ctr.daemon
+	ctr.mu.Unlock()
+	if d == nil {
+		return
+	}
+	for _, c := range d.List() {
+		if usage, ok := d.imageService.GetContainerLayerUsage(c.ID); ok {
+			ch <- prometheus.MustNewConstMetric(ctr.desc, prometheus.GaugeValue, float64(usage), c.ID)
+		}
+	}
+}
+
 func (d *Daemon) cleanupMetricsPlugins() {
 	ls := d.PluginStore.GetAllManagedPluginsByCap(metricsPluginType)
 	var wg sync.WaitGroup
@@ -15,6 +15,9 @@ func (daemon *Daemon) prepareMountPoints(container *container.Container) error {
 		if err := daemon.lazyInitializeVolume(container.ID, config); err != nil {
 			return err
 		}
+		if err := daemon.lazyInitializeImageMount(container, config); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -23,6 +26,14 @@ func (daemon *Daemon) removeMountPoints(container *container.Container, rm bool)
 	var rmErrors []string
 	ctx := context.TODO()
 	for _, m := range container.MountPoints {
+		if m.Type == mounttypes.TypeImage {
+			if err := daemon.lazyInitializeImageMount(container, m); err != nil {
+				rmErrors = append(rmErrors, err.Error())
+				continue
+			}
+			daemon.releaseImageMount(container, m)
+			continue
+		}
 		if m.Type != mounttypes.TypeVolume || m.Volume == nil {
 			continue
 		}
@@ -14,6 +14,7 @@ import (
 	mounttypes "github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/container"
 	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/docker/volume"
 	volumemounts "github.com/docker/docker/volume/mounts"
 	"github.com/docker/docker/volume/service"
@@ -69,6 +70,10 @@ func (daemon *Daemon) registerMountPoints(container *container.Container, hostCo
 		// clean up the container mountpoints once return with error
 		if retErr != nil {
 			for _, m := range mountPoints {
+				if m.Layer != nil {
+					daemon.releaseImageMount(container, m)
+					continue
+				}
 				if m.Volume == nil {
 					continue
 				}
@@ -83,6 +88,9 @@ func (daemon *Daemon) registerMountPoints(container *container.Container, hostCo
 			if v.Volume != nil {
 				daemon.volumes.Release(ctx, v.Volume.Name(), container.ID)
 			}
+			if v.Layer != nil {
+				daemon.releaseImageMount(container, v)
+			}
 		}
 	}
 
@@ -116,7 +124,11 @@ func (daemon *Daemon) registerMountPoints(container *container.Container, hostCo
 				CopyData:    false,
 			}
 
-			if len(cp.Source) == 0 {
+			if cp.Type == mounttypes.TypeImage {
+				if err := daemon.createImageMount(container, cp); err != nil {
+					return err
+				}
+			} else if len(cp.Source) == 0 {
 				v, err := daemon.volumes.Get(ctx, cp.Name, volumeopts.WithGetDriver(cp.Driver), volumeopts.WithGetReference(container.ID))
 				if err != nil {
 					return err
@@ -217,6 +229,12 @@ func (daemon *Daemon) registerMountPoints(container *container.Container, hostCo
 			}
 		}
 
+		if mp.Type == mounttypes.TypeImage {
+			if err := daemon.createImageMount(container, mp); err != nil {
+				return err
+			}
+		}
+
 		if mp.Type == mounttypes.TypeBind {
 			mp.SkipMountpointCreation = true
 		}
@@ -243,6 +261,47 @@ func (daemon *Daemon) registerMountPoints(container *container.Container, hostCo
 	return nil
 }
 
+// createImageMount creates the layer of the image mount m, which holds a
+// reference to the layers of the image for the lifetime of the container.
+func (daemon *Daemon) createImageMount(container *container.Container, m *volumemounts.MountPoint) error {
+	// Mounts copied from another container refer to the image by ID
+	ref := m.Name
+	if ref == "" {
+		ref = m.Spec.Source
+	}
+	name := stringid.GenerateRandomID()
+	rwLayer, imgID, err := daemon.imageService.CreateImageMountLayer(name, ref, container)
+	if err != nil {
+		return err
+	}
+	m.Layer = rwLayer
+	m.ID = name
+	m.Name = imgID.String()
+	return nil
+}
+
+// releaseImageMount releases the layer of the image mount m.
+func (daemon *Daemon) releaseImageMount(container *container.Container, m *volumemounts.MountPoint) {
+	if err := daemon.imageService.ReleaseLayer(m.Layer, container.OS); err != nil {
+		logrus.WithError(err).WithField("container", container.ID).Errorf("Error releasing layer of image mount %s", m.Destination)
+		return
+	}
+	m.Layer = nil
+}
+
+// lazyInitializeImageMount gets the layer of an image mount if needed.
+func (daemon *Daemon) lazyInitializeImageMount(container *container.Container, m *volumemounts.MountPoint) error {
+	if m.Type != mounttypes.TypeImage || m.Layer != nil {
+		return nil
+	}
+	rwLayer, err := daemon.imageService.GetLayerByID(m.ID, container.OS)
+	if err != nil {
+		return errors.Wrapf(err, "error getting layer of image mount %s", m.Destination)
+	}
+	m.Layer = rwLayer
+	return nil
+}
+
 // lazyInitializeVolume initializes a mountpoint's volume if needed.
 // This happens after a daemon restart.
 func (daemon *Daemon) lazyInitializeVolume(containerID string, m *volumemounts.MountPoint) error {
@@ -11,9 +11,11 @@ import (
 	"github.com/docker/distribution/manifest/schema2"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/distribution/partial"
 	"github.com/docker/docker/distribution/xfer"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/system"
 	refstore "github.com/docker/docker/reference"
@@ -62,6 +64,10 @@ type ImagePullConfig struct {
 	Schema2Types []string
 	// Platform is the requested platform of the image being pulled
 	Platform *specs.Platform
+	// PartialStore persists partially downloaded layers, so that they
+	// can be resumed by a later pull. This value is optional, when
+	// excluded layers are downloaded to temporary files.
+	PartialStore *partial.Store
 }
 
 // ImagePushConfig stores push configuration.
@@ -78,6 +84,14 @@ type ImagePushConfig struct {
 	TrustKey libtrust.PrivateKey
 	// UploadManager dispatches uploads.
 	UploadManager *xfer.LayerUploadManager
+	// LayerCompression is the compression algorithm applied to layers
+	// that are stored uncompressed. Only zstd can be selected, any other
+	// value selects gzip.
+	LayerCompression archive.Compression
+	// LayerCompressionLevel is the compression level applied to layers
+	// that are stored uncompressed. 0 selects the default level of the
+	// compression algorithm.
+	LayerCompressionLevel int
 }
 
 // ImageConfigStore handles storing and getting image configurations
@@ -134,6 +148,10 @@ func (s *imageConfigStore) Put(c []byte) (digest.Digest, error) {
 }
 
 func (s *imageConfigStore) Get(d digest.Digest) ([]byte, error) {
+	if s.Store.Quarantined(image.IDFromDigest(d)) {
+		// Pull the layers of the image again
+		return nil, fmt.Errorf("layers of image %s are quarantined", d)
+	}
 	img, err := s.Store.Get(image.IDFromDigest(d))
 	if err != nil {
 		return nil, err
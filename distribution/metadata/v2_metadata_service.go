@@ -36,6 +36,9 @@ type V2Metadata struct {
 	// HMAC hashes above attributes with recent authconfig digest used as a key in order to determine matching
 	// metadata entries accompanied by the same credentials without actually exposing them.
 	HMAC string
+	// MediaType is the media type of the blob if it is not a gzip
+	// compressed layer, for example a zstd compressed one.
+	MediaType string `json:",omitempty"`
 }
 
 // CheckV2MetadataHMAC returns true if the given "meta" is tagged with a hmac hashed by the given "key".
@@ -0,0 +1,133 @@
+package partial // import "github.com/docker/docker/distribution/partial"
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrInUse is returned by Open when the partial download for a digest is
+// already held by another download.
+var ErrInUse = errors.New("partial download is already in use")
+
+// Store keeps partially downloaded blobs on disk, keyed by digest, so that an
+// interrupted download can be resumed by a later pull, even after the daemon
+// has been restarted. Store is goroutine-safe.
+type Store struct {
+	mu    sync.Mutex
+	root  string
+	inUse map[digest.Digest]struct{}
+}
+
+// NewStore creates a new partial download store rooted at root.
+func NewStore(root string) (*Store, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{
+		root:  root,
+		inUse: make(map[digest.Digest]struct{}),
+	}, nil
+}
+
+func (s *Store) path(dgst digest.Digest) string {
+	return filepath.Join(s.root, string(dgst.Algorithm()), dgst.Hex())
+}
+
+// Open opens the partial download for dgst, creating it if it does not exist
+// yet. The returned file is positioned at its start; its size is the number of
+// bytes downloaded so far. The digest is held until Release or Remove is
+// called for it.
+func (s *Store) Open(dgst digest.Digest) (*os.File, error) {
+	if err := dgst.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.inUse[dgst]; ok {
+		return nil, errors.Wrap(ErrInUse, dgst.String())
+	}
+
+	p := s.path(dgst)
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(p, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// Bump the modification time so that a partial download which is
+	// resumed is not considered stale by Prune.
+	now := time.Now()
+	if err := os.Chtimes(p, now, now); err != nil {
+		logrus.Debugf("failed to update modification time of partial download %s: %v", p, err)
+	}
+	s.inUse[dgst] = struct{}{}
+	return f, nil
+}
+
+// Release gives up the hold on the partial download for dgst, leaving its
+// content on disk so that it can be resumed later.
+func (s *Store) Release(dgst digest.Digest) {
+	s.mu.Lock()
+	delete(s.inUse, dgst)
+	s.mu.Unlock()
+}
+
+// Remove deletes the partial download for dgst and releases it.
+func (s *Store) Remove(dgst digest.Digest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inUse, dgst)
+	if err := os.Remove(s.path(dgst)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Prune removes partial downloads which are not in use and have not been
+// written to for longer than maxAge.
+func (s *Store) Prune(maxAge time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	algs, err := ioutil.ReadDir(s.root)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-maxAge)
+	for _, alg := range algs {
+		if !alg.IsDir() {
+			continue
+		}
+		entries, err := ioutil.ReadDir(filepath.Join(s.root, alg.Name()))
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.ModTime().After(cutoff) {
+				continue
+			}
+			dgst := digest.NewDigestFromHex(alg.Name(), e.Name())
+			if _, ok := s.inUse[dgst]; ok {
+				continue
+			}
+			p := filepath.Join(s.root, alg.Name(), e.Name())
+			if err := os.RemoveAll(p); err != nil {
+				logrus.Warnf("failed to remove stale partial download %s: %v", p, err)
+				continue
+			}
+			logrus.Debugf("removed stale partial download %s", dgst)
+		}
+	}
+	return nil
+}
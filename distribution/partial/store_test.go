@@ -0,0 +1,85 @@
+package partial // import "github.com/docker/docker/distribution/partial"
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+func newTestStore(t *testing.T) (*Store, func()) {
+	tmpDir, err := ioutil.TempDir("", "partial-store-test")
+	assert.NilError(t, err)
+	s, err := NewStore(tmpDir)
+	assert.NilError(t, err)
+	return s, func() { os.RemoveAll(tmpDir) }
+}
+
+func TestOpenResumesContent(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	dgst := digest.FromString("layer")
+	f, err := s.Open(dgst)
+	assert.NilError(t, err)
+	_, err = f.Write([]byte("partial"))
+	assert.NilError(t, err)
+	assert.NilError(t, f.Close())
+
+	_, err = s.Open(dgst)
+	assert.Check(t, errors.Cause(err) == ErrInUse)
+
+	s.Release(dgst)
+	f, err = s.Open(dgst)
+	assert.NilError(t, err)
+	defer f.Close()
+	content, err := ioutil.ReadAll(f)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("partial", string(content)))
+
+	assert.NilError(t, s.Remove(dgst))
+	_, err = os.Stat(s.path(dgst))
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestOpenInvalidDigest(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	_, err := s.Open(digest.Digest("sha256:../../etc"))
+	assert.Check(t, err != nil)
+}
+
+func TestPrune(t *testing.T) {
+	s, cleanup := newTestStore(t)
+	defer cleanup()
+
+	stale := digest.FromString("stale")
+	fresh := digest.FromString("fresh")
+	held := digest.FromString("held")
+	for _, dgst := range []digest.Digest{stale, fresh, held} {
+		f, err := s.Open(dgst)
+		assert.NilError(t, err)
+		f.Close()
+	}
+	s.Release(stale)
+	s.Release(fresh)
+
+	old := time.Now().Add(-48 * time.Hour)
+	assert.NilError(t, os.Chtimes(s.path(stale), old, old))
+	assert.NilError(t, os.Chtimes(s.path(held), old, old))
+
+	assert.NilError(t, s.Prune(24*time.Hour))
+
+	_, err := os.Stat(s.path(stale))
+	assert.Check(t, os.IsNotExist(err))
+	_, err = os.Stat(s.path(fresh))
+	assert.Check(t, err)
+	_, err = os.Stat(s.path(held))
+	assert.Check(t, err)
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/docker/distribution/registry/client/auth"
 	"github.com/docker/distribution/registry/client/transport"
 	"github.com/docker/docker/distribution/metadata"
+	"github.com/docker/docker/distribution/partial"
 	"github.com/docker/docker/distribution/xfer"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/image/v1"
@@ -31,6 +32,7 @@ import (
 	"github.com/docker/docker/pkg/system"
 	refstore "github.com/docker/docker/reference"
 	"github.com/docker/docker/registry"
+	"github.com/docker/go-units"
 	"github.com/opencontainers/go-digest"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
@@ -142,6 +144,10 @@ type v2LayerDescriptor struct {
 	tmpFile           *os.File
 	verifier          digest.Verifier
 	src               distribution.Descriptor
+	// partialStore persists tmpFile across pulls, if set.
+	partialStore *partial.Store
+	// persisted is true if tmpFile is held in partialStore.
+	persisted bool
 }
 
 func (ld *v2LayerDescriptor) Key() string {
@@ -168,21 +174,20 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 	)
 
 	if ld.tmpFile == nil {
-		ld.tmpFile, err = createDownloadFile()
+		ld.tmpFile, offset, err = ld.openDownloadFile()
 		if err != nil {
 			return nil, 0, xfer.DoNotRetry{Err: err}
 		}
+		if offset != 0 {
+			logrus.Debugf("attempting to resume partial download of %q from %d bytes", ld.digest, offset)
+		}
 	} else {
 		offset, err = ld.tmpFile.Seek(0, os.SEEK_END)
 		if err != nil {
 			logrus.Debugf("error seeking to end of download file: %v", err)
-			offset = 0
 
-			ld.tmpFile.Close()
-			if err := os.Remove(ld.tmpFile.Name()); err != nil {
-				logrus.Errorf("Failed to remove temp file: %s", ld.tmpFile.Name())
-			}
-			ld.tmpFile, err = createDownloadFile()
+			ld.removeDownloadFile()
+			ld.tmpFile, offset, err = ld.openDownloadFile()
 			if err != nil {
 				return nil, 0, xfer.DoNotRetry{Err: err}
 			}
@@ -232,6 +237,10 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 		}
 	}
 
+	if offset != 0 {
+		progress.Updatef(progressOutput, ld.ID(), "Resuming download from %s", units.HumanSize(float64(offset)))
+	}
+
 	reader := progress.NewProgressReader(ioutils.NewCancelReadCloser(ctx, layerDownload), progressOutput, size-offset, ld.ID(), "Downloading")
 	defer reader.Close()
 
@@ -265,6 +274,13 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 
 			return nil, 0, err
 		}
+		// Do not leave the corrupt content behind for a later pull to
+		// resume from.
+		if ld.persisted {
+			if err := ld.truncateDownloadFile(); err != nil {
+				logrus.Warnf("Failed to discard partial download of %s: %v", ld.digest, err)
+			}
+		}
 		return nil, 0, xfer.DoNotRetry{Err: err}
 	}
 
@@ -274,21 +290,21 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 
 	_, err = tmpFile.Seek(0, os.SEEK_SET)
 	if err != nil {
-		tmpFile.Close()
-		if err := os.Remove(tmpFile.Name()); err != nil {
-			logrus.Errorf("Failed to remove temp file: %s", tmpFile.Name())
-		}
-		ld.tmpFile = nil
-		ld.verifier = nil
+		ld.removeDownloadFile()
 		return nil, 0, xfer.DoNotRetry{Err: err}
 	}
 
 	// hand off the temporary file to the download manager, so it will only
 	// be closed once
 	ld.tmpFile = nil
+	persisted := ld.persisted
+	ld.persisted = false
 
 	return ioutils.NewReadCloserWrapper(tmpFile, func() error {
 		tmpFile.Close()
+		if persisted {
+			return ld.partialStore.Remove(ld.digest)
+		}
 		err := os.RemoveAll(tmpFile.Name())
 		if err != nil {
 			logrus.Errorf("Failed to remove temp file: %s", tmpFile.Name())
@@ -298,12 +314,67 @@ func (ld *v2LayerDescriptor) Download(ctx context.Context, progressOutput progre
 }
 
 func (ld *v2LayerDescriptor) Close() {
-	if ld.tmpFile != nil {
+	if ld.tmpFile == nil {
+		return
+	}
+	if ld.persisted {
+		// Keep the partial download around, so that a later pull can
+		// resume it.
 		ld.tmpFile.Close()
-		if err := os.RemoveAll(ld.tmpFile.Name()); err != nil {
-			logrus.Errorf("Failed to remove temp file: %s", ld.tmpFile.Name())
+		ld.partialStore.Release(ld.digest)
+		ld.tmpFile = nil
+		ld.persisted = false
+		return
+	}
+	ld.removeDownloadFile()
+}
+
+// openDownloadFile opens the file the layer is downloaded to, and returns the
+// number of bytes it already holds. If partial downloads are persisted, the
+// partial download of a previous pull is reused and the verifier is primed
+// with its content.
+func (ld *v2LayerDescriptor) openDownloadFile() (*os.File, int64, error) {
+	if ld.partialStore == nil {
+		f, err := createDownloadFile()
+		return f, 0, err
+	}
+
+	f, err := ld.partialStore.Open(ld.digest)
+	if err != nil {
+		logrus.Debugf("not persisting download of %s: %v", ld.digest, err)
+		f, err := createDownloadFile()
+		return f, 0, err
+	}
+	ld.tmpFile = f
+	ld.persisted = true
+	ld.verifier = ld.digest.Verifier()
+
+	offset, err := io.Copy(ld.verifier, f)
+	if err != nil {
+		logrus.Debugf("error reading partial download of %s: %v", ld.digest, err)
+		if err := ld.truncateDownloadFile(); err != nil {
+			ld.removeDownloadFile()
+			return nil, 0, err
+		}
+		offset = 0
+	}
+	return f, offset, nil
+}
+
+// removeDownloadFile closes and removes the file the layer is downloaded to,
+// discarding its content.
+func (ld *v2LayerDescriptor) removeDownloadFile() {
+	ld.tmpFile.Close()
+	if ld.persisted {
+		if err := ld.partialStore.Remove(ld.digest); err != nil {
+			logrus.Errorf("Failed to remove partial download of %s: %v", ld.digest, err)
 		}
+	} else if err := os.RemoveAll(ld.tmpFile.Name()); err != nil {
+		logrus.Errorf("Failed to remove temp file: %s", ld.tmpFile.Name())
 	}
+	ld.tmpFile = nil
+	ld.persisted = false
+	ld.verifier = nil
 }
 
 func (ld *v2LayerDescriptor) truncateDownloadFile() error {
@@ -325,7 +396,7 @@ func (ld *v2LayerDescriptor) truncateDownloadFile() error {
 
 func (ld *v2LayerDescriptor) Registered(diffID layer.DiffID) {
 	// Cache mapping from this layer's DiffID to the blobsum
-	ld.V2MetadataService.Add(diffID, metadata.V2Metadata{Digest: ld.digest, SourceRepository: ld.repoInfo.Name.Name()})
+	ld.V2MetadataService.Add(diffID, metadata.V2Metadata{Digest: ld.digest, SourceRepository: ld.repoInfo.Name.Name(), MediaType: metadataMediaType(ld.src.MediaType)})
 }
 
 func (p *v2Puller) pullV2Tag(ctx context.Context, ref reference.Named, platform *specs.Platform) (tagUpdated bool, err error) {
@@ -485,6 +556,7 @@ func (p *v2Puller) pullSchema1(ctx context.Context, ref reference.Reference, unv
 			repoInfo:          p.repoInfo,
 			repo:              p.repo,
 			V2MetadataService: p.V2MetadataService,
+			partialStore:      p.config.PartialStore,
 		}
 
 		descriptors = append(descriptors, layerDescriptor)
@@ -572,6 +644,7 @@ func (p *v2Puller) pullSchema2(ctx context.Context, ref reference.Named, mfst *s
 			repoInfo:          p.repoInfo,
 			V2MetadataService: p.V2MetadataService,
 			src:               d,
+			partialStore:      p.config.PartialStore,
 		}
 
 		descriptors = append(descriptors, layerDescriptor)
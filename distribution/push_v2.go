@@ -17,9 +17,12 @@ import (
 	"github.com/docker/distribution/registry/api/errcode"
 	"github.com/docker/distribution/registry/client"
 	apitypes "github.com/docker/docker/api/types"
+	imagetypes "github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/distribution/metadata"
 	"github.com/docker/docker/distribution/xfer"
+	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/progress"
 	"github.com/docker/docker/pkg/stringid"
@@ -143,6 +146,11 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 
 	var descriptors []xfer.UploadDescriptor
 
+	compression := p.config.LayerCompression
+	if compression != archive.Zstd {
+		compression = archive.Gzip
+	}
+
 	descriptorTemplate := v2PushDescriptor{
 		v2MetadataService: p.v2MetadataService,
 		hmacKey:           hmacKey,
@@ -151,6 +159,8 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 		endpoint:          p.endpoint,
 		repo:              p.repo,
 		pushState:         &p.pushState,
+		compression:       compression,
+		compressionLevel:  p.config.LayerCompressionLevel,
 	}
 
 	// Loop bounds condition is to avoid pushing the base layer on Windows.
@@ -222,6 +232,12 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 		return err
 	}
 
+	if _, ok := manifest.(*schema2.DeserializedManifest); ok {
+		if err := p.pushProvenance(ctx, manSvc, id, manifestDigest); err != nil {
+			logrus.Warnf("failed to upload provenance of %s: %v", reference.FamiliarString(ref), err)
+		}
+	}
+
 	// Signal digest to the trust client so it can sign the
 	// push, if appropriate.
 	progress.Aux(p.config.ProgressOutput, apitypes.PushResult{Tag: ref.Tag(), Digest: manifestDigest.String(), Size: len(canonicalManifest)})
@@ -229,6 +245,33 @@ func (p *v2Pusher) pushV2Tag(ctx context.Context, ref reference.NamedTagged, id
 	return nil
 }
 
+// provenanceStore is implemented by image config stores which keep the
+// provenance of the images they store.
+type provenanceStore interface {
+	GetProvenance(image.ID) ([]byte, error)
+}
+
+// pushProvenance pushes the provenance of the image id, if it has any, as an
+// artifact with no layers. It is tagged after the digest of the manifest of
+// the image, as "sha256-<hex>.prov", so that it can be found from the image.
+func (p *v2Pusher) pushProvenance(ctx context.Context, manSvc distribution.ManifestService, id digest.Digest, manifestDigest digest.Digest) error {
+	ps, ok := p.config.ImageStore.(provenanceStore)
+	if !ok {
+		return nil
+	}
+	provenance, err := ps.GetProvenance(image.ID(id))
+	if err != nil || provenance == nil {
+		return err
+	}
+	manifest, err := schema2.NewManifestBuilder(p.repo.Blobs(ctx), imagetypes.ProvenanceMediaType, provenance).Build(ctx)
+	if err != nil {
+		return err
+	}
+	tag := fmt.Sprintf("%s-%s.prov", manifestDigest.Algorithm(), manifestDigest.Hex())
+	_, err = manSvc.Put(ctx, manifest, distribution.WithTag(tag))
+	return err
+}
+
 func manifestFromBuilder(ctx context.Context, builder distribution.ManifestBuilder, descriptors []xfer.UploadDescriptor) (distribution.Manifest, error) {
 	// descriptors is in reverse order; iterate backwards to get references
 	// appended in the right order.
@@ -253,6 +296,10 @@ type v2PushDescriptor struct {
 	remoteDescriptor  distribution.Descriptor
 	// a set of digests whose presence has been checked in a target repository
 	checkedDigests map[digest.Digest]struct{}
+	// compression and compressionLevel are applied to layers that are
+	// stored uncompressed
+	compression      archive.Compression
+	compressionLevel int
 }
 
 func (pd *v2PushDescriptor) Key() string {
@@ -267,6 +314,14 @@ func (pd *v2PushDescriptor) DiffID() layer.DiffID {
 	return pd.layer.DiffID()
 }
 
+// mediaType returns the media type the layer is pushed with.
+func (pd *v2PushDescriptor) mediaType() string {
+	if pd.layer.MediaType() == schema2.MediaTypeUncompressedLayer && pd.compression == archive.Zstd {
+		return mediaTypeImageLayerZstd
+	}
+	return schema2.MediaTypeLayer
+}
+
 func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.Output) (distribution.Descriptor, error) {
 	// Skip foreign layers unless this registry allows nondistributable artifacts.
 	if !pd.endpoint.AllowNondistributableArtifacts {
@@ -295,6 +350,9 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 	// Do we have any metadata associated with this layer's DiffID?
 	v2Metadata, err := pd.v2MetadataService.GetMetadata(diffID)
 	if err == nil {
+		// Only blobs compressed the same way as this push would upload
+		// can be reused.
+		v2Metadata = filterV2MetadataByCompression(v2Metadata, pd.mediaType())
 		// check for blob existence in the target repository
 		descriptor, exists, err := pd.layerAlreadyExists(ctx, progressOutput, diffID, true, 1, v2Metadata)
 		if exists || err != nil {
@@ -348,7 +406,7 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 		case distribution.ErrBlobMounted:
 			progress.Updatef(progressOutput, pd.ID(), "Mounted from %s", err.From.Name())
 
-			err.Descriptor.MediaType = schema2.MediaTypeLayer
+			err.Descriptor.MediaType = pd.mediaType()
 
 			pd.pushState.Lock()
 			pd.pushState.confirmedV2 = true
@@ -359,6 +417,7 @@ func (pd *v2PushDescriptor) Upload(ctx context.Context, progressOutput progress.
 			if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
 				Digest:           err.Descriptor.Digest,
 				SourceRepository: pd.repoInfo.Name(),
+				MediaType:        metadataMediaType(err.Descriptor.MediaType),
 			}); err != nil {
 				return distribution.Descriptor{}, xfer.DoNotRetry{Err: err}
 			}
@@ -447,7 +506,7 @@ func (pd *v2PushDescriptor) uploadUsingSession(
 
 	switch m := pd.layer.MediaType(); m {
 	case schema2.MediaTypeUncompressedLayer:
-		compressedReader, compressionDone := compress(reader)
+		compressedReader, compressionDone := compress(reader, pd.compression, pd.compressionLevel)
 		defer func(closer io.Closer) {
 			closer.Close()
 			<-compressionDone
@@ -480,13 +539,14 @@ func (pd *v2PushDescriptor) uploadUsingSession(
 	if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
 		Digest:           pushDigest,
 		SourceRepository: pd.repoInfo.Name(),
+		MediaType:        metadataMediaType(pd.mediaType()),
 	}); err != nil {
 		return distribution.Descriptor{}, xfer.DoNotRetry{Err: err}
 	}
 
 	desc := distribution.Descriptor{
 		Digest:    pushDigest,
-		MediaType: schema2.MediaTypeLayer,
+		MediaType: pd.mediaType(),
 		Size:      nn,
 	}
 
@@ -555,11 +615,12 @@ attempts:
 				if err := pd.v2MetadataService.TagAndAdd(diffID, pd.hmacKey, metadata.V2Metadata{
 					Digest:           desc.Digest,
 					SourceRepository: pd.repoInfo.Name(),
+					MediaType:        metadataMediaType(pd.mediaType()),
 				}); err != nil {
 					return distribution.Descriptor{}, false, xfer.DoNotRetry{Err: err}
 				}
 			}
-			desc.MediaType = schema2.MediaTypeLayer
+			desc.MediaType = pd.mediaType()
 			exists = true
 			break attempts
 		case distribution.ErrBlobUnknown:
@@ -641,6 +702,18 @@ func getRepositoryMountCandidates(
 	return candidates
 }
 
+// filterV2MetadataByCompression returns the v2 metadata items whose blob is
+// compressed with the same algorithm as a blob of the given media type.
+func filterV2MetadataByCompression(v2Metadata []metadata.V2Metadata, mediaType string) []metadata.V2Metadata {
+	filtered := []metadata.V2Metadata{}
+	for _, meta := range v2Metadata {
+		if meta.MediaType == metadataMediaType(mediaType) {
+			filtered = append(filtered, meta)
+		}
+	}
+	return filtered
+}
+
 // byLikeness is a sorting container for v2 metadata candidates for cross repository mount. The
 // candidate "a" is preferred over "b":
 //
@@ -149,6 +149,19 @@ func TestGetRepositoryMountCandidates(t *testing.T) {
 	}
 }
 
+func TestFilterV2MetadataByCompression(t *testing.T) {
+	gzipMeta := metadata.V2Metadata{Digest: digest.Digest("1"), SourceRepository: "docker.io/library/busybox"}
+	zstdMeta := metadata.V2Metadata{Digest: digest.Digest("2"), SourceRepository: "docker.io/library/busybox", MediaType: mediaTypeImageLayerZstd}
+	all := []metadata.V2Metadata{gzipMeta, zstdMeta}
+
+	if filtered := filterV2MetadataByCompression(all, schema2.MediaTypeLayer); !reflect.DeepEqual(filtered, []metadata.V2Metadata{gzipMeta}) {
+		t.Errorf("unexpected metadata for gzip layers: %v", filtered)
+	}
+	if filtered := filterV2MetadataByCompression(all, mediaTypeImageLayerZstd); !reflect.DeepEqual(filtered, []metadata.V2Metadata{zstdMeta}) {
+		t.Errorf("unexpected metadata for zstd layers: %v", filtered)
+	}
+}
+
 func TestLayerAlreadyExists(t *testing.T) {
 	for _, tc := range []struct {
 		name                   string
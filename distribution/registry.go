@@ -39,6 +39,20 @@ var PluginTypes = []string{
 	schema2.MediaTypePluginConfig,
 }
 
+// mediaTypeImageLayerZstd is the media type used for zstd compressed layers
+// referenced by OCI manifests.
+const mediaTypeImageLayerZstd = "application/vnd.oci.image.layer.v1.tar+zstd"
+
+// metadataMediaType returns the media type recorded in v2 metadata for a
+// blob of the given media type. Gzip compressed blobs are recorded without
+// a media type, as they were before the media type was tracked.
+func metadataMediaType(mediaType string) string {
+	if mediaType == mediaTypeImageLayerZstd {
+		return mediaType
+	}
+	return ""
+}
+
 var mediaTypeClasses map[string]string
 
 func init() {
@@ -134,6 +134,9 @@ if \
 	add_buildtag libdm dlsym_deferred_remove
 fi
 
+# enable RUN --mount in the classic builder
+DOCKER_BUILDTAGS+=' dfrunmount dfsecrets'
+
 # Use these flags when compiling the tests and final binary
 
 IAMSTATIC='true'
@@ -0,0 +1,205 @@
+package image // import "github.com/docker/docker/image"
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// labelGCRoot prevents containerd from garbage collecting the image
+	// configs stored by the daemon.
+	labelGCRoot = "containerd.io/gc.root"
+	// labelImageConfig marks the blobs in the content store which are
+	// image configs stored by the daemon.
+	labelImageConfig = "moby.image.config"
+	// labelMetadataPrefix is the prefix of the labels referencing the blobs
+	// holding the metadata of an image. Using a garbage collection
+	// reference label lets containerd remove them once unreferenced.
+	labelMetadataPrefix = "containerd.io/gc.ref.content.moby."
+)
+
+// contentStore implements StoreBackend using a containerd content store.
+type contentStore struct {
+	sync.RWMutex
+	cs  content.Store
+	ctx context.Context
+}
+
+// NewContentStoreBackend returns a new backend for image.Store, which stores
+// image configs as blobs in a containerd content store, in the provided
+// namespace. Metadata is stored as separate blobs referenced by labels on the
+// image config, as label values are limited in size.
+func NewContentStoreBackend(cs content.Store, namespace string) StoreBackend {
+	return &contentStore{
+		cs:  cs,
+		ctx: namespaces.WithNamespace(context.Background(), namespace),
+	}
+}
+
+// Walk calls the supplied callback for each image ID in the storage backend.
+func (s *contentStore) Walk(f DigestWalkFunc) error {
+	var ids []digest.Digest
+	s.RLock()
+	err := s.cs.Walk(s.ctx, func(info content.Info) error {
+		if _, ok := info.Labels[labelImageConfig]; ok {
+			ids = append(ids, info.Digest)
+		}
+		return nil
+	}, fmt.Sprintf("labels.%q", labelImageConfig))
+	s.RUnlock()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := id.Validate(); err != nil {
+			logrus.Debugf("skipping invalid digest %s: %s", id, err)
+			continue
+		}
+		if err := f(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the content stored under a given digest.
+func (s *contentStore) Get(dgst digest.Digest) ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return s.get(dgst)
+}
+
+func (s *contentStore) get(dgst digest.Digest) ([]byte, error) {
+	data, err := content.ReadBlob(s.ctx, s.cs, ocispec.Descriptor{Digest: dgst})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get digest %s", dgst)
+	}
+
+	if digest.FromBytes(data) != dgst {
+		return nil, fmt.Errorf("failed to verify: %v", dgst)
+	}
+
+	return data, nil
+}
+
+// Set stores content by checksum, and labels it as an image config.
+func (s *contentStore) Set(data []byte) (digest.Digest, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(data) == 0 {
+		return "", fmt.Errorf("invalid empty data")
+	}
+
+	dgst := digest.FromBytes(data)
+	labels := map[string]string{
+		labelGCRoot:      time.Now().UTC().Format(time.RFC3339),
+		labelImageConfig: dgst.String(),
+	}
+	if err := s.writeBlob(dgst, data, labels); err != nil {
+		return "", errors.Wrap(err, "failed to write digest data")
+	}
+	// The blob may already exist, for example when containerd pulled
+	// the same image, in which case the labels must be added.
+	if _, err := s.cs.Update(s.ctx, content.Info{Digest: dgst, Labels: labels}, "labels."+labelGCRoot, "labels."+labelImageConfig); err != nil {
+		return "", errors.Wrap(err, "failed to write digest data")
+	}
+
+	return dgst, nil
+}
+
+func (s *contentStore) writeBlob(dgst digest.Digest, data []byte, labels map[string]string) error {
+	desc := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageConfig,
+		Digest:    dgst,
+		Size:      int64(len(data)),
+	}
+	var opts []content.Opt
+	if labels != nil {
+		opts = append(opts, content.WithLabels(labels))
+	}
+	err := content.WriteBlob(s.ctx, s.cs, "moby-image-"+dgst.String(), bytes.NewReader(data), desc, opts...)
+	if err != nil && !errdefs.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// Delete removes the content associated with the digest. The blobs holding
+// its metadata are garbage collected by containerd.
+func (s *contentStore) Delete(dgst digest.Digest) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.cs.Delete(s.ctx, dgst)
+}
+
+// SetMetadata sets metadata for a given ID. It fails if there's no base blob.
+func (s *contentStore) SetMetadata(dgst digest.Digest, key string, data []byte) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, err := s.cs.Info(s.ctx, dgst); err != nil {
+		return err
+	}
+
+	mdgst := digest.FromBytes(data)
+	if err := s.writeBlob(mdgst, data, nil); err != nil {
+		return errors.Wrap(err, "failed to write metadata")
+	}
+	label := labelMetadataPrefix + key
+	_, err := s.cs.Update(s.ctx, content.Info{
+		Digest: dgst,
+		Labels: map[string]string{label: mdgst.String()},
+	}, "labels."+label)
+	return err
+}
+
+// GetMetadata returns metadata for a given digest.
+func (s *contentStore) GetMetadata(dgst digest.Digest, key string) ([]byte, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	info, err := s.cs.Info(s.ctx, dgst)
+	if err != nil {
+		return nil, err
+	}
+	ref, ok := info.Labels[labelMetadataPrefix+key]
+	if !ok {
+		return nil, errors.Wrapf(os.ErrNotExist, "failed to read metadata %s", key)
+	}
+	mdgst, err := digest.Parse(ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read metadata")
+	}
+	data, err := content.ReadBlob(s.ctx, s.cs, ocispec.Descriptor{Digest: mdgst})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read metadata")
+	}
+	return data, nil
+}
+
+// DeleteMetadata removes the metadata associated with a digest.
+func (s *contentStore) DeleteMetadata(dgst digest.Digest, key string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	label := labelMetadataPrefix + key
+	_, err := s.cs.Update(s.ctx, content.Info{Digest: dgst}, "labels."+label)
+	if errdefs.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
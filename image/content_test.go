@@ -0,0 +1,156 @@
+package image // import "github.com/docker/docker/image"
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/content/local"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+)
+
+type memoryLabelStore struct {
+	mu     sync.Mutex
+	labels map[digest.Digest]map[string]string
+}
+
+func (s *memoryLabelStore) Get(d digest.Digest) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.labels[d], nil
+}
+
+func (s *memoryLabelStore) Set(d digest.Digest, labels map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.labels[d] = labels
+	return nil
+}
+
+func (s *memoryLabelStore) Update(d digest.Digest, update map[string]string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	labels := map[string]string{}
+	for k, v := range s.labels[d] {
+		labels[k] = v
+	}
+	for k, v := range update {
+		if v == "" {
+			delete(labels, k)
+		} else {
+			labels[k] = v
+		}
+	}
+	s.labels[d] = labels
+	return labels, nil
+}
+
+func defaultContentStoreBackend(t *testing.T) (StoreBackend, content.Store, func()) {
+	tmpdir, err := ioutil.TempDir("", "images-content-store")
+	assert.NilError(t, err)
+
+	cs, err := local.NewLabeledStore(tmpdir, &memoryLabelStore{labels: map[digest.Digest]map[string]string{}})
+	assert.NilError(t, err)
+
+	return NewContentStoreBackend(cs, "test"), cs, func() { os.RemoveAll(tmpdir) }
+}
+
+func TestContentStoreGetSet(t *testing.T) {
+	store, cs, cleanup := defaultContentStoreBackend(t)
+	defer cleanup()
+
+	id, err := store.Set([]byte("foobar"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(digest.FromBytes([]byte("foobar")), id))
+
+	data, err := store.Get(id)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("foobar", string(data)))
+
+	info, err := cs.Info(context.Background(), id)
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(info.Labels, labelGCRoot))
+
+	_, err = store.Set(nil)
+	assert.Check(t, is.ErrorContains(err, "invalid empty data"))
+}
+
+func TestContentStoreSetExisting(t *testing.T) {
+	store, cs, cleanup := defaultContentStoreBackend(t)
+	defer cleanup()
+
+	// Content written by containerd is only listed once set as an image.
+	data := []byte("foobar")
+	dgst := digest.FromBytes(data)
+	err := content.WriteBlob(context.Background(), cs, "test", bytes.NewReader(data), ocispec.Descriptor{Digest: dgst, Size: int64(len(data))})
+	assert.NilError(t, err)
+
+	var ids []digest.Digest
+	walk := func(id digest.Digest) error {
+		ids = append(ids, id)
+		return nil
+	}
+	assert.NilError(t, store.Walk(walk))
+	assert.Check(t, is.Len(ids, 0))
+
+	id, err := store.Set(data)
+	assert.NilError(t, err)
+	assert.NilError(t, store.Walk(walk))
+	assert.Check(t, is.DeepEqual([]digest.Digest{id}, ids))
+}
+
+func TestContentStoreMetadata(t *testing.T) {
+	store, _, cleanup := defaultContentStoreBackend(t)
+	defer cleanup()
+
+	err := store.SetMetadata(digest.FromBytes([]byte("foobar")), "tkey", []byte("tval"))
+	assert.Check(t, is.ErrorContains(err, ""))
+
+	id, err := store.Set([]byte("foobar"))
+	assert.NilError(t, err)
+
+	_, err = store.GetMetadata(id, "tkey")
+	assert.Check(t, os.IsNotExist(errors.Cause(err)))
+
+	// Values are not limited to the size of a label
+	large := bytes.Repeat([]byte("a"), 8192)
+	assert.NilError(t, store.SetMetadata(id, "tkey", large))
+	actual, err := store.GetMetadata(id, "tkey")
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(large, actual))
+
+	assert.NilError(t, store.SetMetadata(id, "tkey", []byte("tval")))
+	actual, err = store.GetMetadata(id, "tkey")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("tval", string(actual)))
+
+	assert.NilError(t, store.DeleteMetadata(id, "tkey"))
+	_, err = store.GetMetadata(id, "tkey")
+	assert.Check(t, is.ErrorContains(err, "failed to read metadata"))
+}
+
+func TestContentStoreDelete(t *testing.T) {
+	store, _, cleanup := defaultContentStoreBackend(t)
+	defer cleanup()
+
+	id, err := store.Set([]byte("foo"))
+	assert.NilError(t, err)
+	id2, err := store.Set([]byte("bar"))
+	assert.NilError(t, err)
+
+	assert.NilError(t, store.Delete(id))
+
+	_, err = store.Get(id)
+	assert.Check(t, is.ErrorContains(err, "failed to get digest"))
+
+	_, err = store.Get(id2)
+	assert.NilError(t, err)
+}
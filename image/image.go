@@ -141,6 +141,9 @@ type ChildConfig struct {
 	DiffID          layer.DiffID
 	ContainerConfig *container.Config
 	Config          *container.Config
+	// Created is the creation time of the image. The current time is used
+	// if it is zero.
+	Created time.Time
 }
 
 // NewChildImage creates a new Image as a child of this image.
@@ -161,6 +164,9 @@ func NewChildImage(img *Image, child ChildConfig, platform string) *Image {
 		child.Comment,
 		strings.Join(child.ContainerConfig.Cmd, " "),
 		isEmptyLayer)
+	if !child.Created.IsZero() {
+		imgHistory.Created = child.Created
+	}
 
 	return &Image{
 		V1Image: V1Image{
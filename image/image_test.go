@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/layer"
@@ -123,3 +124,18 @@ func TestNewChildImageFromImageWithRootFS(t *testing.T) {
 	assert.Check(t, !cmp.Equal(parent.RootFS.DiffIDs, newImage.RootFS.DiffIDs),
 		"RootFS should be copied not mutated")
 }
+
+func TestNewChildImageCreated(t *testing.T) {
+	created := time.Unix(1000000000, 0).UTC()
+	childConfig := ChildConfig{
+		DiffID:          layer.DiffID("abcdef"),
+		ContainerConfig: &container.Config{},
+		Config:          &container.Config{},
+		Created:         created,
+	}
+
+	newImage := NewChildImage(&Image{}, childConfig, "platform")
+	assert.Check(t, is.Equal(created, newImage.Created))
+	assert.Check(t, is.Len(newImage.History, 1))
+	assert.Check(t, is.Equal(created, newImage.History[0].Created))
+}
@@ -3,6 +3,7 @@ package image // import "github.com/docker/docker/image"
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -24,7 +25,10 @@ type Store interface {
 	GetParent(id ID) (ID, error)
 	SetLastUpdated(id ID) error
 	GetLastUpdated(id ID) (time.Time, error)
+	SetProvenance(id ID, provenance []byte) error
+	GetProvenance(id ID) ([]byte, error)
 	Children(id ID) []ID
+	Quarantined(id ID) bool
 	Map() map[ID]*Image
 	Heads() map[ID]*Image
 	Len() int
@@ -150,12 +154,22 @@ func (is *store) Create(config []byte) (ID, error) {
 	is.Lock()
 	defer is.Unlock()
 
-	if _, exists := is.images[imageID]; exists {
+	layerID := img.RootFS.ChainID()
+
+	if meta, exists := is.images[imageID]; exists {
+		if meta.layer == nil || !layer.IsQuarantined(meta.layer) {
+			return imageID, nil
+		}
+		// The layers of the image were registered again, replace the
+		// quarantined ones.
+		l, err := is.lss[img.OperatingSystem()].Get(layerID)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get layer %s", layerID)
+		}
+		meta.layer = l
 		return imageID, nil
 	}
 
-	layerID := img.RootFS.ChainID()
-
 	var l layer.Layer
 	if layerID != "" {
 		if !system.IsOSSupported(img.OperatingSystem()) {
@@ -294,6 +308,34 @@ func (is *store) GetLastUpdated(id ID) (time.Time, error) {
 	return time.Parse(time.RFC3339Nano, string(bytes))
 }
 
+// SetProvenance stores the build provenance document of the image ID
+func (is *store) SetProvenance(id ID, provenance []byte) error {
+	return is.fs.SetMetadata(id.Digest(), "provenance", provenance)
+}
+
+// GetProvenance returns the build provenance document of the image ID, or
+// nil if the image has none
+func (is *store) GetProvenance(id ID) ([]byte, error) {
+	bytes, err := is.fs.GetMetadata(id.Digest(), "provenance")
+	if err != nil {
+		if os.IsNotExist(errors.Cause(err)) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return bytes, nil
+}
+
+// Quarantined returns true if the layers of the image were moved to
+// quarantine because they are corrupt, and the image must be pulled or loaded
+// again.
+func (is *store) Quarantined(id ID) bool {
+	is.RLock()
+	defer is.RUnlock()
+	meta, ok := is.images[id]
+	return ok && meta.layer != nil && layer.IsQuarantined(meta.layer)
+}
+
 func (is *store) Children(id ID) []ID {
 	is.RLock()
 	defer is.RUnlock()
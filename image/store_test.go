@@ -172,6 +172,24 @@ func TestGetAndSetLastUpdated(t *testing.T) {
 	assert.Check(t, cmp.Equal(updated.IsZero(), false))
 }
 
+func TestGetAndSetProvenance(t *testing.T) {
+	store, cleanup := defaultImageStore(t)
+	defer cleanup()
+
+	id, err := store.Create([]byte(`{"comment": "abc1", "rootfs": {"type": "layers"}}`))
+	assert.NilError(t, err)
+
+	provenance, err := store.GetProvenance(id)
+	assert.NilError(t, err)
+	assert.Check(t, cmp.Nil(provenance))
+
+	assert.Check(t, store.SetProvenance(id, []byte(`{"Builder":"classic"}`)))
+
+	provenance, err = store.GetProvenance(id)
+	assert.NilError(t, err)
+	assert.Check(t, cmp.Equal(string(provenance), `{"Builder":"classic"}`))
+}
+
 func TestStoreLen(t *testing.T) {
 	store, cleanup := defaultImageStore(t)
 	defer cleanup()
@@ -126,6 +126,19 @@ func (l *tarexporter) Load(inTar io.ReadCloser, outStream io.Writer, quiet bool)
 		if err != nil {
 			return err
 		}
+		if m.Provenance != "" {
+			provenancePath, err := safePath(tmpDir, m.Provenance)
+			if err != nil {
+				return err
+			}
+			provenance, err := ioutil.ReadFile(provenancePath)
+			if err != nil {
+				return err
+			}
+			if err := l.is.SetProvenance(imgID, provenance); err != nil {
+				return err
+			}
+		}
 		imageIDsStr += fmt.Sprintf("Loaded image ID: %s\n", imgID)
 
 		imageRefCount = 0
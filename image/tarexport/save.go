@@ -206,6 +206,10 @@ func (s *saveSession) save(outStream io.Writer) error {
 		if err != nil {
 			return err
 		}
+		provenance, err := s.saveProvenance(id)
+		if err != nil {
+			return err
+		}
 
 		var repoTags []string
 		var layers []string
@@ -232,6 +236,7 @@ func (s *saveSession) save(outStream io.Writer) error {
 			RepoTags:     repoTags,
 			Layers:       layers,
 			LayerSources: foreignSrcs,
+			Provenance:   provenance,
 		})
 
 		parentID, _ := s.is.GetParent(id)
@@ -348,6 +353,25 @@ func (s *saveSession) saveImage(id image.ID) (map[layer.DiffID]distribution.Desc
 	return foreignSrcs, nil
 }
 
+// saveProvenance writes the provenance of the image, if it has any, and
+// returns the name of the file it was written to.
+func (s *saveSession) saveProvenance(id image.ID) (string, error) {
+	provenance, err := s.is.GetProvenance(id)
+	if err != nil || provenance == nil {
+		return "", err
+	}
+	name := id.Digest().Hex() + ".provenance.json"
+	provenanceFile := filepath.Join(s.outDir, name)
+	if err := ioutil.WriteFile(provenanceFile, provenance, 0644); err != nil {
+		return "", err
+	}
+	created := s.images[id].image.Created
+	if err := system.Chtimes(provenanceFile, created, created); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
 func (s *saveSession) saveLayer(id layer.ChainID, legacyImg image.V1Image, createdTime time.Time) (distribution.Descriptor, error) {
 	if _, exists := s.savedLayers[legacyImg.ID]; exists {
 		return distribution.Descriptor{}, nil
@@ -0,0 +1,82 @@
+package image // import "github.com/docker/docker/integration/image"
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/integration/internal/container"
+	"github.com/docker/docker/internal/test/request"
+	"github.com/docker/docker/pkg/archive"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/poll"
+	"gotest.tools/skip"
+)
+
+func TestImageDiff(t *testing.T) {
+	skip.If(t, versions.LessThan(testEnv.DaemonAPIVersion(), "1.40"), "image diff was added in API v1.40")
+	skip.If(t, testEnv.DaemonInfo.OSType == "windows", "FIXME")
+	defer setupTest(t)()
+	client := request.NewAPIClient(t)
+	ctx := context.Background()
+
+	cID := container.Run(t, ctx, client, container.WithCmd("sh", "-c", "echo -n hello > /added && rm /etc/motd"))
+	poll.WaitOn(t, container.IsInState(ctx, client, cID, "exited"), poll.WithDelay(100*time.Millisecond))
+	resp, err := client.ContainerCommit(ctx, cID, types.ContainerCommitOptions{})
+	assert.NilError(t, err)
+
+	diff, err := client.ImageDiff(ctx, "busybox", resp.ID)
+	assert.NilError(t, err)
+
+	expected := map[string]image.DiffResponseItem{
+		"/added": {
+			Kind:   uint8(archive.ChangeAdd),
+			Path:   "/added",
+			Size:   5,
+			Digest: "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+		"/etc/motd": {
+			Kind: uint8(archive.ChangeDelete),
+			Path: "/etc/motd",
+		},
+	}
+	for _, c := range diff {
+		if e, ok := expected[c.Path]; ok {
+			if c.Kind == uint8(archive.ChangeDelete) {
+				c.Size, c.Digest = 0, ""
+			}
+			assert.Check(t, is.DeepEqual(e, c))
+			delete(expected, c.Path)
+		}
+	}
+	assert.Check(t, is.Len(expected, 0))
+}
+
+func TestImageFiles(t *testing.T) {
+	skip.If(t, versions.LessThan(testEnv.DaemonAPIVersion(), "1.40"), "image files was added in API v1.40")
+	skip.If(t, testEnv.DaemonInfo.OSType == "windows", "FIXME")
+	defer setupTest(t)()
+	client := request.NewAPIClient(t)
+	ctx := context.Background()
+
+	files, err := client.ImageFiles(ctx, "busybox", "/bin")
+	assert.NilError(t, err)
+	var found bool
+	for _, f := range files {
+		if f.Name == "sh" {
+			found = true
+		}
+	}
+	assert.Check(t, found, "sh not found in /bin of busybox")
+
+	files, err = client.ImageFiles(ctx, "busybox", "/bin/sh")
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(files, 1))
+
+	_, err = client.ImageFiles(ctx, "busybox", "/does-not-exist")
+	assert.Check(t, is.ErrorContains(err, "does not exist"))
+}
@@ -0,0 +1,57 @@
+package image // import "github.com/docker/docker/integration/image"
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/integration/internal/container"
+	"github.com/docker/docker/internal/test/request"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/poll"
+	"gotest.tools/skip"
+)
+
+func TestRebase(t *testing.T) {
+	skip.If(t, versions.LessThan(testEnv.DaemonAPIVersion(), "1.40"), "rebase was added in API v1.40")
+	skip.If(t, testEnv.DaemonInfo.OSType == "windows", "FIXME")
+	defer setupTest(t)()
+	client := request.NewAPIClient(t)
+	ctx := context.Background()
+
+	commit := func(file string) types.ImageInspect {
+		cID := container.Run(t, ctx, client, container.WithCmd("touch", file))
+		poll.WaitOn(t, container.IsInState(ctx, client, cID, "exited"), poll.WithDelay(100*time.Millisecond))
+		resp, err := client.ContainerCommit(ctx, cID, types.ContainerCommitOptions{})
+		assert.NilError(t, err)
+		img, _, err := client.ImageInspectWithRaw(ctx, resp.ID)
+		assert.NilError(t, err)
+		return img
+	}
+
+	base, _, err := client.ImageInspectWithRaw(ctx, "busybox")
+	assert.NilError(t, err)
+	newBase := commit("/new-base")
+	app := commit("/app")
+
+	resp, err := client.ImageRebase(ctx, app.ID, types.ImageRebaseOptions{
+		OldBase: "busybox",
+		NewBase: newBase.ID,
+	})
+	assert.NilError(t, err)
+
+	rebased, _, err := client.ImageInspectWithRaw(ctx, resp.ID)
+	assert.NilError(t, err)
+	expected := append(append([]string{}, newBase.RootFS.Layers...), app.RootFS.Layers[len(base.RootFS.Layers):]...)
+	assert.Check(t, is.DeepEqual(expected, rebased.RootFS.Layers))
+	assert.Check(t, is.DeepEqual(app.Config.Cmd, rebased.Config.Cmd))
+
+	_, err = client.ImageRebase(ctx, newBase.ID, types.ImageRebaseOptions{
+		OldBase: app.ID,
+		NewBase: "busybox",
+	})
+	assert.Check(t, is.ErrorContains(err, "is not based on"))
+}
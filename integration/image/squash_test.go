@@ -0,0 +1,62 @@
+package image // import "github.com/docker/docker/integration/image"
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/integration/internal/container"
+	"github.com/docker/docker/internal/test/request"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/poll"
+	"gotest.tools/skip"
+)
+
+func TestSquash(t *testing.T) {
+	skip.If(t, versions.LessThan(testEnv.DaemonAPIVersion(), "1.40"), "squash was added in API v1.40")
+	skip.If(t, testEnv.DaemonInfo.OSType == "windows", "FIXME")
+	defer setupTest(t)()
+	client := request.NewAPIClient(t)
+	ctx := context.Background()
+
+	commit := func(image string, cmd ...string) types.ImageInspect {
+		cID := container.Run(t, ctx, client, container.WithImage(image), container.WithCmd(cmd...))
+		poll.WaitOn(t, container.IsInState(ctx, client, cID, "exited"), poll.WithDelay(100*time.Millisecond))
+		resp, err := client.ContainerCommit(ctx, cID, types.ContainerCommitOptions{
+			Changes: []string{"ENV SQUASH=1"},
+		})
+		assert.NilError(t, err)
+		img, _, err := client.ImageInspectWithRaw(ctx, resp.ID)
+		assert.NilError(t, err)
+		return img
+	}
+
+	base, _, err := client.ImageInspectWithRaw(ctx, "busybox")
+	assert.NilError(t, err)
+	img := commit("busybox", "touch", "/removed")
+	img = commit(img.ID, "rm", "/removed")
+	img = commit(img.ID, "touch", "/kept")
+
+	resp, err := client.ImageSquash(ctx, img.ID, types.ImageSquashOptions{From: len(base.RootFS.Layers)})
+	assert.NilError(t, err)
+	squashed, _, err := client.ImageInspectWithRaw(ctx, resp.ID)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(squashed.RootFS.Layers, len(base.RootFS.Layers)+1))
+	assert.Check(t, is.DeepEqual(base.RootFS.Layers, squashed.RootFS.Layers[:len(base.RootFS.Layers)]))
+	assert.Check(t, is.DeepEqual(img.Config.Env, squashed.Config.Env))
+
+	cID := container.Run(t, ctx, client, container.WithImage(squashed.ID), container.WithCmd("sh", "-c", "test -e /kept && ! test -e /removed"))
+	poll.WaitOn(t, container.IsSuccessful(ctx, client, cID), poll.WithDelay(100*time.Millisecond))
+
+	resp, err = client.ImageSquash(ctx, img.ID, types.ImageSquashOptions{})
+	assert.NilError(t, err)
+	squashed, _, err = client.ImageInspectWithRaw(ctx, resp.ID)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(squashed.RootFS.Layers, 1))
+
+	_, err = client.ImageSquash(ctx, img.ID, types.ImageSquashOptions{From: len(img.RootFS.Layers)})
+	assert.Check(t, is.ErrorContains(err, "out of range"))
+}
@@ -0,0 +1,316 @@
+package layer // import "github.com/docker/docker/layer"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
+)
+
+// Kinds of problems found by Check.
+const (
+	// ProblemCorruptLayer is a layer whose contents do not match its
+	// DiffID, or whose metadata cannot be loaded.
+	ProblemCorruptLayer = "corrupt-layer"
+	// ProblemDanglingMount is mount metadata that cannot be loaded, or
+	// whose graphdriver directories do not exist.
+	ProblemDanglingMount = "dangling-mount"
+	// ProblemOrphanedDriverDir is a graphdriver directory that no layer or
+	// mount uses.
+	ProblemOrphanedDriverDir = "orphaned-driver-dir"
+)
+
+const quarantineDirName = "quarantine"
+
+// driverIDRegexp matches the graphdriver IDs the layer store creates, so that
+// directories created by other users of the graphdriver, such as BuildKit,
+// are not reported as orphaned.
+var driverIDRegexp = regexp.MustCompile(`^[a-f0-9]{64}(-init)?$`)
+
+// Problem is a problem found by Check.
+type Problem struct {
+	Kind string
+	// ID is the chain ID of the layer, the name of the mount, or the ID of
+	// the graphdriver directory with the problem.
+	ID      string
+	Message string
+}
+
+// CheckResult is the result of Check.
+type CheckResult struct {
+	// Layers is the number of layers whose contents were checked.
+	Layers   int
+	Problems []Problem
+	// Quarantined are the chain IDs of the layers moved to quarantine.
+	Quarantined []ChainID
+}
+
+// Checker represents a layer store capable of checking its integrity.
+type Checker interface {
+	// Check reassembles the tar stream of every layer and compares its
+	// digest with the DiffID of the layer, and looks for mount metadata
+	// and graphdriver directories that are not used. If quarantine is
+	// true, corrupt layers, and the layers on top of them, are moved to
+	// quarantine: they are removed from the store, with their metadata
+	// and graphdriver directory kept aside, so that they can be
+	// registered again, for example by pulling the images using them.
+	Check(ctx context.Context, quarantine bool) (*CheckResult, error)
+}
+
+// IsQuarantined returns true if l was moved to quarantine by Check. The
+// layer must be registered again to be used.
+func IsQuarantined(l Layer) bool {
+	ref, ok := l.(*referencedCacheLayer)
+	return ok && ref.quarantined
+}
+
+func (ls *layerStore) Check(ctx context.Context, quarantine bool) (*CheckResult, error) {
+	res := &CheckResult{}
+
+	ids, mounts, err := ls.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	// Layers are checked without taking a reference, as releasing the last
+	// reference to a layer deletes it.
+	var layers []*roLayer
+	ls.layerL.Lock()
+	for _, id := range ids {
+		l, ok := ls.layerMap[id]
+		if !ok {
+			res.Problems = append(res.Problems, Problem{Kind: ProblemCorruptLayer, ID: id.String(), Message: "layer metadata could not be loaded"})
+			continue
+		}
+		layers = append(layers, l)
+	}
+	ls.layerL.Unlock()
+
+	corrupt := make(map[ChainID]bool)
+	for _, l := range layers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := ls.checkLayer(l); err != nil {
+			ls.layerL.Lock()
+			current := ls.layerMap[l.chainID]
+			ls.layerL.Unlock()
+			if current != l {
+				// Layer was deleted while checking it
+				continue
+			}
+			corrupt[l.chainID] = true
+			res.Problems = append(res.Problems, Problem{Kind: ProblemCorruptLayer, ID: l.chainID.String(), Message: err.Error()})
+		}
+		res.Layers++
+	}
+
+	res.Problems = append(res.Problems, ls.checkMounts(mounts)...)
+
+	orphans, err := ls.checkDriverDirs(ids, mounts)
+	if err != nil {
+		return nil, err
+	}
+	res.Problems = append(res.Problems, orphans...)
+
+	if quarantine && len(corrupt) > 0 {
+		res.Quarantined, err = ls.quarantine(corrupt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// checkLayer compares the digest of the tar stream of rl with its DiffID.
+func (ls *layerStore) checkLayer(rl *roLayer) error {
+	ts, err := ls.getTarStream(rl)
+	if err != nil {
+		return fmt.Errorf("failed to read layer: %v", err)
+	}
+	defer ts.Close()
+
+	digester := digest.Canonical.Digester()
+	if _, err := io.Copy(digester.Hash(), ts); err != nil {
+		return fmt.Errorf("failed to read layer: %v", err)
+	}
+	if dgst := DiffID(digester.Digest()); dgst != rl.diffID {
+		return fmt.Errorf("layer contents have digest %s, expected DiffID %s", dgst, rl.diffID)
+	}
+	return nil
+}
+
+func (ls *layerStore) checkMounts(mounts []string) []Problem {
+	var problems []Problem
+	ls.mountL.Lock()
+	defer ls.mountL.Unlock()
+	for _, name := range mounts {
+		m, ok := ls.mounts[name]
+		if !ok {
+			problems = append(problems, Problem{Kind: ProblemDanglingMount, ID: name, Message: "mount metadata could not be loaded"})
+			continue
+		}
+		if !ls.driver.Exists(m.mountID) {
+			problems = append(problems, Problem{Kind: ProblemDanglingMount, ID: name, Message: fmt.Sprintf("graphdriver directory %s of mount does not exist", m.mountID)})
+		}
+		if m.initID != "" && !ls.driver.Exists(m.initID) {
+			problems = append(problems, Problem{Kind: ProblemDanglingMount, ID: name, Message: fmt.Sprintf("graphdriver init directory %s of mount does not exist", m.initID)})
+		}
+	}
+	return problems
+}
+
+// checkDriverDirs looks for graphdriver directories that no layer or mount
+// uses, if the graphdriver can list its directories.
+func (ls *layerStore) checkDriverDirs(ids []ChainID, mounts []string) ([]Problem, error) {
+	ld, ok := ls.driver.(graphdriver.ListDriver)
+	if !ok {
+		return nil, nil
+	}
+
+	used := make(map[string]bool)
+	for _, id := range ids {
+		if cacheID, err := ls.store.GetCacheID(id); err == nil {
+			used[cacheID] = true
+		}
+	}
+	for _, cacheID := range ls.store.quarantinedCacheIDs() {
+		used[cacheID] = true
+	}
+	for _, name := range mounts {
+		if mountID, err := ls.store.GetMountID(name); err == nil {
+			used[mountID] = true
+		}
+		if initID, err := ls.store.GetInitID(name); err == nil && initID != "" {
+			used[initID] = true
+		}
+	}
+	// Layers and mounts created while listing are not orphaned
+	ls.layerL.Lock()
+	for _, l := range ls.layerMap {
+		used[l.cacheID] = true
+	}
+	ls.layerL.Unlock()
+	ls.mountL.Lock()
+	for _, m := range ls.mounts {
+		used[m.mountID] = true
+		used[m.initID] = true
+	}
+	ls.mountL.Unlock()
+
+	dirs, err := ld.List()
+	if err != nil {
+		if err == graphdriver.ErrNotSupported {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var problems []Problem
+	for _, dir := range dirs {
+		if !used[dir] && driverIDRegexp.MatchString(dir) {
+			problems = append(problems, Problem{Kind: ProblemOrphanedDriverDir, ID: dir, Message: "graphdriver directory is not used by any layer or mount"})
+		}
+	}
+	return problems, nil
+}
+
+// quarantine moves the corrupt layers, and the layers on top of them, to
+// quarantine. Corrupt layers used by a mount are not moved, as the mount
+// keeps a reference to them.
+func (ls *layerStore) quarantine(corrupt map[ChainID]bool) ([]ChainID, error) {
+	ls.mountL.Lock()
+	defer ls.mountL.Unlock()
+	ls.layerL.Lock()
+	defer ls.layerL.Unlock()
+
+	for _, m := range ls.mounts {
+		for p := m.parent; p != nil; p = p.parent {
+			if corrupt[p.chainID] {
+				logrus.Warnf("Not moving layer %s to quarantine as it is used by container %s", p.chainID, m.name)
+				delete(corrupt, p.chainID)
+			}
+		}
+	}
+
+	var quarantined []ChainID
+	for chainID, l := range ls.layerMap {
+		for p := l; p != nil; p = p.parent {
+			if corrupt[p.chainID] {
+				quarantined = append(quarantined, chainID)
+				break
+			}
+		}
+	}
+	sort.Slice(quarantined, func(i, j int) bool { return quarantined[i] < quarantined[j] })
+
+	var layers []*roLayer
+	for _, chainID := range quarantined {
+		l := ls.layerMap[chainID]
+		if err := ls.store.quarantine(chainID); err != nil {
+			return nil, err
+		}
+		l.quarantined = true
+		delete(ls.layerMap, chainID)
+		layers = append(layers, l)
+		logrus.Warnf("Moved layer %s to quarantine", chainID)
+	}
+
+	// Release the reference the lowest quarantined layers hold on their
+	// parent, which stays in the store.
+	for _, l := range layers {
+		if l.parent != nil && !l.parent.quarantined {
+			if _, err := ls.releaseLayer(l.parent); err != nil {
+				logrus.Errorf("Error releasing parent of quarantined layer %s: %v", l.chainID, err)
+			}
+		}
+	}
+	return quarantined, nil
+}
+
+func (fms *fileMetadataStore) getQuarantineDirectory(layer ChainID) string {
+	dgst := digest.Digest(layer)
+	return filepath.Join(fms.root, quarantineDirName, string(dgst.Algorithm()), dgst.Hex())
+}
+
+// quarantine moves the metadata of layer out of the store, keeping it aside
+// with the graphdriver directory it refers to.
+func (fms *fileMetadataStore) quarantine(layer ChainID) error {
+	dir := fms.getQuarantineDirectory(layer)
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(fms.getLayerDirectory(layer), dir)
+}
+
+// quarantinedCacheIDs returns the graphdriver IDs of the layers in
+// quarantine.
+func (fms *fileMetadataStore) quarantinedCacheIDs() []string {
+	var cacheIDs []string
+	for _, algorithm := range supportedAlgorithms {
+		dirs, err := filepath.Glob(filepath.Join(fms.root, quarantineDirName, string(algorithm), "*", "cache-id"))
+		if err != nil {
+			continue
+		}
+		for _, name := range dirs {
+			content, err := ioutil.ReadFile(name)
+			if err != nil {
+				continue
+			}
+			cacheIDs = append(cacheIDs, strings.TrimSpace(string(content)))
+		}
+	}
+	return cacheIDs
+}
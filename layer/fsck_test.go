@@ -0,0 +1,134 @@
+package layer // import "github.com/docker/docker/layer"
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/docker/pkg/stringid"
+)
+
+func TestCheck(t *testing.T) {
+	// TODO Windows: Figure out why this is failing
+	if runtime.GOOS == "windows" {
+		t.Skip("Failing on Windows")
+	}
+	ls, _, cleanup := newTestStore(t)
+	defer cleanup()
+	driver := ls.(*layerStore).driver
+
+	tar1, err := tarFromFiles(newTestFile("/etc/profile", []byte("# Base configuration"), 0644))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tar2, err := tarFromFiles(newTestFile("/root/.bashrc", []byte("# Root configuration"), 0644))
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer1, err := ls.Register(bytes.NewReader(tar1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	layer2, err := ls.Register(bytes.NewReader(tar2), layer1.ChainID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the contents of layer1
+	fs, err := driver.Get(getCachedLayer(layer1).cacheID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(fs.Path(), "etc", "profile"), []byte("# Corrupt configuration"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Put(getCachedLayer(layer1).cacheID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Remove the graphdriver directory of a mount
+	mountName := stringid.GenerateRandomID()
+	mount, err := ls.CreateRWLayer(mountName, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.Remove(getMountLayer(mount).mountID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create graphdriver directories not used by the store, one of which
+	// is not named like the directories the store creates.
+	orphan := stringid.GenerateRandomID()
+	for _, id := range []string{orphan, "buildkit-snapshot"} {
+		if err := driver.Create(id, "", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := ls.(Checker).Check(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Layers != 2 {
+		t.Fatalf("Unexpected number of layers checked %d, expected 2", res.Layers)
+	}
+	expected := map[string]string{
+		ProblemCorruptLayer:      layer1.ChainID().String(),
+		ProblemDanglingMount:     mountName,
+		ProblemOrphanedDriverDir: orphan,
+	}
+	if len(res.Problems) != len(expected) {
+		t.Fatalf("Unexpected problems %#v", res.Problems)
+	}
+	for _, p := range res.Problems {
+		if expected[p.Kind] != p.ID {
+			t.Errorf("Unexpected problem %#v", p)
+		}
+	}
+	if len(res.Quarantined) != 0 {
+		t.Fatalf("Unexpected quarantined layers %v", res.Quarantined)
+	}
+	if _, err := ls.Get(layer1.ChainID()); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = ls.(Checker).Check(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Quarantined) != 2 {
+		t.Fatalf("Unexpected quarantined layers %v, expected %v and %v", res.Quarantined, layer1.ChainID(), layer2.ChainID())
+	}
+	if _, err := ls.Get(layer2.ChainID()); err != ErrLayerDoesNotExist {
+		t.Fatalf("Unexpected error getting quarantined layer: %v", err)
+	}
+	if !IsQuarantined(layer1) || !IsQuarantined(layer2) {
+		t.Fatal("Expected layers to be quarantined")
+	}
+
+	// Quarantined layers can be registered again
+	layer1b, err := ls.Register(bytes.NewReader(tar1), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layer1b.ChainID() != layer1.ChainID() {
+		t.Fatalf("Unexpected chain ID %s, expected %s", layer1b.ChainID(), layer1.ChainID())
+	}
+	if _, err := ls.Release(layer1); err != nil {
+		t.Fatalf("Unexpected error releasing quarantined layer: %v", err)
+	}
+	assertReferences(t, layer1b)
+
+	res, err = ls.(Checker).Check(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range res.Problems {
+		if p.Kind == ProblemCorruptLayer || p.ID == getCachedLayer(layer1).cacheID {
+			t.Errorf("Unexpected problem %#v", p)
+		}
+	}
+}
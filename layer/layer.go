@@ -136,9 +136,15 @@ type RWLayer interface {
 
 	// Size represents the size of the writable layer
 	// as calculated by the total size of the files
-	// changed in the mutable layer.
+	// changed in the mutable layer, or by its disk
+	// usage when the graphdriver reports it.
 	Size() (int64, error)
 
+	// Usage returns the disk usage of the writable
+	// layer, and false if the graphdriver cannot
+	// report it without walking its files.
+	Usage() (int64, bool, error)
+
 	// Changes returns the set of changes for the mutable layer
 	// from the base layer.
 	Changes() ([]archive.Change, error)
@@ -463,6 +463,9 @@ func (ls *layerStore) releaseLayer(l *roLayer) ([]Metadata, error) {
 func (ls *layerStore) Release(l Layer) ([]Metadata, error) {
 	ls.layerL.Lock()
 	defer ls.layerL.Unlock()
+	if ref, ok := l.(*referencedCacheLayer); ok && ref.quarantined {
+		return []Metadata{}, nil
+	}
 	layer, ok := ls.layerMap[l.ChainID()]
 	if !ok {
 		return []Metadata{}, nil
@@ -3,6 +3,7 @@ package layer // import "github.com/docker/docker/layer"
 import (
 	"io"
 
+	"github.com/docker/docker/daemon/graphdriver"
 	"github.com/docker/docker/pkg/archive"
 	"github.com/docker/docker/pkg/containerfs"
 )
@@ -47,9 +48,19 @@ func (ml *mountedLayer) Parent() Layer {
 }
 
 func (ml *mountedLayer) Size() (int64, error) {
+	if usage, ok, err := ml.Usage(); err == nil && ok {
+		return usage, nil
+	}
 	return ml.layerStore.driver.DiffSize(ml.mountID, ml.cacheParent())
 }
 
+func (ml *mountedLayer) Usage() (int64, bool, error) {
+	if ud, ok := ml.layerStore.driver.(graphdriver.UsageDriver); ok {
+		return ud.Usage(ml.mountID)
+	}
+	return 0, false, nil
+}
+
 func (ml *mountedLayer) Changes() ([]archive.Change, error) {
 	return ml.layerStore.driver.Changes(ml.mountID, ml.cacheParent())
 }
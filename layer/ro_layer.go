@@ -19,6 +19,9 @@ type roLayer struct {
 
 	referenceCount int
 	references     map[Layer]struct{}
+
+	// quarantined is set when the layer is moved out of the store by Check
+	quarantined bool
 }
 
 // TarStream for roLayer guarantees that the data that is produced is the exact
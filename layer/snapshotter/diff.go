@@ -0,0 +1,141 @@
+package snapshotter // import "github.com/docker/docker/layer/snapshotter"
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/sirupsen/logrus"
+)
+
+// diff returns a tar stream of the changes between two committed snapshots.
+// An empty lower snapshot results in the full content of the upper one.
+func (s *store) diff(upper, lower string) (io.ReadCloser, error) {
+	mounts, release, err := s.view(upper)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := s.diffMounts(mounts, lower)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return ioutils.NewReadCloserWrapper(rc, func() error {
+		err := rc.Close()
+		release()
+		return err
+	}), nil
+}
+
+// diffMounts returns a tar stream of the changes between the filesystem
+// described by upper and the lower committed snapshot. The stream must be
+// closed to release the mounts.
+func (s *store) diffMounts(upper []mount.Mount, lower string) (io.ReadCloser, error) {
+	root, changes, release, err := s.changes(upper, lower)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := archive.ExportChanges(root, changes, s.idMapping.UIDs(), s.idMapping.GIDs())
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return ioutils.NewReadCloserWrapper(rc, func() error {
+		err := rc.Close()
+		release()
+		return err
+	}), nil
+}
+
+// changes mounts upper and the lower committed snapshot, and returns the
+// changes between them along with the path upper is mounted at. The
+// returned function unmounts both.
+func (s *store) changes(upper []mount.Mount, lower string) (string, []archive.Change, func(), error) {
+	upperDir, unmountUpper, err := s.mountTemp(upper)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var (
+		lowerDir     string
+		unmountLower = func() {}
+	)
+	if lower == "" {
+		lowerDir, err = ioutil.TempDir(filepath.Join(s.root, "tmp"), "empty")
+		if err != nil {
+			unmountUpper()
+			return "", nil, nil, err
+		}
+		unmountLower = func() { os.RemoveAll(lowerDir) }
+	} else {
+		mounts, releaseView, err := s.view(lower)
+		if err != nil {
+			unmountUpper()
+			return "", nil, nil, err
+		}
+		dir, unmount, err := s.mountTemp(mounts)
+		if err != nil {
+			releaseView()
+			unmountUpper()
+			return "", nil, nil, err
+		}
+		lowerDir = dir
+		unmountLower = func() {
+			unmount()
+			releaseView()
+		}
+	}
+
+	release := func() {
+		unmountLower()
+		unmountUpper()
+	}
+	changes, err := archive.ChangesDirs(upperDir, lowerDir)
+	if err != nil {
+		release()
+		return "", nil, nil, err
+	}
+	return upperDir, changes, release, nil
+}
+
+// view creates a temporary read-only view of a committed snapshot. The
+// returned function removes the view.
+func (s *store) view(parent string) ([]mount.Mount, func(), error) {
+	key := tmpKeyPrefix + stringid.GenerateRandomID()
+	mounts, err := s.sn.View(s.ctx, key, parent, snapshots.WithLabels(map[string]string{
+		labelGCRoot: gcRoot(),
+	}))
+	if err != nil {
+		return nil, nil, err
+	}
+	return mounts, func() {
+		if err := s.sn.Remove(s.ctx, key); err != nil {
+			logrus.Warnf("Failed to remove snapshot view %s: %v", key, err)
+		}
+	}, nil
+}
+
+// mountTemp mounts mounts on a temporary directory under the store root.
+// The returned function unmounts it and removes the directory.
+func (s *store) mountTemp(mounts []mount.Mount) (string, func(), error) {
+	dir, err := ioutil.TempDir(filepath.Join(s.root, "tmp"), "mount")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := mount.All(mounts, dir); err != nil {
+		os.Remove(dir)
+		return "", nil, err
+	}
+	return dir, func() {
+		if err := mount.UnmountAll(dir, 0); err != nil {
+			logrus.Warnf("Failed to unmount %s: %v", dir, err)
+		}
+		os.Remove(dir)
+	}, nil
+}
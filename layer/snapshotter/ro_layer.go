@@ -0,0 +1,154 @@
+package snapshotter // import "github.com/docker/docker/layer/snapshotter"
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/containerd/containerd/content"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/ioutils"
+	"github.com/opencontainers/go-digest"
+)
+
+type roLayer struct {
+	chainID layer.ChainID
+	diffID  layer.DiffID
+	parent  *roLayer
+	size    int64
+	store   *store
+
+	referenceCount int
+	references     map[layer.Layer]struct{}
+}
+
+// TarStream returns the tar the layer was registered with, as stored in the
+// content store, and verifies it against the DiffID of the layer.
+func (rl *roLayer) TarStream() (io.ReadCloser, error) {
+	s := rl.store
+	ra, err := s.cs.ReaderAt(s.ctx, contentDescriptor(digest.Digest(rl.diffID)))
+	if err != nil {
+		return nil, err
+	}
+	rc := ioutils.NewReadCloserWrapper(content.NewReader(ra), ra.Close)
+	return newVerifiedReadCloser(rc, digest.Digest(rl.diffID)), nil
+}
+
+// TarStreamFrom does not make any guarantees to the correctness of the produced
+// data. As such it should not be used when the layer content must be verified
+// to be an exact match to the registered layer.
+func (rl *roLayer) TarStreamFrom(parent layer.ChainID) (io.ReadCloser, error) {
+	found := parent == ""
+	for pl := rl.parent; pl != nil && !found; pl = pl.parent {
+		found = pl.chainID == parent
+	}
+	if !found {
+		return nil, fmt.Errorf("layer ID '%s' is not a parent of the specified layer: cannot provide diff to non-parent", parent)
+	}
+	return rl.store.diff(string(rl.chainID), string(parent))
+}
+
+func (rl *roLayer) ChainID() layer.ChainID {
+	return rl.chainID
+}
+
+func (rl *roLayer) DiffID() layer.DiffID {
+	return rl.diffID
+}
+
+func (rl *roLayer) Parent() layer.Layer {
+	if rl.parent == nil {
+		return nil
+	}
+	return rl.parent
+}
+
+func (rl *roLayer) Size() (int64, error) {
+	return rl.sizeWithParents(), nil
+}
+
+func (rl *roLayer) sizeWithParents() int64 {
+	var size int64
+	for l := rl; l != nil; l = l.parent {
+		size += l.size
+	}
+	return size
+}
+
+func (rl *roLayer) DiffSize() (int64, error) {
+	return rl.size, nil
+}
+
+// Metadata returns the snapshotter and the name of the snapshot of the
+// layer.
+func (rl *roLayer) Metadata() (map[string]string, error) {
+	return map[string]string{
+		"Snapshotter": rl.store.name,
+		"Snapshot":    string(rl.chainID),
+	}, nil
+}
+
+type referencedLayer struct {
+	*roLayer
+}
+
+func (rl *roLayer) getReference() layer.Layer {
+	ref := &referencedLayer{
+		roLayer: rl,
+	}
+	rl.references[ref] = struct{}{}
+
+	return ref
+}
+
+func (rl *roLayer) hasReference(ref layer.Layer) bool {
+	_, ok := rl.references[ref]
+	return ok
+}
+
+func (rl *roLayer) hasReferences() bool {
+	return len(rl.references) > 0
+}
+
+func (rl *roLayer) deleteReference(ref layer.Layer) {
+	delete(rl.references, ref)
+}
+
+func (rl *roLayer) depth() int {
+	if rl.parent == nil {
+		return 1
+	}
+	return rl.parent.depth() + 1
+}
+
+func newVerifiedReadCloser(rc io.ReadCloser, dgst digest.Digest) io.ReadCloser {
+	return &verifiedReadCloser{
+		rc:       rc,
+		dgst:     dgst,
+		verifier: dgst.Verifier(),
+	}
+}
+
+type verifiedReadCloser struct {
+	rc       io.ReadCloser
+	dgst     digest.Digest
+	verifier digest.Verifier
+}
+
+func (vrc *verifiedReadCloser) Read(p []byte) (n int, err error) {
+	n, err = vrc.rc.Read(p)
+	if n > 0 {
+		if n, err := vrc.verifier.Write(p[:n]); err != nil {
+			return n, err
+		}
+	}
+	if err == io.EOF {
+		if !vrc.verifier.Verified() {
+			err = fmt.Errorf("could not verify layer data for: %s. This may be because internal files in the layer store were modified. Re-pulling or rebuilding this image may resolve the issue", vrc.dgst)
+		}
+	}
+	return
+}
+
+func (vrc *verifiedReadCloser) Close() error {
+	return vrc.rc.Close()
+}
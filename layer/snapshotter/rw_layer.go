@@ -0,0 +1,177 @@
+package snapshotter // import "github.com/docker/docker/layer/snapshotter"
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/containerd/containerd/mount"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/containerfs"
+)
+
+type rwLayer struct {
+	name    string
+	key     string
+	initKey string
+	parent  *roLayer
+	store   *store
+
+	// mu protects activeCount, the number of callers using the mount of
+	// the layer.
+	mu          sync.Mutex
+	activeCount int
+
+	references map[layer.RWLayer]*referencedRWLayer
+}
+
+// baseKey returns the committed snapshot the read-write layer is compared
+// against when exporting its changes.
+func (ml *rwLayer) baseKey() string {
+	if ml.initKey != "" {
+		return ml.initKey
+	}
+	if ml.parent != nil {
+		return string(ml.parent.chainID)
+	}
+	return ""
+}
+
+func (ml *rwLayer) path() string {
+	return filepath.Join(ml.store.root, "mounts", ml.name)
+}
+
+func (ml *rwLayer) TarStream() (io.ReadCloser, error) {
+	mounts, err := ml.store.sn.Mounts(ml.store.ctx, ml.key)
+	if err != nil {
+		return nil, err
+	}
+	return ml.store.diffMounts(mounts, ml.baseKey())
+}
+
+func (ml *rwLayer) Name() string {
+	return ml.name
+}
+
+func (ml *rwLayer) Parent() layer.Layer {
+	if ml.parent != nil {
+		return ml.parent
+	}
+
+	// Return a nil interface instead of an interface wrapping a nil
+	// pointer.
+	return nil
+}
+
+func (ml *rwLayer) Size() (int64, error) {
+	size, _, err := ml.Usage()
+	return size, err
+}
+
+// Usage returns the disk usage of the read-write snapshot, as reported by
+// the snapshotter.
+func (ml *rwLayer) Usage() (int64, bool, error) {
+	usage, err := ml.store.sn.Usage(ml.store.ctx, ml.key)
+	if err != nil {
+		return 0, false, err
+	}
+	return usage.Size, true, nil
+}
+
+func (ml *rwLayer) Changes() ([]archive.Change, error) {
+	mounts, err := ml.store.sn.Mounts(ml.store.ctx, ml.key)
+	if err != nil {
+		return nil, err
+	}
+	_, changes, release, err := ml.store.changes(mounts, ml.baseKey())
+	if err != nil {
+		return nil, err
+	}
+	release()
+	return changes, nil
+}
+
+// Metadata returns the snapshotter and the key of the snapshot of the
+// read-write layer.
+func (ml *rwLayer) Metadata() (map[string]string, error) {
+	return map[string]string{
+		"Snapshotter": ml.store.name,
+		"Snapshot":    ml.key,
+	}, nil
+}
+
+func (ml *rwLayer) getReference() layer.RWLayer {
+	ref := &referencedRWLayer{
+		rwLayer: ml,
+	}
+	ml.references[ref] = ref
+
+	return ref
+}
+
+func (ml *rwLayer) hasReferences() bool {
+	return len(ml.references) > 0
+}
+
+func (ml *rwLayer) deleteReference(ref layer.RWLayer) error {
+	if _, ok := ml.references[ref]; !ok {
+		return layer.ErrLayerNotRetained
+	}
+	delete(ml.references, ref)
+	return nil
+}
+
+func (ml *rwLayer) retakeReference(r layer.RWLayer) {
+	if ref, ok := r.(*referencedRWLayer); ok {
+		ml.references[ref] = ref
+	}
+}
+
+type referencedRWLayer struct {
+	*rwLayer
+}
+
+// Mount mounts the read-write snapshot on the first call, and returns the
+// path it is mounted at. The mount label is not applied, as the mounts are
+// provided by the snapshotter.
+func (rl *referencedRWLayer) Mount(mountLabel string) (containerfs.ContainerFS, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	dir := rl.path()
+	if rl.activeCount == 0 {
+		mounts, err := rl.store.sn.Mounts(rl.store.ctx, rl.key)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, err
+		}
+		if err := mount.All(mounts, dir); err != nil {
+			return nil, err
+		}
+	}
+	rl.activeCount++
+	return containerfs.NewLocalContainerFS(dir), nil
+}
+
+// Unmount decrements the activity count and unmounts the underlying layer
+// Callers should only call `Unmount` once per call to `Mount`, even on error.
+func (rl *referencedRWLayer) Unmount() error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.activeCount == 0 {
+		return nil
+	}
+	rl.activeCount--
+	if rl.activeCount > 0 {
+		return nil
+	}
+	if err := mount.UnmountAll(rl.path(), 0); err != nil {
+		return err
+	}
+	return os.Remove(rl.path())
+}
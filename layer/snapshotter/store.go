@@ -0,0 +1,707 @@
+// Package snapshotter implements a layer.Store on top of a containerd
+// snapshotter and content store. Read-only layers are stored as committed
+// snapshots named by their chain ID, the same naming containerd uses when
+// unpacking images, and the tar each layer was registered with is kept in
+// the content store under its DiffID. Layers are therefore visible to, and
+// can be shared with, containerd clients using the same namespace.
+package snapshotter // import "github.com/docker/docker/layer/snapshotter"
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/containerfs"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/pkg/system"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// maxLayerDepth matches the limit enforced by the graphdriver backed store.
+const maxLayerDepth = 125
+
+const (
+	// labelGCRoot prevents containerd from garbage collecting snapshots and
+	// content owned by the store. They are removed explicitly on release.
+	labelGCRoot = "containerd.io/gc.root"
+	// labelDiffID is set on committed layer snapshots to the DiffID of the
+	// layer, which is also the digest of its tar in the content store.
+	labelDiffID = "moby.layer.diffid"
+	// labelSize is set on committed layer snapshots to the size of the
+	// files applied from the layer tar.
+	labelSize = "moby.layer.size"
+	// labelInit is set on the committed snapshot holding the
+	// initialization of a read-write layer.
+	labelInit = "moby.layer.init"
+	// labelRWLayer is set on the active snapshot of a read-write layer to
+	// the name of the layer.
+	labelRWLayer = "moby.layer.rw"
+
+	// tmpKeyPrefix is the prefix of the keys of short lived snapshots, such
+	// as the ones used while extracting a layer, which are removed on
+	// restore if the daemon exited while using them.
+	tmpKeyPrefix  = "moby-tmp-"
+	initKeyPrefix = "moby-init-"
+	rwKeyPrefix   = "moby-rw-"
+)
+
+// Options are the options used to create a new snapshotter backed Store.
+type Options struct {
+	// Root is the directory used for the mountpoints of read-write layers.
+	Root string
+	// Snapshotter is the containerd snapshotter storing the layers, and
+	// SnapshotterName its name, as reported by DriverName.
+	Snapshotter     snapshots.Snapshotter
+	SnapshotterName string
+	// ContentStore stores the tar each read-only layer was registered with.
+	ContentStore content.Store
+	// Namespace is the containerd namespace the snapshots and content
+	// are created in.
+	Namespace string
+	IDMapping *idtools.IdentityMapping
+	OS        string
+}
+
+type store struct {
+	root      string
+	ctx       context.Context
+	sn        snapshots.Snapshotter
+	name      string
+	cs        content.Store
+	idMapping *idtools.IdentityMapping
+	os        string
+
+	layerMap map[layer.ChainID]*roLayer
+	layerL   sync.Mutex
+
+	mounts map[string]*rwLayer
+	mountL sync.Mutex
+}
+
+// NewStore creates a new Store instance using the snapshotter and content
+// store from the provided options. Layers and read-write layers previously
+// created by the store are restored from the snapshotter.
+func NewStore(options Options) (layer.Store, error) {
+	if !system.IsOSSupported(options.OS) {
+		return nil, fmt.Errorf("failed to initialize layer store as operating system '%s' is not supported", options.OS)
+	}
+	if options.Snapshotter == nil || options.ContentStore == nil {
+		return nil, errors.New("snapshotter layer store requires a snapshotter and a content store")
+	}
+	if err := os.MkdirAll(filepath.Join(options.Root, "mounts"), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(options.Root, "tmp"), 0700); err != nil {
+		return nil, err
+	}
+	idMapping := options.IDMapping
+	if idMapping == nil {
+		idMapping = &idtools.IdentityMapping{}
+	}
+
+	s := &store{
+		root:      options.Root,
+		ctx:       namespaces.WithNamespace(context.Background(), options.Namespace),
+		sn:        options.Snapshotter,
+		name:      options.SnapshotterName,
+		cs:        options.ContentStore,
+		idMapping: idMapping,
+		os:        options.OS,
+		layerMap:  map[layer.ChainID]*roLayer{},
+		mounts:    map[string]*rwLayer{},
+	}
+	if err := s.restore(); err != nil {
+		return nil, errors.Wrap(err, "failed to restore layers from snapshotter")
+	}
+	return s, nil
+}
+
+// restore loads the layers and read-write layers from the snapshotter, and
+// removes the temporary snapshots left behind by an unclean shutdown.
+func (s *store) restore() error {
+	infos := map[string]snapshots.Info{}
+	if err := s.sn.Walk(s.ctx, func(ctx context.Context, info snapshots.Info) error {
+		infos[info.Name] = info
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for name := range infos {
+		if strings.HasPrefix(name, tmpKeyPrefix) {
+			if err := s.sn.Remove(s.ctx, name); err != nil {
+				logrus.Warnf("Failed to remove temporary snapshot %s: %v", name, err)
+			}
+		}
+	}
+
+	for name, info := range infos {
+		if info.Kind != snapshots.KindCommitted || info.Labels[labelDiffID] == "" {
+			continue
+		}
+		l, err := s.loadLayer(infos, layer.ChainID(name))
+		if err != nil {
+			logrus.Debugf("Failed to load layer %s: %s", name, err)
+			continue
+		}
+		if l.parent != nil {
+			l.parent.referenceCount++
+		}
+	}
+
+	for key, info := range infos {
+		name := info.Labels[labelRWLayer]
+		if info.Kind != snapshots.KindActive || name == "" {
+			continue
+		}
+		m := &rwLayer{
+			name:       name,
+			key:        key,
+			store:      s,
+			references: map[layer.RWLayer]*referencedRWLayer{},
+		}
+		parent := info.Parent
+		if pi, ok := infos[parent]; ok && pi.Labels[labelInit] != "" {
+			m.initKey = parent
+			parent = pi.Parent
+		}
+		if parent != "" {
+			p, ok := s.layerMap[layer.ChainID(parent)]
+			if !ok {
+				logrus.Debugf("Failed to load mount %s: parent layer %s does not exist", name, parent)
+				continue
+			}
+			m.parent = p
+			p.referenceCount++
+		}
+		s.mounts[name] = m
+	}
+	return nil
+}
+
+func (s *store) loadLayer(infos map[string]snapshots.Info, chainID layer.ChainID) (*roLayer, error) {
+	if l, ok := s.layerMap[chainID]; ok {
+		return l, nil
+	}
+	info, ok := infos[string(chainID)]
+	if !ok {
+		return nil, fmt.Errorf("snapshot %s does not exist", chainID)
+	}
+	diffID, err := digest.Parse(info.Labels[labelDiffID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff id for %s: %s", chainID, err)
+	}
+	size, err := strconv.ParseInt(info.Labels[labelSize], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get size for %s: %s", chainID, err)
+	}
+
+	l := &roLayer{
+		chainID:    chainID,
+		diffID:     layer.DiffID(diffID),
+		size:       size,
+		store:      s,
+		references: map[layer.Layer]struct{}{},
+	}
+	if info.Parent != "" {
+		p, err := s.loadLayer(infos, layer.ChainID(info.Parent))
+		if err != nil {
+			return nil, err
+		}
+		l.parent = p
+	}
+	s.layerMap[chainID] = l
+	return l, nil
+}
+
+// Register applies the layer tar on top of the parent layer, commits the
+// result as a snapshot named by the chain ID of the new layer, and stores
+// the tar in the content store.
+func (s *store) Register(ts io.Reader, parent layer.ChainID) (layer.Layer, error) {
+	// err is used to hold the error which will always trigger
+	// cleanup of the parent reference.
+	var err error
+	var p *roLayer
+
+	if string(parent) != "" {
+		p = s.get(parent)
+		if p == nil {
+			return nil, layer.ErrLayerDoesNotExist
+		}
+		// Release parent chain if error
+		defer func() {
+			if err != nil {
+				s.layerL.Lock()
+				s.releaseLayer(p)
+				s.layerL.Unlock()
+			}
+		}()
+		if p.depth() >= maxLayerDepth {
+			err = layer.ErrMaxDepthExceeded
+			return nil, err
+		}
+	}
+
+	key := tmpKeyPrefix + stringid.GenerateRandomID()
+	mounts, err := s.sn.Prepare(s.ctx, key, string(parent), snapshots.WithLabels(map[string]string{
+		labelGCRoot: gcRoot(),
+	}))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to prepare snapshot")
+	}
+	defer func() {
+		if err := s.sn.Remove(s.ctx, key); err != nil && !errdefs.IsNotFound(err) {
+			logrus.Errorf("Error removing snapshot %s: %v", key, err)
+		}
+	}()
+
+	var (
+		diffID layer.DiffID
+		size   int64
+	)
+	err = mount.WithTempMount(s.ctx, mounts, func(root string) error {
+		var applyErr error
+		diffID, size, applyErr = s.applyTar(ts, root, key)
+		return applyErr
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	l := &roLayer{
+		parent:         p,
+		diffID:         diffID,
+		size:           size,
+		store:          s,
+		referenceCount: 1,
+		references:     map[layer.Layer]struct{}{},
+	}
+	if p == nil {
+		l.chainID = layer.ChainID(diffID)
+	} else {
+		l.chainID = layer.CreateChainID([]layer.DiffID{layer.DiffID(p.chainID), diffID})
+	}
+
+	s.layerL.Lock()
+	defer s.layerL.Unlock()
+
+	if existing := s.getWithoutLock(l.chainID); existing != nil {
+		// Set error for cleanup, but do not return the error
+		err = errors.New("layer already exists")
+		return existing.getReference(), nil
+	}
+
+	err = s.sn.Commit(s.ctx, string(l.chainID), key, snapshots.WithLabels(map[string]string{
+		labelGCRoot: gcRoot(),
+		labelDiffID: diffID.String(),
+		labelSize:   strconv.FormatInt(size, 10),
+	}))
+	if err != nil && errdefs.IsAlreadyExists(err) {
+		// The snapshot was already unpacked by containerd; adopt it.
+		err = s.adoptSnapshot(l)
+	}
+	if err != nil {
+		if !s.diffIDInUse(l) {
+			if err := s.cs.Delete(s.ctx, digest.Digest(diffID)); err != nil && !errdefs.IsNotFound(err) {
+				logrus.Errorf("Error removing layer content %s: %v", diffID, err)
+			}
+		}
+		return nil, errors.Wrap(err, "failed to commit snapshot")
+	}
+
+	s.layerMap[l.chainID] = l
+	return l.getReference(), nil
+}
+
+// applyTar applies the layer tar to root, while writing it to the content
+// store so that it can be exported exactly as it was registered.
+func (s *store) applyTar(ts io.Reader, root, ref string) (layer.DiffID, int64, error) {
+	w, err := content.OpenWriter(s.ctx, s.cs, content.WithRef(ref))
+	if err != nil {
+		return "", 0, errors.Wrap(err, "failed to open content writer")
+	}
+	defer w.Close()
+
+	digester := digest.Canonical.Digester()
+	counter := &countingWriter{}
+	rdr := io.TeeReader(ts, io.MultiWriter(w, digester.Hash(), counter))
+
+	size, err := chrootarchive.ApplyUncompressedLayer(root, rdr, &archive.TarOptions{
+		UIDMaps: s.idMapping.UIDs(),
+		GIDMaps: s.idMapping.GIDs(),
+	})
+	if err != nil {
+		s.cs.Abort(s.ctx, ref)
+		return "", 0, err
+	}
+	// Discard trailing data to ensure the entire stream is stored and
+	// verified, as the graphdriver backed store does.
+	if _, err := io.Copy(io.MultiWriter(w, digester.Hash(), counter), ts); err != nil {
+		s.cs.Abort(s.ctx, ref)
+		return "", 0, err
+	}
+
+	dgst := digester.Digest()
+	if err := w.Commit(s.ctx, counter.n, dgst, content.WithLabels(map[string]string{
+		labelGCRoot: gcRoot(),
+	})); err != nil && !errdefs.IsAlreadyExists(err) {
+		return "", 0, errors.Wrap(err, "failed to commit layer content")
+	}
+	return layer.DiffID(dgst), size, nil
+}
+
+// adoptSnapshot labels a snapshot created outside of the store, such as by
+// containerd unpacking an image, so that it is managed by the store.
+func (s *store) adoptSnapshot(l *roLayer) error {
+	info, err := s.sn.Stat(s.ctx, string(l.chainID))
+	if err != nil {
+		return err
+	}
+	info.Labels = map[string]string{
+		labelGCRoot: gcRoot(),
+		labelDiffID: l.diffID.String(),
+		labelSize:   strconv.FormatInt(l.size, 10),
+	}
+	_, err = s.sn.Update(s.ctx, info, "labels."+labelGCRoot, "labels."+labelDiffID, "labels."+labelSize)
+	return err
+}
+
+func (s *store) getWithoutLock(chainID layer.ChainID) *roLayer {
+	l, ok := s.layerMap[chainID]
+	if !ok {
+		return nil
+	}
+	l.referenceCount++
+	return l
+}
+
+func (s *store) get(chainID layer.ChainID) *roLayer {
+	s.layerL.Lock()
+	defer s.layerL.Unlock()
+	return s.getWithoutLock(chainID)
+}
+
+func (s *store) Get(chainID layer.ChainID) (layer.Layer, error) {
+	s.layerL.Lock()
+	defer s.layerL.Unlock()
+
+	l := s.getWithoutLock(chainID)
+	if l == nil {
+		return nil, layer.ErrLayerDoesNotExist
+	}
+	return l.getReference(), nil
+}
+
+func (s *store) Map() map[layer.ChainID]layer.Layer {
+	s.layerL.Lock()
+	defer s.layerL.Unlock()
+
+	layers := map[layer.ChainID]layer.Layer{}
+	for k, v := range s.layerMap {
+		layers[k] = v
+	}
+	return layers
+}
+
+func (s *store) deleteLayer(l *roLayer, metadata *layer.Metadata) error {
+	if err := s.sn.Remove(s.ctx, string(l.chainID)); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	// The content may be shared with a layer in another chain.
+	if !s.diffIDInUse(l) {
+		if err := s.cs.Delete(s.ctx, digest.Digest(l.diffID)); err != nil && !errdefs.IsNotFound(err) {
+			return err
+		}
+	}
+	metadata.DiffID = l.diffID
+	metadata.ChainID = l.chainID
+	metadata.Size = l.sizeWithParents()
+	metadata.DiffSize = l.size
+	return nil
+}
+
+func (s *store) diffIDInUse(l *roLayer) bool {
+	for _, other := range s.layerMap {
+		if other != l && other.diffID == l.diffID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *store) releaseLayer(l *roLayer) ([]layer.Metadata, error) {
+	depth := 0
+	removed := []layer.Metadata{}
+	for {
+		if l.referenceCount == 0 {
+			panic("layer not retained")
+		}
+		l.referenceCount--
+		if l.referenceCount != 0 {
+			return removed, nil
+		}
+
+		if len(removed) == 0 && depth > 0 {
+			panic("cannot remove layer with child")
+		}
+		if l.hasReferences() {
+			panic("cannot delete referenced layer")
+		}
+		var metadata layer.Metadata
+		if err := s.deleteLayer(l, &metadata); err != nil {
+			return nil, err
+		}
+
+		delete(s.layerMap, l.chainID)
+		removed = append(removed, metadata)
+
+		if l.parent == nil {
+			return removed, nil
+		}
+
+		depth++
+		l = l.parent
+	}
+}
+
+func (s *store) Release(l layer.Layer) ([]layer.Metadata, error) {
+	s.layerL.Lock()
+	defer s.layerL.Unlock()
+	rl, ok := s.layerMap[l.ChainID()]
+	if !ok {
+		return []layer.Metadata{}, nil
+	}
+	if !rl.hasReference(l) {
+		return nil, layer.ErrLayerNotRetained
+	}
+
+	rl.deleteReference(l)
+
+	return s.releaseLayer(rl)
+}
+
+// CreateRWLayer prepares an active snapshot for the read-write layer. When
+// an init function is provided, it is run against a separate snapshot which
+// is committed and used as the parent of the read-write snapshot, so that
+// the changes it makes are not exported with the layer.
+func (s *store) CreateRWLayer(name string, parent layer.ChainID, opts *layer.CreateRWLayerOpts) (layer.RWLayer, error) {
+	var initFunc layer.MountInit
+	if opts != nil {
+		initFunc = opts.InitFunc
+	}
+
+	s.mountL.Lock()
+	defer s.mountL.Unlock()
+	if _, ok := s.mounts[name]; ok {
+		return nil, layer.ErrMountNameConflict
+	}
+
+	var err error
+	var p *roLayer
+	if string(parent) != "" {
+		p = s.get(parent)
+		if p == nil {
+			return nil, layer.ErrLayerDoesNotExist
+		}
+
+		// Release parent chain if error
+		defer func() {
+			if err != nil {
+				s.layerL.Lock()
+				s.releaseLayer(p)
+				s.layerL.Unlock()
+			}
+		}()
+	}
+
+	m := &rwLayer{
+		name:       name,
+		key:        rwKeyPrefix + name,
+		parent:     p,
+		store:      s,
+		references: map[layer.RWLayer]*referencedRWLayer{},
+	}
+
+	snapshotParent := string(parent)
+	if initFunc != nil {
+		m.initKey, err = s.initMount(name, snapshotParent, initFunc)
+		if err != nil {
+			return nil, err
+		}
+		snapshotParent = m.initKey
+	}
+
+	if _, err = s.sn.Prepare(s.ctx, m.key, snapshotParent, snapshots.WithLabels(map[string]string{
+		labelGCRoot:  gcRoot(),
+		labelRWLayer: name,
+	})); err != nil {
+		if m.initKey != "" {
+			if err := s.sn.Remove(s.ctx, m.initKey); err != nil {
+				logrus.Errorf("Error removing init snapshot %s: %v", m.initKey, err)
+			}
+		}
+		return nil, errors.Wrap(err, "failed to prepare snapshot")
+	}
+
+	s.mounts[name] = m
+	return m.getReference(), nil
+}
+
+func (s *store) initMount(name, parent string, initFunc layer.MountInit) (string, error) {
+	initKey := initKeyPrefix + name
+	key := tmpKeyPrefix + stringid.GenerateRandomID()
+
+	mounts, err := s.sn.Prepare(s.ctx, key, parent, snapshots.WithLabels(map[string]string{
+		labelGCRoot: gcRoot(),
+	}))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to prepare init snapshot")
+	}
+	if err := mount.WithTempMount(s.ctx, mounts, func(root string) error {
+		return initFunc(containerfs.NewLocalContainerFS(root))
+	}); err != nil {
+		s.sn.Remove(s.ctx, key)
+		return "", err
+	}
+	if err := s.sn.Commit(s.ctx, initKey, key, snapshots.WithLabels(map[string]string{
+		labelGCRoot: gcRoot(),
+		labelInit:   name,
+	})); err != nil {
+		s.sn.Remove(s.ctx, key)
+		return "", errors.Wrap(err, "failed to commit init snapshot")
+	}
+	return initKey, nil
+}
+
+func (s *store) GetRWLayer(id string) (layer.RWLayer, error) {
+	s.mountL.Lock()
+	defer s.mountL.Unlock()
+	m, ok := s.mounts[id]
+	if !ok {
+		return nil, layer.ErrMountDoesNotExist
+	}
+	return m.getReference(), nil
+}
+
+// GetMountID returns the key of the snapshot of the read-write layer.
+func (s *store) GetMountID(id string) (string, error) {
+	s.mountL.Lock()
+	defer s.mountL.Unlock()
+	m, ok := s.mounts[id]
+	if !ok {
+		return "", layer.ErrMountDoesNotExist
+	}
+	return m.key, nil
+}
+
+func (s *store) ReleaseRWLayer(l layer.RWLayer) ([]layer.Metadata, error) {
+	s.mountL.Lock()
+	defer s.mountL.Unlock()
+	m, ok := s.mounts[l.Name()]
+	if !ok {
+		return []layer.Metadata{}, nil
+	}
+
+	if err := m.deleteReference(l); err != nil {
+		return nil, err
+	}
+
+	if m.hasReferences() {
+		return []layer.Metadata{}, nil
+	}
+
+	if err := s.sn.Remove(s.ctx, m.key); err != nil && !errdefs.IsNotFound(err) {
+		logrus.Errorf("Error removing mounted layer %s: %s", m.name, err)
+		m.retakeReference(l)
+		return nil, err
+	}
+
+	if m.initKey != "" {
+		if err := s.sn.Remove(s.ctx, m.initKey); err != nil && !errdefs.IsNotFound(err) {
+			logrus.Errorf("Error removing init layer %s: %s", m.name, err)
+			m.retakeReference(l)
+			return nil, err
+		}
+	}
+
+	delete(s.mounts, m.name)
+
+	s.layerL.Lock()
+	defer s.layerL.Unlock()
+	if m.parent != nil {
+		return s.releaseLayer(m.parent)
+	}
+
+	return []layer.Metadata{}, nil
+}
+
+// Cleanup unmounts the read-write layers which are still mounted. The
+// snapshotter itself is owned by the containerd client and is not closed.
+func (s *store) Cleanup() error {
+	s.mountL.Lock()
+	defer s.mountL.Unlock()
+	for _, m := range s.mounts {
+		m.mu.Lock()
+		if m.activeCount > 0 {
+			if err := mount.UnmountAll(m.path(), 0); err != nil {
+				logrus.Warnf("Failed to unmount %s: %v", m.path(), err)
+			}
+			m.activeCount = 0
+		}
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *store) DriverStatus() [][2]string {
+	return [][2]string{
+		{"Snapshotter", s.name},
+		{"Layers", strconv.Itoa(len(s.Map()))},
+	}
+}
+
+func (s *store) DriverName() string {
+	return s.name
+}
+
+// contentDescriptor returns the descriptor of the tar a layer was
+// registered with.
+func contentDescriptor(dgst digest.Digest) ocispec.Descriptor {
+	return ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    dgst,
+	}
+}
+
+// gcRoot returns the value of the label protecting snapshots and content
+// from garbage collection.
+func gcRoot() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,372 @@
+package snapshotter // import "github.com/docker/docker/layer/snapshotter"
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/containerd/containerd/content/local"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/mount"
+	"github.com/containerd/containerd/snapshots"
+	"github.com/docker/docker/daemon/graphdriver/copy"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/containerfs"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/opencontainers/go-digest"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/skip"
+)
+
+// naiveSnapshotter is a snapshotter copying the parent snapshot on prepare,
+// and providing bind mounts of the snapshot directories.
+type naiveSnapshotter struct {
+	mu    sync.Mutex
+	root  string
+	infos map[string]snapshots.Info
+	dirs  map[string]string
+}
+
+func newNaiveSnapshotter(root string) *naiveSnapshotter {
+	return &naiveSnapshotter{
+		root:  root,
+		infos: map[string]snapshots.Info{},
+		dirs:  map[string]string{},
+	}
+}
+
+func (s *naiveSnapshotter) Stat(ctx context.Context, key string) (snapshots.Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.infos[key]
+	if !ok {
+		return snapshots.Info{}, errdefs.ErrNotFound
+	}
+	return info, nil
+}
+
+func (s *naiveSnapshotter) Update(ctx context.Context, info snapshots.Info, fieldpaths ...string) (snapshots.Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.infos[info.Name]
+	if !ok {
+		return snapshots.Info{}, errdefs.ErrNotFound
+	}
+	labels := map[string]string{}
+	for k, v := range existing.Labels {
+		labels[k] = v
+	}
+	for k, v := range info.Labels {
+		labels[k] = v
+	}
+	existing.Labels = labels
+	s.infos[info.Name] = existing
+	return existing, nil
+}
+
+func (s *naiveSnapshotter) Usage(ctx context.Context, key string) (snapshots.Usage, error) {
+	s.mu.Lock()
+	dir, ok := s.dirs[key]
+	s.mu.Unlock()
+	if !ok {
+		return snapshots.Usage{}, errdefs.ErrNotFound
+	}
+	var usage snapshots.Usage
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		usage.Inodes++
+		usage.Size += fi.Size()
+		return nil
+	})
+	return usage, err
+}
+
+func (s *naiveSnapshotter) Mounts(ctx context.Context, key string) ([]mount.Mount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dir, ok := s.dirs[key]
+	if !ok {
+		return nil, errdefs.ErrNotFound
+	}
+	return []mount.Mount{{Type: "bind", Source: dir, Options: []string{"rbind", "rw"}}}, nil
+}
+
+func (s *naiveSnapshotter) create(key, parent string, kind snapshots.Kind, opts []snapshots.Opt) ([]mount.Mount, error) {
+	s.mu.Lock()
+	if _, ok := s.infos[key]; ok {
+		s.mu.Unlock()
+		return nil, errdefs.ErrAlreadyExists
+	}
+	info := snapshots.Info{Name: key, Parent: parent, Kind: kind}
+	for _, opt := range opts {
+		if err := opt(&info); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+	dir := filepath.Join(s.root, stringid.GenerateRandomID())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if parent != "" {
+		if err := copy.DirCopy(s.dirs[parent], dir, copy.Content, false); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+	}
+	s.infos[key] = info
+	s.dirs[key] = dir
+	s.mu.Unlock()
+	return s.Mounts(context.Background(), key)
+}
+
+func (s *naiveSnapshotter) Prepare(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	return s.create(key, parent, snapshots.KindActive, opts)
+}
+
+func (s *naiveSnapshotter) View(ctx context.Context, key, parent string, opts ...snapshots.Opt) ([]mount.Mount, error) {
+	return s.create(key, parent, snapshots.KindView, opts)
+}
+
+func (s *naiveSnapshotter) Commit(ctx context.Context, name, key string, opts ...snapshots.Opt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.infos[name]; ok {
+		return errdefs.ErrAlreadyExists
+	}
+	info, ok := s.infos[key]
+	if !ok {
+		return errdefs.ErrNotFound
+	}
+	info.Name = name
+	info.Kind = snapshots.KindCommitted
+	info.Labels = nil
+	for _, opt := range opts {
+		if err := opt(&info); err != nil {
+			return err
+		}
+	}
+	s.infos[name] = info
+	s.dirs[name] = s.dirs[key]
+	delete(s.infos, key)
+	delete(s.dirs, key)
+	return nil
+}
+
+func (s *naiveSnapshotter) Remove(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.infos[key]; !ok {
+		return errdefs.ErrNotFound
+	}
+	for _, info := range s.infos {
+		if info.Parent == key {
+			return errdefs.ErrFailedPrecondition
+		}
+	}
+	if err := os.RemoveAll(s.dirs[key]); err != nil {
+		return err
+	}
+	delete(s.infos, key)
+	delete(s.dirs, key)
+	return nil
+}
+
+func (s *naiveSnapshotter) Walk(ctx context.Context, fn func(context.Context, snapshots.Info) error) error {
+	s.mu.Lock()
+	var infos []snapshots.Info
+	for _, info := range s.infos {
+		infos = append(infos, info)
+	}
+	s.mu.Unlock()
+	for _, info := range infos {
+		if err := fn(ctx, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *naiveSnapshotter) Close() error {
+	return nil
+}
+
+func newTestStore(t *testing.T) (*naiveSnapshotter, Options, func()) {
+	skip.If(t, os.Getuid() != 0, "requires mounts")
+
+	td, err := ioutil.TempDir("", "snapshotter-layer-store")
+	assert.NilError(t, err)
+
+	cs, err := local.NewStore(filepath.Join(td, "content"))
+	assert.NilError(t, err)
+	sn := newNaiveSnapshotter(filepath.Join(td, "snapshots"))
+
+	opts := Options{
+		Root:            filepath.Join(td, "root"),
+		Snapshotter:     sn,
+		SnapshotterName: "naive",
+		ContentStore:    cs,
+		Namespace:       "test",
+		OS:              "linux",
+	}
+	return sn, opts, func() { os.RemoveAll(td) }
+}
+
+func tarFromFiles(t *testing.T, files map[string]string) []byte {
+	td, err := ioutil.TempDir("", "snapshotter-layer-tar")
+	assert.NilError(t, err)
+	defer os.RemoveAll(td)
+
+	for name, data := range files {
+		assert.NilError(t, ioutil.WriteFile(filepath.Join(td, name), []byte(data), 0644))
+	}
+	rc, err := archive.Tar(td, archive.Uncompressed)
+	assert.NilError(t, err)
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	assert.NilError(t, err)
+	return data
+}
+
+func TestRegisterRelease(t *testing.T) {
+	sn, opts, cleanup := newTestStore(t)
+	defer cleanup()
+
+	ls, err := NewStore(opts)
+	assert.NilError(t, err)
+
+	tar1 := tarFromFiles(t, map[string]string{"foo": "foo"})
+	l1, err := ls.Register(bytes.NewReader(tar1), "")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(layer.DiffID(digest.FromBytes(tar1)), l1.DiffID()))
+	assert.Check(t, is.Equal(layer.ChainID(l1.DiffID()), l1.ChainID()))
+
+	tar2 := tarFromFiles(t, map[string]string{"bar": "bar"})
+	l2, err := ls.Register(bytes.NewReader(tar2), l1.ChainID())
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(layer.CreateChainID([]layer.DiffID{l1.DiffID(), l2.DiffID()}), l2.ChainID()))
+
+	// Layers are stored as snapshots named by chain ID
+	info, err := sn.Stat(context.Background(), string(l2.ChainID()))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(l1.ChainID()), info.Parent))
+	assert.Check(t, is.Equal(snapshots.KindCommitted, info.Kind))
+
+	// The tar stream is the exact tar the layer was registered with
+	rc, err := l2.TarStream()
+	assert.NilError(t, err)
+	data, err := ioutil.ReadAll(rc)
+	rc.Close()
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(tar2, data))
+
+	// Releasing the first layer keeps it, as it is the parent of the second
+	metadata, err := ls.Release(l1)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(metadata, 0))
+	_, err = ls.Release(l1)
+	assert.Check(t, is.Equal(layer.ErrLayerNotRetained, err))
+
+	metadata, err = ls.Release(l2)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(metadata, 2))
+	assert.Check(t, is.Len(ls.Map(), 0))
+	assert.Check(t, is.Len(sn.infos, 0))
+}
+
+func TestRegisterExisting(t *testing.T) {
+	_, opts, cleanup := newTestStore(t)
+	defer cleanup()
+
+	ls, err := NewStore(opts)
+	assert.NilError(t, err)
+
+	tar1 := tarFromFiles(t, map[string]string{"foo": "foo"})
+	l1, err := ls.Register(bytes.NewReader(tar1), "")
+	assert.NilError(t, err)
+	l2, err := ls.Register(bytes.NewReader(tar1), "")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(l1.ChainID(), l2.ChainID()))
+
+	_, err = ls.Release(l1)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(ls.Map(), 1))
+	_, err = ls.Release(l2)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(ls.Map(), 0))
+}
+
+func TestRWLayer(t *testing.T) {
+	sn, opts, cleanup := newTestStore(t)
+	defer cleanup()
+
+	ls, err := NewStore(opts)
+	assert.NilError(t, err)
+
+	l, err := ls.Register(bytes.NewReader(tarFromFiles(t, map[string]string{"foo": "foo"})), "")
+	assert.NilError(t, err)
+
+	initFunc := func(root containerfs.ContainerFS) error {
+		return ioutil.WriteFile(filepath.Join(root.Path(), "init"), nil, 0644)
+	}
+	rw, err := ls.CreateRWLayer("test", l.ChainID(), &layer.CreateRWLayerOpts{InitFunc: initFunc})
+	assert.NilError(t, err)
+	_, err = ls.CreateRWLayer("test", l.ChainID(), nil)
+	assert.Check(t, is.Equal(layer.ErrMountNameConflict, err))
+
+	root, err := rw.Mount("")
+	assert.NilError(t, err)
+	for _, name := range []string{"foo", "init"} {
+		_, err := os.Stat(filepath.Join(root.Path(), name))
+		assert.Check(t, err)
+	}
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(root.Path(), "bar"), []byte("bar"), 0644))
+	assert.NilError(t, rw.Unmount())
+
+	// Changes made by the init function are not part of the layer
+	changes, err := rw.Changes()
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual([]archive.Change{{Path: "/bar", Kind: archive.ChangeAdd}}, changes))
+
+	rc, err := rw.TarStream()
+	assert.NilError(t, err)
+	l2, err := ls.Register(rc, l.ChainID())
+	rc.Close()
+	assert.NilError(t, err)
+	rc, err = l2.TarStreamFrom("")
+	assert.NilError(t, err)
+	_, err = io.Copy(ioutil.Discard, rc)
+	rc.Close()
+	assert.NilError(t, err)
+
+	// The read-write layer is restored from the snapshotter
+	ls, err = NewStore(opts)
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(ls.Map(), 2))
+	mountID, err := ls.GetMountID("test")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(rwKeyPrefix+"test", mountID))
+
+	rw, err = ls.GetRWLayer("test")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(l.ChainID(), rw.Parent().ChainID()))
+	_, err = ls.ReleaseRWLayer(rw)
+	assert.NilError(t, err)
+	_, err = ls.GetRWLayer("test")
+	assert.Check(t, is.Equal(layer.ErrMountDoesNotExist, err))
+
+	for _, info := range sn.infos {
+		assert.Check(t, info.Kind == snapshots.KindCommitted, "unexpected snapshot %s", info.Name)
+	}
+}
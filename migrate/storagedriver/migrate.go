@@ -0,0 +1,512 @@
+// +build linux
+
+// Package storagedriver migrates the images and containers of a daemon from
+// one storage driver to another, under a new data-root.
+package storagedriver // import "github.com/docker/docker/migrate/storagedriver"
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	mounttypes "github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/container"
+	"github.com/docker/docker/daemon/graphdriver/copy"
+	"github.com/docker/docker/daemon/initlayer"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/containerfs"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	imageDirName      = "image"
+	layerDBDirName    = "layerdb"
+	imageDBDirName    = "imagedb"
+	containersDirName = "containers"
+	copyPrefix        = ".migrate-"
+)
+
+// Options holds the data-roots and storage drivers to migrate between.
+type Options struct {
+	// Root is the data-root to migrate from, and Driver and DriverOptions
+	// its storage driver. Driver is detected from the state in Root if it
+	// is empty.
+	Root          string
+	Driver        string
+	DriverOptions []string
+
+	// NewRoot is the data-root to migrate to, and NewDriver and
+	// NewDriverOptions its storage driver.
+	NewRoot          string
+	NewDriver        string
+	NewDriverOptions []string
+
+	// DryRun verifies that every layer can be migrated, by checking its
+	// tar stream against its DiffID, without writing to NewRoot.
+	DryRun bool
+}
+
+// Result holds the number of images, layers and containers migrated.
+type Result struct {
+	Images     int
+	Layers     int
+	Containers int
+}
+
+type migrator struct {
+	opts      Options
+	driver    string
+	newDriver string
+	oldLS    layer.Store
+	newLS    layer.Store
+	migrated map[layer.ChainID]bool
+	result   Result
+}
+
+// Migrate reads all layers and container RW layers from the storage driver
+// of opts.Root through the tar streams of the layer store, and registers them
+// in the storage driver of opts.NewRoot. The chain ID of every layer, and the
+// ID of every image, are verified to be identical after the migration. The
+// metadata of containers is rewritten to use the new storage driver, and the
+// rest of the data-root is copied as is.
+//
+// Migrate must run while no daemon uses either data-root. It can be run again
+// after being interrupted, and resumes from what was already migrated.
+//
+// References to layers are never released, as releasing the last reference
+// to a layer removes it: the layer stores are only used by Migrate, and are
+// discarded when it returns.
+func Migrate(opts Options) (*Result, error) {
+	if opts.NewDriver == "" {
+		return nil, errors.New("no storage driver to migrate to")
+	}
+	if opts.NewRoot == "" {
+		return nil, errors.New("no data-root to migrate to")
+	}
+	root, err := filepath.Abs(opts.Root)
+	if err != nil {
+		return nil, err
+	}
+	newRoot, err := filepath.Abs(opts.NewRoot)
+	if err != nil {
+		return nil, err
+	}
+	if root == newRoot {
+		return nil, errors.Errorf("cannot migrate data-root %s to itself", root)
+	}
+	opts.Root, opts.NewRoot = root, newRoot
+
+	m := &migrator{
+		opts:     opts,
+		migrated: make(map[layer.ChainID]bool),
+	}
+
+	m.oldLS, err = newLayerStore(opts.Root, opts.Driver, opts.DriverOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer m.oldLS.Cleanup()
+	m.driver = m.oldLS.DriverName()
+	if m.driver == opts.NewDriver {
+		return nil, errors.Errorf("data-root %s already uses storage driver %s", opts.Root, m.driver)
+	}
+
+	oldImageRoot := filepath.Join(opts.Root, imageDirName, m.driver)
+	oldIS, err := newImageStore(oldImageRoot, m.oldLS)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.DryRun {
+		m.newLS, err = newLayerStore(opts.NewRoot, opts.NewDriver, opts.NewDriverOptions)
+		if err != nil {
+			return nil, err
+		}
+		defer m.newLS.Cleanup()
+		m.newDriver = m.newLS.DriverName()
+	}
+
+	images := oldIS.Map()
+	for id, img := range images {
+		chainID := img.RootFS.ChainID()
+		if chainID == "" {
+			continue
+		}
+		l, err := m.oldLS.Get(chainID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get layer %s of image %s", chainID, id)
+		}
+		if err := m.migrateLayer(l); err != nil {
+			return nil, errors.Wrapf(err, "failed to migrate image %s", id)
+		}
+		m.result.Images++
+	}
+
+	if err := m.migrateContainers(); err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return &m.result, nil
+	}
+
+	// The image metadata does not depend on the storage driver, and is
+	// copied as is, except for the layer store metadata.
+	newImageRoot := filepath.Join(opts.NewRoot, imageDirName, m.newDriver)
+	if err := copyEntries(oldImageRoot, newImageRoot, layerDBDirName); err != nil {
+		return nil, err
+	}
+	newIS, err := newImageStore(newImageRoot, m.newLS)
+	if err != nil {
+		return nil, err
+	}
+	newImages := newIS.Map()
+	for id, img := range images {
+		newImg, ok := newImages[id]
+		if !ok {
+			return nil, errors.Errorf("image %s was not migrated", id)
+		}
+		if newImg.RootFS.ChainID() != img.RootFS.ChainID() {
+			return nil, errors.Errorf("image %s has chain ID %s after migration, expected %s", id, newImg.RootFS.ChainID(), img.RootFS.ChainID())
+		}
+	}
+
+	// Everything else in the data-root, such as volumes, networks and
+	// plugins, does not depend on the storage driver either.
+	if err := copyEntries(opts.Root, opts.NewRoot, imageDirName, containersDirName, m.driver, m.newDriver); err != nil {
+		return nil, err
+	}
+
+	return &m.result, nil
+}
+
+func newLayerStore(root, driver string, driverOptions []string) (layer.Store, error) {
+	return layer.NewStoreFromOptions(layer.StoreOptions{
+		Root:                      root,
+		MetadataStorePathTemplate: filepath.Join(root, imageDirName, "%s", layerDBDirName),
+		GraphDriver:               driver,
+		GraphDriverOptions:        driverOptions,
+		IDMapping:                 &idtools.IdentityMapping{},
+		OS:                        runtime.GOOS,
+	})
+}
+
+func newImageStore(imageRoot string, ls layer.Store) (image.Store, error) {
+	ifs, err := image.NewFSStoreBackend(filepath.Join(imageRoot, imageDBDirName))
+	if err != nil {
+		return nil, err
+	}
+	return image.NewImageStore(ifs, map[string]image.LayerGetReleaser{runtime.GOOS: ls})
+}
+
+// migrateLayer registers the layer l, and its parents, in the new layer store
+// if they are not there yet, and verifies that their chain IDs are unchanged.
+// In a dry run, the tar streams of the layers are only checked against their
+// DiffIDs.
+func (m *migrator) migrateLayer(l layer.Layer) error {
+	chainID := l.ChainID()
+	if m.migrated[chainID] {
+		return nil
+	}
+
+	var parentChainID layer.ChainID
+	if parent := l.Parent(); parent != nil {
+		if err := m.migrateLayer(parent); err != nil {
+			return err
+		}
+		parentChainID = parent.ChainID()
+	}
+
+	if m.newLS != nil {
+		if _, err := m.newLS.Get(chainID); err == nil {
+			m.migrated[chainID] = true
+			return nil
+		}
+	}
+
+	ts, err := l.TarStream()
+	if err != nil {
+		return errors.Wrapf(err, "failed to read layer %s", chainID)
+	}
+	defer ts.Close()
+
+	if m.newLS == nil {
+		dgst, err := digest.FromReader(ts)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read layer %s", chainID)
+		}
+		if layer.DiffID(dgst) != l.DiffID() {
+			return errors.Errorf("layer %s has DiffID %s, expected %s", chainID, dgst, l.DiffID())
+		}
+	} else {
+		newLayer, err := m.newLS.Register(ts, parentChainID)
+		if err != nil {
+			return errors.Wrapf(err, "failed to register layer %s", chainID)
+		}
+		if newLayer.ChainID() != chainID {
+			return errors.Errorf("layer %s has chain ID %s after migration", chainID, newLayer.ChainID())
+		}
+	}
+
+	logrus.Debugf("migrated layer %s", chainID)
+	m.migrated[chainID] = true
+	m.result.Layers++
+	return nil
+}
+
+// migrateContainers creates the RW layer of every container in the new layer
+// store, with the changes of its RW layer in the old one, and copies the
+// container directory with its storage driver rewritten.
+func (m *migrator) migrateContainers() error {
+	dir, err := ioutil.ReadDir(filepath.Join(m.opts.Root, containersDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !m.opts.DryRun {
+		if err := os.MkdirAll(filepath.Join(m.opts.NewRoot, containersDirName), 0700); err != nil {
+			return err
+		}
+	}
+	for _, v := range dir {
+		if !v.IsDir() || strings.HasPrefix(v.Name(), copyPrefix) {
+			continue
+		}
+		if err := m.migrateContainer(v.Name()); err != nil {
+			return errors.Wrapf(err, "failed to migrate container %s", v.Name())
+		}
+	}
+	return nil
+}
+
+func (m *migrator) migrateContainer(id string) error {
+	c := container.NewBaseContainer(id, filepath.Join(m.opts.Root, containersDirName, id))
+	if err := c.FromDisk(); err != nil {
+		return err
+	}
+	if c.OS != runtime.GOOS {
+		logrus.Warnf("not migrating container %s with operating system %s", id, c.OS)
+		return nil
+	}
+
+	newDir := filepath.Join(m.opts.NewRoot, containersDirName, id)
+	if !m.opts.DryRun {
+		newC := container.NewBaseContainer(id, newDir)
+		if err := newC.FromDisk(); err == nil && newC.Driver == m.newDriver {
+			return nil
+		}
+	}
+
+	rwLayer, err := m.oldLS.GetRWLayer(id)
+	if err == layer.ErrMountDoesNotExist {
+		logrus.Warnf("not migrating container %s without RW layer", id)
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to get RW layer")
+	}
+	var parentChainID layer.ChainID
+	if parent := rwLayer.Parent(); parent != nil {
+		if err := m.migrateLayer(parent); err != nil {
+			return err
+		}
+		parentChainID = parent.ChainID()
+	}
+	for _, mp := range c.MountPoints {
+		if mp.Type == mounttypes.TypeImage {
+			if err := m.migrateImageMount(mp.ID, c.MountLabel); err != nil {
+				return errors.Wrapf(err, "failed to migrate image mount %s", mp.Destination)
+			}
+		}
+	}
+	if m.opts.DryRun {
+		m.result.Containers++
+		return nil
+	}
+
+	// An RW layer left by an interrupted migration is recreated
+	if l, err := m.newLS.GetRWLayer(id); err == nil {
+		if _, err := m.newLS.ReleaseRWLayer(l); err != nil {
+			return err
+		}
+	}
+	newRWLayer, err := m.newLS.CreateRWLayer(id, parentChainID, &layer.CreateRWLayerOpts{
+		MountLabel: c.MountLabel,
+		InitFunc: func(root containerfs.ContainerFS) error {
+			return initlayer.Setup(root, idtools.Identity{UID: 0, GID: 0})
+		},
+		StorageOpt: c.HostConfig.StorageOpt,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create RW layer")
+	}
+	if err := applyRWLayer(rwLayer, newRWLayer, c.MountLabel); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(newDir); err != nil {
+		return err
+	}
+	if err := copyEntry(c.Root, newDir); err != nil {
+		return err
+	}
+	newC := container.NewBaseContainer(id, newDir)
+	if err := newC.FromDisk(); err != nil {
+		return err
+	}
+	newC.Driver = m.newDriver
+	viewDB, err := container.NewViewDB()
+	if err != nil {
+		return err
+	}
+	if err := newC.CheckpointTo(viewDB); err != nil {
+		return err
+	}
+
+	logrus.Debugf("migrated container %s", id)
+	m.result.Containers++
+	return nil
+}
+
+// migrateImageMount creates the layer of an image mount in the new layer
+// store. Image mounts are read-only, so there are no changes to apply.
+func (m *migrator) migrateImageMount(name, mountLabel string) error {
+	// References are not released, as releasing the last reference to an
+	// RW layer removes it.
+	rwLayer, err := m.oldLS.GetRWLayer(name)
+	if err != nil {
+		return err
+	}
+	parent := rwLayer.Parent()
+	if parent == nil {
+		return nil
+	}
+	if err := m.migrateLayer(parent); err != nil {
+		return err
+	}
+	if m.opts.DryRun {
+		return nil
+	}
+	if _, err := m.newLS.GetRWLayer(name); err == nil {
+		// Created by an interrupted migration
+		return nil
+	}
+	_, err = m.newLS.CreateRWLayer(name, parent.ChainID(), &layer.CreateRWLayerOpts{MountLabel: mountLabel})
+	return err
+}
+
+// applyRWLayer applies the changes of the RW layer from to the RW layer to.
+func applyRWLayer(from, to layer.RWLayer, mountLabel string) error {
+	ts, err := from.TarStream()
+	if err != nil {
+		return errors.Wrap(err, "failed to read RW layer")
+	}
+	defer ts.Close()
+
+	fs, err := to.Mount(mountLabel)
+	if err != nil {
+		return err
+	}
+	defer to.Unmount()
+
+	if _, err := chrootarchive.ApplyUncompressedLayer(fs.Path(), ts, &archive.TarOptions{}); err != nil {
+		return errors.Wrap(err, "failed to apply RW layer")
+	}
+	return nil
+}
+
+// copyEntries copies the entries of the directory src to dst, except for
+// those named in exclude.
+func copyEntries(src, dst string, exclude ...string) error {
+	dir, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0700); err != nil {
+		return err
+	}
+	excluded := make(map[string]bool)
+	for _, name := range exclude {
+		excluded[name] = true
+	}
+	for _, v := range dir {
+		name := v.Name()
+		srcPath := filepath.Join(src, name)
+		if excluded[name] || strings.HasPrefix(name, copyPrefix) || strings.HasPrefix(dst, srcPath+string(filepath.Separator)) || dst == srcPath {
+			continue
+		}
+		if err := copyEntry(srcPath, filepath.Join(dst, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyEntry copies the file or directory src to dst, unless dst exists. The
+// copy is made to a temporary path first and renamed to dst when complete, so
+// that a copy interrupted is made again when the migration is resumed.
+func copyEntry(src, dst string) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(dst), copyPrefix+filepath.Base(dst))
+	if err := os.RemoveAll(tmp); err != nil {
+		return err
+	}
+
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	switch {
+	case fi.IsDir():
+		if err := copy.DirCopy(src, tmp, copy.Content, true); err != nil {
+			return errors.Wrapf(err, "failed to copy %s", src)
+		}
+	case fi.Mode()&os.ModeSymlink != 0:
+		link, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(link, tmp); err != nil {
+			return err
+		}
+	case fi.Mode().IsRegular():
+		if err := copyFile(src, tmp, fi.Mode()); err != nil {
+			return errors.Wrapf(err, "failed to copy %s", src)
+		}
+	default:
+		logrus.Warnf("not copying %s, which is not a regular file, directory or symlink", src)
+		return nil
+	}
+	return os.Rename(tmp, dst)
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
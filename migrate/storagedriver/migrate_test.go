@@ -0,0 +1,198 @@
+// +build linux
+
+package storagedriver // import "github.com/docker/docker/migrate/storagedriver"
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	containertypes "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/container"
+	"github.com/docker/docker/daemon/graphdriver"
+	"github.com/docker/docker/daemon/graphdriver/vfs"
+	"github.com/docker/docker/image"
+	"github.com/docker/docker/layer"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/reexec"
+	"github.com/docker/docker/pkg/stringid"
+	"gotest.tools/assert"
+	is "gotest.tools/assert/cmp"
+	"gotest.tools/skip"
+)
+
+const migratedDriverName = "vfs-migrated"
+
+// migratedDriver is the vfs driver under another name, to migrate to.
+type migratedDriver struct {
+	graphdriver.Driver
+}
+
+func (migratedDriver) String() string {
+	return migratedDriverName
+}
+
+func init() {
+	reexec.Init()
+	graphdriver.Register(migratedDriverName, func(home string, options []string, uidMaps, gidMaps []idtools.IDMap) (graphdriver.Driver, error) {
+		d, err := vfs.Init(home, options, uidMaps, gidMaps)
+		if err != nil {
+			return nil, err
+		}
+		return migratedDriver{d}, nil
+	})
+}
+
+// setupRoot creates a data-root using the vfs driver, with an image of two
+// layers and a container with a file in its RW layer.
+func setupRoot(t *testing.T, root string) (image.ID, layer.ChainID, string) {
+	ls, err := newLayerStore(root, "vfs", nil)
+	assert.NilError(t, err)
+	defer ls.Cleanup()
+
+	rootFS := image.NewRootFS()
+	var top layer.Layer
+	for _, content := range []string{"base", "app"} {
+		tar, err := archive.Generate(content, content)
+		assert.NilError(t, err)
+		var parent layer.ChainID
+		if top != nil {
+			parent = top.ChainID()
+		}
+		top, err = ls.Register(tar, parent)
+		assert.NilError(t, err)
+		rootFS.Append(top.DiffID())
+	}
+
+	config, err := json.Marshal(&image.Image{
+		V1Image: image.V1Image{OS: runtime.GOOS, Architecture: runtime.GOARCH},
+		RootFS:  rootFS,
+	})
+	assert.NilError(t, err)
+	imgStore, err := newImageStore(filepath.Join(root, imageDirName, "vfs"), ls)
+	assert.NilError(t, err)
+	imgID, err := imgStore.Create(config)
+	assert.NilError(t, err)
+
+	id := stringid.GenerateRandomID()
+	rwLayer, err := ls.CreateRWLayer(id, top.ChainID(), nil)
+	assert.NilError(t, err)
+	fs, err := rwLayer.Mount("")
+	assert.NilError(t, err)
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(fs.Path(), "changed"), []byte("changed"), 0644))
+	assert.NilError(t, rwLayer.Unmount())
+
+	c := container.NewBaseContainer(id, filepath.Join(root, containersDirName, id))
+	c.Driver = "vfs"
+	c.ImageID = imgID
+	c.OS = runtime.GOOS
+	c.Config = &containertypes.Config{}
+	c.HostConfig = &containertypes.HostConfig{}
+	assert.NilError(t, os.MkdirAll(c.Root, 0700))
+	viewDB, err := container.NewViewDB()
+	assert.NilError(t, err)
+	assert.NilError(t, c.CheckpointTo(viewDB))
+
+	assert.NilError(t, os.MkdirAll(filepath.Join(root, "volumes"), 0700))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(root, "volumes", "metadata.db"), []byte("volumes"), 0600))
+
+	return imgID, top.ChainID(), id
+}
+
+func TestMigrate(t *testing.T) {
+	skip.If(t, os.Getuid() != 0, "skipping test that requires root")
+	tmp, err := ioutil.TempDir("", "migrate-storagedriver-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmp)
+	root, newRoot := filepath.Join(tmp, "old"), filepath.Join(tmp, "new")
+
+	imgID, chainID, containerID := setupRoot(t, root)
+
+	opts := Options{
+		Root:      root,
+		NewRoot:   newRoot,
+		NewDriver: migratedDriverName,
+	}
+	res, err := Migrate(opts)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(Result{Images: 1, Layers: 2, Containers: 1}, *res))
+
+	// Running the migration again resumes it, with nothing left to migrate
+	res, err = Migrate(opts)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(Result{Images: 1}, *res))
+
+	ls, err := newLayerStore(newRoot, migratedDriverName, nil)
+	assert.NilError(t, err)
+	defer ls.Cleanup()
+	_, err = ls.Get(chainID)
+	assert.NilError(t, err)
+
+	imgStore, err := newImageStore(filepath.Join(newRoot, imageDirName, migratedDriverName), ls)
+	assert.NilError(t, err)
+	img, err := imgStore.Get(imgID)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(chainID, img.RootFS.ChainID()))
+
+	c := container.NewBaseContainer(containerID, filepath.Join(newRoot, containersDirName, containerID))
+	assert.NilError(t, c.FromDisk())
+	assert.Check(t, is.Equal(migratedDriverName, c.Driver))
+
+	rwLayer, err := ls.GetRWLayer(containerID)
+	assert.NilError(t, err)
+	fs, err := rwLayer.Mount("")
+	assert.NilError(t, err)
+	for name, content := range map[string]string{"base": "base", "app": "app", "changed": "changed"} {
+		b, err := ioutil.ReadFile(filepath.Join(fs.Path(), name))
+		assert.Check(t, err)
+		assert.Check(t, is.Equal(content, string(b)))
+	}
+	assert.NilError(t, rwLayer.Unmount())
+
+	b, err := ioutil.ReadFile(filepath.Join(newRoot, "volumes", "metadata.db"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal("volumes", string(b)))
+	_, err = os.Stat(filepath.Join(newRoot, "vfs"))
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	skip.If(t, os.Getuid() != 0, "skipping test that requires root")
+	tmp, err := ioutil.TempDir("", "migrate-storagedriver-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmp)
+	root, newRoot := filepath.Join(tmp, "old"), filepath.Join(tmp, "new")
+
+	setupRoot(t, root)
+
+	res, err := Migrate(Options{
+		Root:      root,
+		NewRoot:   newRoot,
+		NewDriver: migratedDriverName,
+		DryRun:    true,
+	})
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(Result{Images: 1, Layers: 2, Containers: 1}, *res))
+
+	_, err = os.Stat(newRoot)
+	assert.Check(t, os.IsNotExist(err))
+}
+
+func TestMigrateSameDriver(t *testing.T) {
+	skip.If(t, os.Getuid() != 0, "skipping test that requires root")
+	tmp, err := ioutil.TempDir("", "migrate-storagedriver-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmp)
+
+	_, err = Migrate(Options{
+		Root:      filepath.Join(tmp, "old"),
+		Driver:    "vfs",
+		NewRoot:   filepath.Join(tmp, "new"),
+		NewDriver: "vfs",
+	})
+	assert.Check(t, is.ErrorContains(err, "already uses storage driver vfs"))
+}
@@ -95,6 +95,8 @@ const (
 	Gzip
 	// Xz is xz compression algorithm.
 	Xz
+	// Zstd is zstd compression algorithm.
+	Zstd
 )
 
 const (
@@ -139,6 +141,7 @@ func DetectCompression(source []byte) Compression {
 		Bzip2: {0x42, 0x5A, 0x68},
 		Gzip:  {0x1F, 0x8B, 0x08},
 		Xz:    {0xFD, 0x37, 0x7A, 0x58, 0x5A, 0x00},
+		Zstd:  {0x28, 0xB5, 0x2F, 0xFD},
 	} {
 		if len(source) < len(m) {
 			logrus.Debug("Len too short")
@@ -157,6 +160,21 @@ func xzDecompress(ctx context.Context, archive io.Reader) (io.ReadCloser, error)
 	return cmdStream(exec.CommandContext(ctx, args[0], args[1:]...), archive)
 }
 
+func zstdDecompress(ctx context.Context, archive io.Reader) (io.ReadCloser, error) {
+	args := []string{"zstd", "-d", "-c", "-q"}
+
+	return cmdStream(exec.CommandContext(ctx, args[0], args[1:]...), archive)
+}
+
+func zstdCompress(dest io.Writer, level int) (io.WriteCloser, error) {
+	args := []string{"zstd", "-c", "-q"}
+	if level != 0 {
+		args = append(args, "-"+strconv.Itoa(level))
+	}
+
+	return cmdWriteStream(exec.Command(args[0], args[1:]...), dest)
+}
+
 func gzDecompress(ctx context.Context, buf io.Reader) (io.ReadCloser, error) {
 	if unpigzPath == "" {
 		return gzip.NewReader(buf)
@@ -225,6 +243,16 @@ func DecompressStream(archive io.Reader) (io.ReadCloser, error) {
 		}
 		readBufWrapper := p.NewReadCloserWrapper(buf, xzReader)
 		return wrapReadCloser(readBufWrapper, cancel), nil
+	case Zstd:
+		ctx, cancel := context.WithCancel(context.Background())
+
+		zstdReader, err := zstdDecompress(ctx, buf)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		readBufWrapper := p.NewReadCloserWrapper(buf, zstdReader)
+		return wrapReadCloser(readBufWrapper, cancel), nil
 	default:
 		return nil, fmt.Errorf("Unsupported compression format %s", (&compression).Extension())
 	}
@@ -232,6 +260,13 @@ func DecompressStream(archive io.Reader) (io.ReadCloser, error) {
 
 // CompressStream compresses the dest with specified compression algorithm.
 func CompressStream(dest io.Writer, compression Compression) (io.WriteCloser, error) {
+	return CompressStreamWithLevel(dest, compression, 0)
+}
+
+// CompressStreamWithLevel compresses the dest with specified compression
+// algorithm at the given compression level. A level of 0 selects the default
+// level of the algorithm.
+func CompressStreamWithLevel(dest io.Writer, compression Compression, level int) (io.WriteCloser, error) {
 	p := pools.BufioWriter32KPool
 	buf := p.Get(dest)
 	switch compression {
@@ -239,9 +274,22 @@ func CompressStream(dest io.Writer, compression Compression) (io.WriteCloser, er
 		writeBufWrapper := p.NewWriteCloserWrapper(buf, buf)
 		return writeBufWrapper, nil
 	case Gzip:
-		gzWriter := gzip.NewWriter(dest)
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		gzWriter, err := gzip.NewWriterLevel(dest, level)
+		if err != nil {
+			return nil, err
+		}
 		writeBufWrapper := p.NewWriteCloserWrapper(buf, gzWriter)
 		return writeBufWrapper, nil
+	case Zstd:
+		zstdWriter, err := zstdCompress(dest, level)
+		if err != nil {
+			return nil, err
+		}
+		writeBufWrapper := p.NewWriteCloserWrapper(buf, zstdWriter)
+		return writeBufWrapper, nil
 	case Bzip2, Xz:
 		// archive/bzip2 does not support writing, and there is no xz support at all
 		// However, this is not a problem as docker only currently generates gzipped tars
@@ -338,6 +386,53 @@ func ReplaceFileTarWrapper(inputTarStream io.ReadCloser, mods map[string]TarModi
 	return pipeReader
 }
 
+// ClampTimestampsTarWrapper converts inputTarStream to a new tar stream in
+// which no timestamp is later than epoch, so that archives of the same files
+// produced at different times are identical. This is used to honour
+// SOURCE_DATE_EPOCH (https://reproducible-builds.org/specs/source-date-epoch/).
+// inputTarStream is closed before the end of the new stream is reached.
+func ClampTimestampsTarWrapper(inputTarStream io.ReadCloser, epoch time.Time) io.ReadCloser {
+	pipeReader, pipeWriter := io.Pipe()
+
+	go func() {
+		err := clampTimestamps(tar.NewWriter(pipeWriter), tar.NewReader(inputTarStream), epoch)
+		if cerr := inputTarStream.Close(); err == nil {
+			err = cerr
+		}
+		pipeWriter.CloseWithError(err)
+	}()
+	return pipeReader
+}
+
+func clampTimestamps(tarWriter *tar.Writer, tarReader *tar.Reader, epoch time.Time) error {
+	clamp := func(t time.Time) time.Time {
+		if t.After(epoch) {
+			return epoch
+		}
+		return t
+	}
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		header.ModTime = clamp(header.ModTime)
+		header.AccessTime = clamp(header.AccessTime)
+		header.ChangeTime = clamp(header.ChangeTime)
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := pools.Copy(tarWriter, tarReader); err != nil {
+			return err
+		}
+	}
+	return tarWriter.Close()
+}
+
 // Extension returns the extension of a file that uses the specified compression algorithm.
 func (compression *Compression) Extension() string {
 	switch *compression {
@@ -349,6 +444,8 @@ func (compression *Compression) Extension() string {
 		return "tar.gz"
 	case Xz:
 		return "tar.xz"
+	case Zstd:
+		return "tar.zst"
 	}
 	return ""
 }
@@ -1019,7 +1116,7 @@ loop:
 // Untar reads a stream of bytes from `archive`, parses it as a tar archive,
 // and unpacks it into the directory at `dest`.
 // The archive may be compressed with one of the following algorithms:
-//  identity (uncompressed), gzip, bzip2, xz.
+//  identity (uncompressed), gzip, bzip2, xz, zstd.
 // FIXME: specify behavior when target path exists vs. doesn't exist.
 func Untar(tarArchive io.Reader, dest string, options *TarOptions) error {
 	return untarHandler(tarArchive, dest, options, true)
@@ -1230,6 +1327,31 @@ func cmdStream(cmd *exec.Cmd, input io.Reader) (io.ReadCloser, error) {
 	return pipeR, nil
 }
 
+// cmdWriteStream executes a command, and returns its stdin as a stream.
+// The command's stdout is written to output. Closing the stream waits
+// for the command to exit, and returns an error if it failed.
+func cmdWriteStream(cmd *exec.Cmd, output io.Writer) (io.WriteCloser, error) {
+	cmd.Stdout = output
+	var errBuf bytes.Buffer
+	cmd.Stderr = &errBuf
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return ioutils.NewWriteCloserWrapper(stdin, func() error {
+		stdin.Close()
+		if err := cmd.Wait(); err != nil {
+			return fmt.Errorf("%s: %s", err, errBuf.String())
+		}
+		return nil
+	}), nil
+}
+
 // NewTempArchive reads the content of src into a temporary file, and returns the contents
 // of that file as an archive. The archive can only be read once - as soon as reading completes,
 // the file will be deleted.
@@ -134,6 +134,52 @@ func TestDecompressStreamXz(t *testing.T) {
 	testDecompressStream(t, "xz", "xz -f")
 }
 
+func TestDecompressStreamZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	testDecompressStream(t, "zst", "zstd -f -q")
+}
+
+func TestCompressStreamZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	dest := &bytes.Buffer{}
+	w, err := CompressStreamWithLevel(dest, Zstd, 19)
+	if err != nil {
+		t.Fatalf("Failed to create zstd compressor: %v", err)
+	}
+	if _, err := w.Write([]byte("hello zstd")); err != nil {
+		t.Fatalf("Failed to write to the compressed stream: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close the compressed stream: %v", err)
+	}
+	if compression := DetectCompression(dest.Bytes()); compression != Zstd {
+		t.Fatalf("Expected zstd compression, got %s", compression.Extension())
+	}
+
+	r, err := DecompressStream(dest)
+	if err != nil {
+		t.Fatalf("Failed to decompress zstd stream: %v", err)
+	}
+	defer r.Close()
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("Failed to read the decompressed stream: %v", err)
+	}
+	if string(content) != "hello zstd" {
+		t.Fatalf("Unexpected decompressed content %q", content)
+	}
+}
+
+func TestCompressStreamGzipInvalidLevel(t *testing.T) {
+	if _, err := CompressStreamWithLevel(&bytes.Buffer{}, Gzip, 42); err == nil {
+		t.Fatalf("Should fail as 42 is not a valid gzip compression level.")
+	}
+}
+
 func TestCompressStreamXzUnsupported(t *testing.T) {
 	dest, err := os.Create(tmp + "dest")
 	if err != nil {
@@ -209,6 +255,13 @@ func TestExtensionXz(t *testing.T) {
 		t.Fatalf("The extension of a xz archive should be 'tar.xz'")
 	}
 }
+func TestExtensionZstd(t *testing.T) {
+	compression := Zstd
+	output := compression.Extension()
+	if output != "tar.zst" {
+		t.Fatalf("The extension of a zstd archive should be 'tar.zst'")
+	}
+}
 
 func TestCmdStreamLargeStderr(t *testing.T) {
 	cmd := exec.Command("sh", "-c", "dd if=/dev/zero bs=1k count=1000 of=/dev/stderr; echo hello")
@@ -1225,6 +1278,43 @@ func TestReplaceFileTarWrapper(t *testing.T) {
 	}
 }
 
+func TestClampTimestampsTarWrapper(t *testing.T) {
+	epoch := time.Unix(1000000000, 0)
+	before := epoch.Add(-time.Hour)
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for name, modTime := range map[string]time.Time{"old": before, "new": time.Now()} {
+		assert.NilError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: 3, ModTime: modTime, Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte("foo"))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, tw.Close())
+
+	rdr := ClampTimestampsTarWrapper(ioutil.NopCloser(buf), epoch)
+	defer rdr.Close()
+	tr := tar.NewReader(rdr)
+	var count int
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NilError(t, err)
+		count++
+
+		expected := epoch
+		if hdr.Name == "old" {
+			expected = before
+		}
+		assert.Check(t, is.Equal(hdr.ModTime.Unix(), expected.Unix()), hdr.Name)
+		content, err := ioutil.ReadAll(tr)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(string(content), "foo"))
+	}
+	assert.Check(t, is.Equal(count, 2))
+}
+
 // TestPrefixHeaderReadable tests that files that could be created with the
 // version of this package that was built with <=go17 are still readable.
 func TestPrefixHeaderReadable(t *testing.T) {
@@ -36,6 +36,22 @@ func (a *volumeDriverAdapter) Create(name string, opts map[string]string) (volum
 	}, nil
 }
 
+func (a *volumeDriverAdapter) CanClone() bool {
+	return a.getCapabilities().Clone
+}
+
+func (a *volumeDriverAdapter) Clone(name, source string, opts map[string]string) (volume.Volume, error) {
+	if err := a.proxy.Clone(name, source, opts); err != nil {
+		return nil, err
+	}
+	return &volumeAdapter{
+		proxy:      a.proxy,
+		name:       name,
+		driverName: a.name,
+		scopePath:  a.scopePath,
+	}, nil
+}
+
 func (a *volumeDriverAdapter) Remove(v volume.Volume) error {
 	return a.proxy.Remove(v.Name())
 }
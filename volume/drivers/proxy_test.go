@@ -52,6 +52,11 @@ func TestVolumeRequestError(t *testing.T) {
 		fmt.Fprintln(w, `{"Err": "Cannot get volume"}`)
 	})
 
+	mux.HandleFunc("/VolumeDriver.Clone", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.docker.plugins.v1+json")
+		fmt.Fprintln(w, `{"Err": "Cannot clone volume"}`)
+	})
+
 	mux.HandleFunc("/VolumeDriver.Capabilities", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/vnd.docker.plugins.v1+json")
 		http.Error(w, "error", 500)
@@ -129,4 +134,12 @@ func TestVolumeRequestError(t *testing.T) {
 	if err == nil {
 		t.Fatal(err)
 	}
+
+	err = driver.Clone("volume", "source", nil)
+	if err == nil {
+		t.Fatal("Expected error, was nil")
+	}
+	if !strings.Contains(err.Error(), "Cannot clone volume") {
+		t.Fatalf("Unexpected error: %v\n", err)
+	}
 }
@@ -13,12 +13,14 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/daemon/names"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/volume"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 // VolumeDataPathName is the name of the directory where the volume data is stored.
@@ -60,6 +62,15 @@ func New(scope string, rootIdentity idtools.Identity) (*Root, error) {
 		rootIdentity: rootIdentity,
 	}
 
+	// Project quotas limit the size of the volumes created with the "size"
+	// option, if the filesystem of the volumes directory supports them.
+	quotaCtl, err := quota.NewControl(rootDirectory)
+	if err != nil {
+		logrus.Debugf("Project quotas are not supported for local volumes in %s: %v", rootDirectory, err)
+	} else {
+		r.quotaCtl = quotaCtl
+	}
+
 	dirs, err := ioutil.ReadDir(rootDirectory)
 	if err != nil {
 		return nil, err
@@ -75,6 +86,7 @@ func New(scope string, rootIdentity idtools.Identity) (*Root, error) {
 			driverName: r.Name(),
 			name:       name,
 			path:       r.DataPath(name),
+			quotaCtl:   r.quotaCtl,
 		}
 		r.volumes[name] = v
 		optsFilePath := filepath.Join(rootDirectory, name, "opts.json")
@@ -90,7 +102,9 @@ func New(scope string, rootIdentity idtools.Identity) (*Root, error) {
 			}
 
 			// unmount anything that may still be mounted (for example, from an unclean shutdown)
-			mount.Unmount(v.path)
+			if v.needsMount() {
+				mount.Unmount(v.path)
+			}
 		}
 	}
 
@@ -106,6 +120,7 @@ type Root struct {
 	path         string
 	volumes      map[string]*localVolume
 	rootIdentity idtools.Identity
+	quotaCtl     *quota.Control
 }
 
 // List lists all the volumes
@@ -161,6 +176,7 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 		driverName: r.Name(),
 		name:       name,
 		path:       path,
+		quotaCtl:   r.quotaCtl,
 	}
 
 	if len(opts) != 0 {
@@ -175,6 +191,9 @@ func (r *Root) Create(name string, opts map[string]string) (volume.Volume, error
 		if err = ioutil.WriteFile(filepath.Join(filepath.Dir(path), "opts.json"), b, 600); err != nil {
 			return nil, errdefs.System(errors.Wrap(err, "error while persisting volume options"))
 		}
+		if err = v.setQuota(); err != nil {
+			return nil, err
+		}
 	}
 
 	r.volumes[name] = v
@@ -278,6 +297,8 @@ type localVolume struct {
 	driverName string
 	// opts is the parsed list of options used to create the volume
 	opts *optsConfig
+	// quotaCtl sets the size limit of the volume, if it has one
+	quotaCtl *quota.Control
 	// active refcounts the active mounts
 	active activeMount
 }
@@ -307,7 +328,7 @@ func (v *localVolume) CachedPath() string {
 func (v *localVolume) Mount(id string) (string, error) {
 	v.m.Lock()
 	defer v.m.Unlock()
-	if v.opts != nil {
+	if v.needsMount() {
 		if !v.active.mounted {
 			if err := v.mount(); err != nil {
 				return "", errdefs.System(err)
@@ -329,7 +350,7 @@ func (v *localVolume) Unmount(id string) error {
 	// Essentially docker doesn't care if this fails, it will send an error, but
 	// ultimately there's nothing that can be done. If we don't decrement the count
 	// this volume can never be removed until a daemon restart occurs.
-	if v.opts != nil {
+	if v.needsMount() {
 		v.active.count--
 	}
 
@@ -341,7 +362,7 @@ func (v *localVolume) Unmount(id string) error {
 }
 
 func (v *localVolume) unmount() error {
-	if v.opts != nil {
+	if v.needsMount() {
 		if err := mount.Unmount(v.path); err != nil {
 			if mounted, mErr := mount.Mounted(v.path); mounted || mErr != nil {
 				return errdefs.System(errors.Wrapf(err, "error while unmounting volume path '%s'", v.path))
@@ -333,3 +333,38 @@ func TestRelaodNoOpts(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateWithSize(t *testing.T) {
+	skip.If(t, runtime.GOOS == "windows")
+	rootDir, err := ioutil.TempDir("", "local-volume-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootDir)
+
+	r, err := New(rootDir, idtools.Identity{UID: os.Geteuid(), GID: os.Getegid()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, opts := range []map[string]string{
+		{"size": "invalid"},
+		{"size": "0"},
+		{"size": "1m", "device": "tmpfs", "type": "tmpfs"},
+	} {
+		if _, err := r.Create("test", opts); err == nil {
+			t.Fatalf("expected options %v to cause error", opts)
+		}
+	}
+
+	if r.quotaCtl != nil {
+		t.Skip("requires a filesystem without project quota support")
+	}
+	_, err = r.Create("test", map[string]string{"size": "1m"})
+	if err == nil || !strings.Contains(err.Error(), "requires project quota support") {
+		t.Fatalf("expected missing project quota support to cause error, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(rootDir, volumesPathName, "test")); !os.IsNotExist(err) {
+		t.Fatalf("expected volume directory to be removed, got: %v", err)
+	}
+}
@@ -16,7 +16,9 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/docker/docker/daemon/graphdriver/quota"
 	"github.com/docker/docker/pkg/mount"
+	"github.com/docker/go-units"
 )
 
 var (
@@ -26,6 +28,7 @@ var (
 		"type":   true, // specify the filesystem type for mount, e.g. nfs
 		"o":      true, // generic mount options
 		"device": true, // device to mount from
+		"size":   true, // size limit, for volumes stored under the daemon root
 	}
 )
 
@@ -33,6 +36,7 @@ type optsConfig struct {
 	MountType   string
 	MountOpts   string
 	MountDevice string
+	Quota       quota.Quota
 }
 
 func (o *optsConfig) String() string {
@@ -68,9 +72,60 @@ func setOpts(v *localVolume, opts map[string]string) error {
 		MountOpts:   opts["o"],
 		MountDevice: opts["device"],
 	}
+	if val, ok := opts["size"]; ok {
+		size, err := units.RAMInBytes(val)
+		if err != nil {
+			return validationError(fmt.Sprintf("invalid size %q: %v", val, err))
+		}
+		if size <= 0 {
+			return validationError(fmt.Sprintf("invalid size %q: size must be positive", val))
+		}
+		if v.needsMount() {
+			return validationError("the size option cannot be combined with the type, o and device options")
+		}
+		if v.quotaCtl == nil {
+			return validationError(fmt.Sprintf("the size option requires project quota support on the filesystem of %s: xfs mounted with pquota, or ext4 with the project feature mounted with prjquota", filepath.Dir(filepath.Dir(v.path))))
+		}
+		v.opts.Quota.Size = uint64(size)
+	}
+	return nil
+}
+
+// needsMount returns true if the volume mounts a filesystem, rather than
+// storing its data under the daemon root.
+func (v *localVolume) needsMount() bool {
+	if v.opts == nil {
+		return false
+	}
+	return v.opts.MountType != "" || v.opts.MountOpts != "" || v.opts.MountDevice != ""
+}
+
+// setQuota limits the size of the directory of the volume, if the volume has
+// a size option.
+func (v *localVolume) setQuota() error {
+	if v.opts == nil || v.opts.Quota.Size == 0 {
+		return nil
+	}
+	if err := v.quotaCtl.SetQuota(filepath.Dir(v.path), v.opts.Quota); err != nil {
+		return errors.Wrap(err, "error while setting volume size limit")
+	}
 	return nil
 }
 
+// Usage returns the number of bytes used by the volume and its size limit,
+// from the project quota accounting of the filesystem, and false if the
+// volume has no size limit.
+func (v *localVolume) Usage() (usage int64, limit int64, ok bool, err error) {
+	if v.opts == nil || v.opts.Quota.Size == 0 || v.quotaCtl == nil {
+		return 0, 0, false, nil
+	}
+	u, ok, err := v.quotaCtl.GetUsage(filepath.Dir(v.path))
+	if err != nil || !ok {
+		return 0, 0, false, err
+	}
+	return int64(u), int64(v.opts.Quota.Size), true, nil
+}
+
 func (v *localVolume) mount() error {
 	if v.opts.MountDevice == "" {
 		return fmt.Errorf("missing device in volume options")
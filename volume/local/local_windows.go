@@ -36,6 +36,14 @@ func (v *localVolume) mount() error {
 	return nil
 }
 
+func (v *localVolume) needsMount() bool {
+	return false
+}
+
+func (v *localVolume) setQuota() error {
+	return nil
+}
+
 func (v *localVolume) CreatedAt() (time.Time, error) {
 	fileInfo, err := os.Stat(v.path)
 	if err != nil {
@@ -96,6 +96,28 @@ func (p *linuxParser) validateMountConfigImpl(mnt *mount.Mount, validateBindSour
 		if len(mnt.Source) == 0 && mnt.ReadOnly {
 			return &errMountConfig{mnt, fmt.Errorf("must not set ReadOnly mode when using anonymous volumes")}
 		}
+
+		if opts := mnt.VolumeOptions; opts != nil && opts.Subpath != "" {
+			if len(mnt.Source) == 0 {
+				return &errMountConfig{mnt, fmt.Errorf("must not set Subpath when using anonymous volumes")}
+			}
+			if err := validateSubpath(opts.Subpath); err != nil {
+				return &errMountConfig{mnt, err}
+			}
+		}
+	case mount.TypeImage:
+		if len(mnt.Source) == 0 {
+			return &errMountConfig{mnt, errMissingField("Source")}
+		}
+		if mnt.BindOptions != nil {
+			return &errMountConfig{mnt, errExtraField("BindOptions")}
+		}
+		if mnt.VolumeOptions != nil {
+			return &errMountConfig{mnt, errExtraField("VolumeOptions")}
+		}
+		if mnt.TmpfsOptions != nil {
+			return &errMountConfig{mnt, errExtraField("TmpfsOptions")}
+		}
 	case mount.TypeTmpfs:
 		if mnt.BindOptions != nil {
 			return &errMountConfig{mnt, errExtraField("BindOptions")}
@@ -305,7 +327,8 @@ func (p *linuxParser) parseMountSpec(cfg mount.Mount, validateBindSourceExists b
 			if cfg.VolumeOptions.DriverConfig != nil {
 				mp.Driver = cfg.VolumeOptions.DriverConfig.Name
 			}
-			if cfg.VolumeOptions.NoCopy {
+			// Image content is only copied to the root of a volume
+			if cfg.VolumeOptions.NoCopy || cfg.VolumeOptions.Subpath != "" {
 				mp.CopyData = false
 			}
 		}
@@ -318,6 +341,11 @@ func (p *linuxParser) parseMountSpec(cfg mount.Mount, validateBindSourceExists b
 			// default propagation mode.
 			mp.Propagation = linuxDefaultPropagationMode
 		}
+	case mount.TypeImage:
+		// The layer of the image is created by the daemon, and is always
+		// mounted read-only.
+		mp.RW = false
+		mp.Source = cfg.Source
 	case mount.TypeTmpfs:
 		// NOP
 	}
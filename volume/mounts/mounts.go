@@ -7,11 +7,14 @@ import (
 	"syscall"
 
 	mounttypes "github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/layer"
 	"github.com/docker/docker/pkg/idtools"
 	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/pkg/symlink"
 	"github.com/docker/docker/volume"
 	"github.com/opencontainers/selinux/go-selinux/label"
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 )
 
 // MountPoint is the intersection point between a volume and a container. It
@@ -39,6 +42,9 @@ type MountPoint struct {
 	// Volume is the volume providing data to this mountpoint.
 	// This is nil unless `Type` is set to `TypeVolume`
 	Volume volume.Volume `json:"-"`
+	// Layer is the layer providing the filesystem of the image to this
+	// mountpoint. This is nil unless `Type` is set to `TypeImage`
+	Layer layer.RWLayer `json:"-"`
 
 	// Mode is the comma separated list of options supplied by the user when creating
 	// the bind/volume mount.
@@ -57,6 +63,7 @@ type MountPoint struct {
 	CopyData bool `json:"-"`
 	// ID is the opaque ID used to pass to the volume driver.
 	// This should be set by calls to `Mount` and unset by calls to `Unmount`
+	// For image mounts, it is the name of the layer in the layer store.
 	ID string `json:",omitempty"`
 
 	// Sepc is a copy of the API request that created this mount.
@@ -76,6 +83,17 @@ type MountPoint struct {
 
 // Cleanup frees resources used by the mountpoint
 func (m *MountPoint) Cleanup() error {
+	if m.Layer != nil {
+		if m.active == 0 {
+			return nil
+		}
+		if err := m.Layer.Unmount(); err != nil {
+			return errors.Wrapf(err, "error unmounting image %s", m.Source)
+		}
+		m.active--
+		return nil
+	}
+
 	if m.Volume == nil || m.ID == "" {
 		return nil
 	}
@@ -122,6 +140,15 @@ func (m *MountPoint) Setup(mountLabel string, rootIDs idtools.Identity, checkFun
 		}
 	}()
 
+	if m.Layer != nil {
+		fs, err := m.Layer.Mount(mountLabel)
+		if err != nil {
+			return "", errors.Wrapf(err, "error while mounting image '%s'", m.Source)
+		}
+		m.active++
+		return fs.Path(), nil
+	}
+
 	if m.Volume != nil {
 		id := m.ID
 		if id == "" {
@@ -131,6 +158,15 @@ func (m *MountPoint) Setup(mountLabel string, rootIDs idtools.Identity, checkFun
 		if err != nil {
 			return "", errors.Wrapf(err, "error while mounting volume '%s'", m.Source)
 		}
+		if m.Spec.VolumeOptions != nil && m.Spec.VolumeOptions.Subpath != "" {
+			path, err = resolveSubpath(path, m.Spec.VolumeOptions.Subpath)
+			if err != nil {
+				if uerr := m.Volume.Unmount(id); uerr != nil {
+					logrus.WithError(uerr).Warnf("error unmounting volume %s", m.Volume.Name())
+				}
+				return "", errors.Wrapf(err, "error while mounting volume '%s'", m.Source)
+			}
+		}
 
 		m.ID = id
 		m.active++
@@ -164,6 +200,27 @@ func (m *MountPoint) Setup(mountLabel string, rootIDs idtools.Identity, checkFun
 	return m.Source, nil
 }
 
+// resolveSubpath returns the path of subpath in the volume mounted at root.
+// Symlinks are resolved within root, so that the resulting path cannot be
+// outside of the volume.
+func resolveSubpath(root, subpath string) (string, error) {
+	path, err := symlink.FollowSymlinkInScope(filepath.Join(root, subpath), root)
+	if err != nil {
+		return "", errors.Wrapf(err, "error resolving subpath %q", subpath)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", errors.Errorf("subpath %q does not exist in the volume", subpath)
+		}
+		return "", errors.Wrapf(err, "error resolving subpath %q", subpath)
+	}
+	if !fi.IsDir() {
+		return "", errors.Errorf("subpath %q is not a directory", subpath)
+	}
+	return path, nil
+}
+
 // Path returns the path of a volume in a mount point.
 func (m *MountPoint) Path() string {
 	if m.Volume != nil {
@@ -0,0 +1,62 @@
+// +build !windows
+
+package mounts // import "github.com/docker/docker/volume/mounts"
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveSubpath(t *testing.T) {
+	root, err := ioutil.TempDir("", "test-resolve-subpath")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := os.MkdirAll(filepath.Join(root, "foo", "bar"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Symlinks are resolved within the volume
+	if err := os.Symlink("/foo", filepath.Join(root, "abs")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../..", filepath.Join(root, "foo", "up")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		subpath  string
+		expected string
+		err      string
+	}{
+		{subpath: "foo", expected: filepath.Join(root, "foo")},
+		{subpath: "foo/bar", expected: filepath.Join(root, "foo", "bar")},
+		{subpath: "abs/bar", expected: filepath.Join(root, "foo", "bar")},
+		{subpath: "foo/up", expected: root},
+		{subpath: "missing", err: `subpath "missing" does not exist in the volume`},
+		{subpath: "file", err: `subpath "file" is not a directory`},
+	}
+	for _, c := range cases {
+		path, err := resolveSubpath(root, c.subpath)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected error %q, got %v", c.subpath, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.subpath, err)
+			continue
+		}
+		if path != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.subpath, c.expected, path)
+		}
+	}
+}
@@ -446,6 +446,7 @@ func TestParseMountSpec(t *testing.T) {
 		{mount.Mount{Type: mount.TypeBind, Source: testDir, Target: testDestinationPath + string(os.PathSeparator), ReadOnly: true}, MountPoint{Type: mount.TypeBind, Source: testDir, Destination: testDestinationPath, Propagation: parser.DefaultPropagationMode()}},
 		{mount.Mount{Type: mount.TypeVolume, Target: testDestinationPath}, MountPoint{Type: mount.TypeVolume, Destination: testDestinationPath, RW: true, CopyData: parser.DefaultCopyMode()}},
 		{mount.Mount{Type: mount.TypeVolume, Target: testDestinationPath + string(os.PathSeparator)}, MountPoint{Type: mount.TypeVolume, Destination: testDestinationPath, RW: true, CopyData: parser.DefaultCopyMode()}},
+		{mount.Mount{Type: mount.TypeVolume, Source: "hello", Target: testDestinationPath, VolumeOptions: &mount.VolumeOptions{Subpath: "foo"}}, MountPoint{Type: mount.TypeVolume, Destination: testDestinationPath, RW: true, CopyData: false}},
 	}
 
 	for i, c := range cases {
@@ -478,3 +479,29 @@ func TestParseMountSpec(t *testing.T) {
 		}
 	}
 }
+
+func TestParseMountSpecImage(t *testing.T) {
+	parser := &linuxParser{}
+
+	mp, err := parser.ParseMountSpec(mount.Mount{Type: mount.TypeImage, Source: "busybox:latest", Target: "/data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mp.Type != mount.TypeImage || mp.Source != "busybox:latest" || mp.Destination != "/data" {
+		t.Fatalf("unexpected mount point: %+v", mp)
+	}
+	if mp.RW {
+		t.Fatal("expected image mount to be read-only")
+	}
+
+	for _, cfg := range []mount.Mount{
+		{Type: mount.TypeImage, Target: "/data"},
+		{Type: mount.TypeImage, Source: "busybox", Target: "/data", VolumeOptions: &mount.VolumeOptions{}},
+		{Type: mount.TypeImage, Source: "busybox", Target: "/data", BindOptions: &mount.BindOptions{}},
+		{Type: mount.TypeImage, Source: "busybox", Target: "/data", TmpfsOptions: &mount.TmpfsOptions{}},
+	} {
+		if _, err := parser.ParseMountSpec(cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package mounts // import "github.com/docker/docker/volume/mounts"
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/docker/docker/api/types/mount"
 	"github.com/pkg/errors"
@@ -26,3 +27,18 @@ func errExtraField(name string) error {
 func errMissingField(name string) error {
 	return errors.Errorf("field %s must not be empty", name)
 }
+
+// validateSubpath checks that subpath is a relative path that stays inside
+// the volume. Both separators are checked, as the parsers of all platforms
+// are used on the same host.
+func validateSubpath(subpath string) error {
+	if strings.HasPrefix(subpath, "/") || strings.HasPrefix(subpath, `\`) || (len(subpath) >= 2 && subpath[1] == ':') {
+		return errors.Errorf("subpath must be a relative path: %s", subpath)
+	}
+	for _, elem := range strings.FieldsFunc(subpath, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return errors.Errorf("subpath must not contain \"..\": %s", subpath)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,10 @@ func TestValidateMount(t *testing.T) {
 		{mount.Mount{Type: mount.TypeVolume}, errMissingField("Target")},
 		{mount.Mount{Type: mount.TypeVolume, Target: testDestinationPath, Source: "hello"}, nil},
 		{mount.Mount{Type: mount.TypeVolume, Target: testDestinationPath}, nil},
+		{mount.Mount{Type: mount.TypeVolume, Target: testDestinationPath, Source: "hello", VolumeOptions: &mount.VolumeOptions{Subpath: "foo/bar"}}, nil},
+		{mount.Mount{Type: mount.TypeVolume, Target: testDestinationPath, VolumeOptions: &mount.VolumeOptions{Subpath: "foo"}}, errors.New("must not set Subpath when using anonymous volumes")},
+		{mount.Mount{Type: mount.TypeVolume, Target: testDestinationPath, Source: "hello", VolumeOptions: &mount.VolumeOptions{Subpath: "/foo"}}, errors.New("subpath must be a relative path: /foo")},
+		{mount.Mount{Type: mount.TypeVolume, Target: testDestinationPath, Source: "hello", VolumeOptions: &mount.VolumeOptions{Subpath: "foo/../../bar"}}, errors.New(`subpath must not contain "..": foo/../../bar`)},
 		{mount.Mount{Type: mount.TypeBind}, errMissingField("Target")},
 		{mount.Mount{Type: mount.TypeBind, Target: testDestinationPath}, errMissingField("Source")},
 		{mount.Mount{Type: mount.TypeBind, Target: testDestinationPath, Source: testSourcePath, VolumeOptions: &mount.VolumeOptions{}}, errExtraField("VolumeOptions")},
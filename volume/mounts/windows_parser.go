@@ -267,6 +267,15 @@ func (p *windowsParser) validateMountConfigReg(mnt *mount.Mount, destRegex strin
 			return &errMountConfig{mnt, fmt.Errorf("must not set ReadOnly mode when using anonymous volumes")}
 		}
 
+		if opts := mnt.VolumeOptions; opts != nil && opts.Subpath != "" {
+			if len(mnt.Source) == 0 {
+				return &errMountConfig{mnt, fmt.Errorf("must not set Subpath when using anonymous volumes")}
+			}
+			if err := validateSubpath(opts.Subpath); err != nil {
+				return &errMountConfig{mnt, err}
+			}
+		}
+
 		if len(mnt.Source) != 0 {
 			if err := p.ValidateVolumeName(mnt.Source); err != nil {
 				return &errMountConfig{mnt, err}
@@ -395,7 +404,8 @@ func (p *windowsParser) parseMountSpec(cfg mount.Mount, destRegex string, conver
 			if cfg.VolumeOptions.DriverConfig != nil {
 				mp.Driver = cfg.VolumeOptions.DriverConfig.Name
 			}
-			if cfg.VolumeOptions.NoCopy {
+			// Image content is only copied to the root of a volume
+			if cfg.VolumeOptions.NoCopy || cfg.VolumeOptions.Subpath != "" {
 				mp.CopyData = false
 			}
 		}
@@ -0,0 +1,87 @@
+package service // import "github.com/docker/docker/volume/service"
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/archive"
+	"github.com/docker/docker/pkg/chrootarchive"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/volume/service/opts"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Export writes a tar archive of the contents of the volume to w.
+// The owners of the files are mapped from the remapped root of the daemon,
+// if any, to the IDs seen by containers.
+func (s *VolumesService) Export(ctx context.Context, name string, w io.Writer) error {
+	return s.withMountedVolume(ctx, name, func(path string) error {
+		rdr, err := archive.TarWithOptions(path, s.tarOptions())
+		if err != nil {
+			return errors.Wrapf(err, "error exporting volume %s", name)
+		}
+		defer rdr.Close()
+
+		if _, err := io.Copy(w, rdr); err != nil {
+			return errors.Wrapf(err, "error exporting volume %s", name)
+		}
+		return nil
+	})
+}
+
+// Import extracts the tar archive read from r into the volume, replacing
+// existing files with the same names.
+// The owners of the files are mapped to the remapped root of the daemon, if
+// any.
+func (s *VolumesService) Import(ctx context.Context, name string, r io.Reader) error {
+	return s.withMountedVolume(ctx, name, func(path string) error {
+		if err := chrootarchive.Untar(r, path, s.tarOptions()); err != nil {
+			return errors.Wrapf(err, "error importing volume %s", name)
+		}
+		return nil
+	})
+}
+
+// withMountedVolume calls fn with the path of the volume, which is
+// referenced and mounted while fn runs.
+func (s *VolumesService) withMountedVolume(ctx context.Context, name string, fn func(path string) error) error {
+	ref := stringid.GenerateRandomID()
+	v, err := s.vs.Get(ctx, name, opts.WithGetReference(ref))
+	if err != nil {
+		if IsNotExist(err) {
+			err = errdefs.NotFound(err)
+		}
+		return err
+	}
+	defer func() {
+		if err := s.vs.Release(ctx, name, ref); err != nil {
+			logrus.WithError(err).WithField("volume", name).Warn("Failed to release volume reference")
+		}
+	}()
+
+	path, err := v.Mount(ref)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := v.Unmount(ref); err != nil {
+			logrus.WithError(err).WithField("volume", name).Warn("Failed to unmount volume")
+		}
+	}()
+	if path == "" {
+		return errdefs.NotImplemented(errors.Errorf("driver %s did not return a path for volume %s", v.DriverName(), name))
+	}
+
+	return fn(path)
+}
+
+func (s *VolumesService) tarOptions() *archive.TarOptions {
+	options := &archive.TarOptions{Compression: archive.Uncompressed}
+	if s.idMapping != nil {
+		options.UIDMaps = s.idMapping.UIDs()
+		options.GIDMaps = s.idMapping.GIDs()
+	}
+	return options
+}
@@ -0,0 +1,95 @@
+package service // import "github.com/docker/docker/volume/service"
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/docker/docker/volume"
+	"github.com/docker/docker/volume/service/opts"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Clone creates a new volume with the given name, the driver and driver
+// options of the volume source, and a copy of its contents.
+// Drivers which are able to clone volumes copy the contents themselves.
+// Otherwise, both volumes are mounted and the contents are copied by the
+// daemon, using reflinks where the filesystem supports them.
+// The contents of source should not be changed while it is cloned.
+func (s *VolumesService) Clone(ctx context.Context, source, name string) (*types.Volume, error) {
+	if name == "" {
+		return nil, errdefs.InvalidParameter(errors.New("name of the new volume cannot be empty"))
+	}
+	src, err := s.vs.Get(ctx, source)
+	if err != nil {
+		if IsNotExist(err) {
+			err = errdefs.NotFound(err)
+		}
+		return nil, err
+	}
+	if _, err := s.vs.Get(ctx, name); err == nil {
+		return nil, errdefs.Conflict(errors.Errorf("volume %s already exists", name))
+	} else if !IsNotExist(err) {
+		return nil, err
+	}
+
+	var driverOpts map[string]string
+	if dv, ok := src.(volume.DetailedVolume); ok {
+		driverOpts = dv.Options()
+	}
+	vd, err := s.ds.GetDriver(src.DriverName())
+	if err != nil {
+		return nil, err
+	}
+
+	var v volume.Volume
+	if c, ok := vd.(volume.Cloner); ok && c.CanClone() {
+		v, err = s.vs.Create(ctx, name, src.DriverName(), opts.WithCreateOptions(driverOpts), opts.WithCreateCloneSource(src.Name()))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if src.DriverName() == volume.DefaultDriverName && !hasNoMountOptions(src) {
+			// The clone would mount the same filesystem as the source
+			return nil, errdefs.InvalidParameter(errors.Errorf("cannot clone volume %s: local volumes with mount options cannot be cloned", source))
+		}
+		v, err = s.createCopy(ctx, src, name, driverOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.eventLogger.LogVolumeEvent(v.Name(), "create", map[string]string{"driver": v.DriverName(), "source": src.Name()})
+	apiV := volumeToAPIType(v)
+	return &apiV, nil
+}
+
+// createCopy creates the volume name with the driver of src, and copies the
+// contents of src to it. The volume is removed if the copy fails.
+func (s *VolumesService) createCopy(ctx context.Context, src volume.Volume, name string, driverOpts map[string]string) (volume.Volume, error) {
+	// The reference protects the new volume from being pruned before its
+	// contents are copied
+	ref := stringid.GenerateRandomID()
+	v, err := s.vs.Create(ctx, name, src.DriverName(), opts.WithCreateOptions(driverOpts), opts.WithCreateReference(ref))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.withMountedVolume(ctx, src.Name(), func(srcPath string) error {
+		return s.withMountedVolume(ctx, name, func(dstPath string) error {
+			return s.copyVolumeData(srcPath, dstPath)
+		})
+	})
+	if err := s.vs.Release(ctx, name, ref); err != nil {
+		logrus.WithError(err).WithField("volume", name).Warn("Failed to release volume reference")
+	}
+	if err != nil {
+		if err := s.vs.Remove(ctx, v); err != nil {
+			logrus.WithError(err).WithField("volume", name).Warn("Failed to remove volume after failed clone")
+		}
+		return nil, errors.Wrapf(err, "error cloning volume %s", src.Name())
+	}
+	return v, nil
+}
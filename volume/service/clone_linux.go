@@ -0,0 +1,9 @@
+package service // import "github.com/docker/docker/volume/service"
+
+import "github.com/docker/docker/daemon/graphdriver/copy"
+
+// copyVolumeData copies the contents of the directory src to the directory
+// dst, with reflinks if the filesystem supports them.
+func (s *VolumesService) copyVolumeData(src, dst string) error {
+	return copy.DirCopy(src, dst, copy.Content, true)
+}
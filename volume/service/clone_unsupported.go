@@ -0,0 +1,11 @@
+// +build !linux
+
+package service // import "github.com/docker/docker/volume/service"
+
+import "github.com/docker/docker/pkg/chrootarchive"
+
+// copyVolumeData copies the contents of the directory src to the directory
+// dst.
+func (s *VolumesService) copyVolumeData(src, dst string) error {
+	return chrootarchive.NewArchiver(s.idMapping).CopyWithTar(src, dst)
+}
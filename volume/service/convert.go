@@ -28,6 +28,10 @@ type pathCacher interface {
 	CachedPath() string
 }
 
+type usageGetter interface {
+	Usage() (usage int64, limit int64, ok bool, err error)
+}
+
 func (s *VolumesService) volumesToAPI(ctx context.Context, volumes []volume.Volume, opts ...convertOpt) []*types.Volume {
 	var (
 		out        = make([]*types.Volume, 0, len(volumes))
@@ -64,12 +68,24 @@ func (s *VolumesService) volumesToAPI(ctx context.Context, volumes []volume.Volu
 			if apiV.Mountpoint == "" {
 				apiV.Mountpoint = p
 			}
-			sz, err := directory.Size(ctx, p)
-			if err != nil {
-				logrus.WithError(err).WithField("volume", v.Name()).Warnf("Failed to determine size of volume")
-				sz = -1
+			apiV.UsageData = &types.VolumeUsageData{RefCount: int64(s.vs.CountReferences(v))}
+			if vv, ok := v.(usageGetter); ok {
+				usage, limit, ok, err := vv.Usage()
+				if err != nil {
+					logrus.WithError(err).WithField("volume", v.Name()).Warnf("Failed to get usage of volume from quota")
+				} else if ok {
+					apiV.UsageData.Size = usage
+					apiV.UsageData.Limit = limit
+				}
+			}
+			if apiV.UsageData.Limit == 0 {
+				sz, err := directory.Size(ctx, p)
+				if err != nil {
+					logrus.WithError(err).WithField("volume", v.Name()).Warnf("Failed to determine size of volume")
+					sz = -1
+				}
+				apiV.UsageData.Size = sz
 			}
-			apiV.UsageData = &types.VolumeUsageData{Size: sz, RefCount: int64(s.vs.CountReferences(v))}
 		}
 
 		out = append(out, &apiV)
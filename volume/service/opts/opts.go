@@ -9,6 +9,9 @@ type CreateConfig struct {
 	Options   map[string]string
 	Labels    map[string]string
 	Reference string
+	// CloneSource is the name of the volume the driver creates the volume
+	// as a copy of.
+	CloneSource string
 }
 
 // WithCreateLabels creates a CreateOption which sets the labels to the
@@ -36,6 +39,15 @@ func WithCreateReference(ref string) CreateOption {
 	}
 }
 
+// WithCreateCloneSource creates a CreateOption which makes the volume driver
+// create the volume as a copy of the volume named source. The driver must be
+// able to clone volumes.
+func WithCreateCloneSource(source string) CreateOption {
+	return func(cfg *CreateConfig) {
+		cfg.CloneSource = source
+	}
+}
+
 // GetConfig is used with `GetOption` to set options for the volumes service's
 // `Get` implementation.
 type GetConfig struct {
@@ -20,6 +20,7 @@ import (
 
 type ds interface {
 	GetDriverList() []string
+	GetDriver(name string) (volume.Driver, error)
 }
 
 type volumeEventLogger interface {
@@ -33,12 +34,13 @@ type VolumesService struct {
 	ds           ds
 	pruneRunning int32
 	eventLogger  volumeEventLogger
+	idMapping    *idtools.IdentityMapping
 }
 
 // NewVolumeService creates a new volume service
-func NewVolumeService(root string, pg plugingetter.PluginGetter, rootIDs idtools.Identity, logger volumeEventLogger) (*VolumesService, error) {
+func NewVolumeService(root string, pg plugingetter.PluginGetter, idMapping *idtools.IdentityMapping, logger volumeEventLogger) (*VolumesService, error) {
 	ds := drivers.NewStore(pg)
-	if err := setupDefaultDriver(ds, root, rootIDs); err != nil {
+	if err := setupDefaultDriver(ds, root, idMapping.RootPair()); err != nil {
 		return nil, err
 	}
 
@@ -46,7 +48,7 @@ func NewVolumeService(root string, pg plugingetter.PluginGetter, rootIDs idtools
 	if err != nil {
 		return nil, err
 	}
-	return &VolumesService{vs: vs, ds: ds, eventLogger: logger}, nil
+	return &VolumesService{vs: vs, ds: ds, eventLogger: logger, idMapping: idMapping}, nil
 }
 
 // GetDriverList gets the list of registered volume drivers
@@ -184,16 +186,29 @@ var acceptedListFilters = map[string]bool{
 // volumes with mount options are not really local even if they are using the
 // local driver.
 func (s *VolumesService) LocalVolumesSize(ctx context.Context) ([]*types.Volume, error) {
-	ls, _, err := s.vs.Find(ctx, And(ByDriver(volume.DefaultDriverName), CustomFilter(func(v volume.Volume) bool {
-		dv, ok := v.(volume.DetailedVolume)
-		return ok && len(dv.Options()) == 0
-	})))
+	ls, _, err := s.vs.Find(ctx, And(ByDriver(volume.DefaultDriverName), CustomFilter(hasNoMountOptions)))
 	if err != nil {
 		return nil, err
 	}
 	return s.volumesToAPI(ctx, ls, calcSize(true)), nil
 }
 
+// hasNoMountOptions returns true if the local volume v stores its data under
+// the daemon root. The "size" option, which limits the size of the data, is
+// the only option of such volumes.
+func hasNoMountOptions(v volume.Volume) bool {
+	dv, ok := v.(volume.DetailedVolume)
+	if !ok {
+		return false
+	}
+	for opt := range dv.Options() {
+		if opt != "size" {
+			return false
+		}
+	}
+	return true
+}
+
 // Prune removes (local) volumes which match the past in filter arguments.
 // Note that this intentionally skips volumes with mount options as there would
 // be no space reclaimed in this case.
@@ -207,10 +222,7 @@ func (s *VolumesService) Prune(ctx context.Context, filter filters.Args) (*types
 	if err != nil {
 		return nil, err
 	}
-	ls, _, err := s.vs.Find(ctx, And(ByDriver(volume.DefaultDriverName), ByReferenced(false), by, CustomFilter(func(v volume.Volume) bool {
-		dv, ok := v.(volume.DetailedVolume)
-		return ok && len(dv.Options()) == 0
-	})))
+	ls, _, err := s.vs.Find(ctx, And(ByDriver(volume.DefaultDriverName), ByReferenced(false), by, CustomFilter(hasNoMountOptions)))
 	if err != nil {
 		return nil, err
 	}
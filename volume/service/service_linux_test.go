@@ -1,13 +1,17 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
+	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/idtools"
+	"github.com/docker/docker/pkg/reexec"
 	"github.com/docker/docker/volume"
 	volumedrivers "github.com/docker/docker/volume/drivers"
 	"github.com/docker/docker/volume/local"
@@ -15,8 +19,13 @@ import (
 	"github.com/docker/docker/volume/testutils"
 	"gotest.tools/assert"
 	is "gotest.tools/assert/cmp"
+	"gotest.tools/skip"
 )
 
+func init() {
+	reexec.Init()
+}
+
 func TestLocalVolumeSize(t *testing.T) {
 	t.Parallel()
 
@@ -64,3 +73,109 @@ func TestLocalVolumeSize(t *testing.T) {
 		}
 	}
 }
+
+func TestExportImport(t *testing.T) {
+	skip.If(t, os.Getuid() != 0, "requires root to chroot")
+
+	ds := volumedrivers.NewStore(nil)
+	dir, err := ioutil.TempDir("", t.Name())
+	assert.Assert(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := local.New(dir, idtools.Identity{UID: os.Getuid(), GID: os.Getegid()})
+	assert.Assert(t, err)
+	assert.Assert(t, ds.Register(l, volume.DefaultDriverName))
+
+	service, cleanup := newTestService(t, ds)
+	defer cleanup()
+
+	ctx := context.Background()
+	src, err := service.Create(ctx, "src", volume.DefaultDriverName)
+	assert.Assert(t, err)
+	dst, err := service.Create(ctx, "dst", volume.DefaultDriverName)
+	assert.Assert(t, err)
+
+	assert.Assert(t, os.MkdirAll(filepath.Join(src.Mountpoint, "dir"), 0755))
+	assert.Assert(t, ioutil.WriteFile(filepath.Join(src.Mountpoint, "dir", "data"), []byte("data"), 0644))
+	assert.Assert(t, os.Chown(filepath.Join(src.Mountpoint, "dir", "data"), 1000, 1000))
+
+	var buf bytes.Buffer
+	assert.Assert(t, service.Export(ctx, "src", &buf))
+	assert.Assert(t, service.Import(ctx, "dst", &buf))
+
+	b, err := ioutil.ReadFile(filepath.Join(dst.Mountpoint, "dir", "data"))
+	assert.Assert(t, err)
+	assert.Check(t, is.Equal("data", string(b)))
+	fi, err := os.Stat(filepath.Join(dst.Mountpoint, "dir", "data"))
+	assert.Assert(t, err)
+	assert.Check(t, is.Equal(uint32(1000), fi.Sys().(*syscall.Stat_t).Uid))
+
+	// The volumes are not referenced once exported or imported
+	_, err = service.Get(ctx, "src")
+	assert.Assert(t, err)
+	assert.Assert(t, service.Remove(ctx, "src"))
+	assert.Assert(t, service.Remove(ctx, "dst"))
+
+	err = service.Export(ctx, "unknown", &buf)
+	assert.Check(t, errdefs.IsNotFound(err), err)
+}
+
+type fakeCloneDriver struct {
+	volume.Driver
+	sources map[string]string
+}
+
+func (d *fakeCloneDriver) CanClone() bool {
+	return true
+}
+
+func (d *fakeCloneDriver) Clone(name, source string, opts map[string]string) (volume.Volume, error) {
+	d.sources[name] = source
+	return d.Driver.Create(name, opts)
+}
+
+func TestClone(t *testing.T) {
+	ds := volumedrivers.NewStore(nil)
+	dir, err := ioutil.TempDir("", t.Name())
+	assert.Assert(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := local.New(dir, idtools.Identity{UID: os.Getuid(), GID: os.Getegid()})
+	assert.Assert(t, err)
+	assert.Assert(t, ds.Register(l, volume.DefaultDriverName))
+	cloneDriver := &fakeCloneDriver{Driver: testutils.NewFakeDriver("fake"), sources: make(map[string]string)}
+	assert.Assert(t, ds.Register(cloneDriver, "fake"))
+
+	service, cleanup := newTestService(t, ds)
+	defer cleanup()
+
+	ctx := context.Background()
+	src, err := service.Create(ctx, "src", volume.DefaultDriverName)
+	assert.Assert(t, err)
+	assert.Assert(t, os.MkdirAll(filepath.Join(src.Mountpoint, "dir"), 0755))
+	assert.Assert(t, ioutil.WriteFile(filepath.Join(src.Mountpoint, "dir", "data"), []byte("data"), 0644))
+
+	clone, err := service.Clone(ctx, "src", "clone")
+	assert.Assert(t, err)
+	assert.Check(t, is.Equal(volume.DefaultDriverName, clone.Driver))
+	b, err := ioutil.ReadFile(filepath.Join(clone.Mountpoint, "dir", "data"))
+	assert.Assert(t, err)
+	assert.Check(t, is.Equal("data", string(b)))
+
+	// Neither volume is referenced once cloned
+	assert.Assert(t, service.Remove(ctx, "clone"))
+	assert.Assert(t, service.Remove(ctx, "src"))
+
+	_, err = service.Create(ctx, "fake-src", "fake", opts.WithCreateOptions(map[string]string{"opt": "value"}))
+	assert.Assert(t, err)
+	clone, err = service.Clone(ctx, "fake-src", "fake-clone")
+	assert.Assert(t, err)
+	assert.Check(t, is.Equal("fake", clone.Driver))
+	assert.Check(t, is.DeepEqual(map[string]string{"opt": "value"}, clone.Options))
+	assert.Check(t, is.Equal("fake-src", cloneDriver.sources["fake-clone"]))
+
+	_, err = service.Clone(ctx, "fake-src", "fake-clone")
+	assert.Check(t, errdefs.IsConflict(err), err)
+	_, err = service.Clone(ctx, "unknown", "other")
+	assert.Check(t, errdefs.IsNotFound(err), err)
+}
@@ -241,7 +241,7 @@ func newTestService(t *testing.T, ds *volumedrivers.Store) (*VolumesService, fun
 
 	store, err := NewStore(dir, ds)
 	assert.Assert(t, err)
-	s := &VolumesService{vs: store, eventLogger: dummyEventLogger{}}
+	s := &VolumesService{vs: store, ds: ds, eventLogger: dummyEventLogger{}}
 	return s, func() {
 		assert.Check(t, s.Shutdown())
 		assert.Check(t, os.RemoveAll(dir))
@@ -60,6 +60,13 @@ func (v volumeWrapper) Scope() string {
 	return v.scope
 }
 
+func (v volumeWrapper) Usage() (int64, int64, bool, error) {
+	if vv, ok := v.Volume.(usageGetter); ok {
+		return vv.Usage()
+	}
+	return 0, 0, false, nil
+}
+
 func (v volumeWrapper) CachedPath() string {
 	if vv, ok := v.Volume.(interface {
 		CachedPath() string
@@ -465,7 +472,7 @@ func (s *VolumeStore) Create(ctx context.Context, name, driverName string, creat
 	default:
 	}
 
-	v, err := s.create(ctx, name, driverName, cfg.Options, cfg.Labels)
+	v, err := s.create(ctx, name, driverName, cfg.Options, cfg.Labels, cfg.CloneSource)
 	if err != nil {
 		if _, ok := err.(*OpErr); ok {
 			return nil, err
@@ -553,7 +560,7 @@ func volumeExists(ctx context.Context, store *drivers.Store, v volume.Volume) (b
 //  for the given volume name, an error is returned after checking if the reference is stale.
 // If the reference is stale, it will be purged and this create can continue.
 // It is expected that callers of this function hold any necessary locks.
-func (s *VolumeStore) create(ctx context.Context, name, driverName string, opts, labels map[string]string) (volume.Volume, error) {
+func (s *VolumeStore) create(ctx context.Context, name, driverName string, opts, labels map[string]string, cloneSource string) (volume.Volume, error) {
 	// Validate the name in a platform-specific manner
 
 	// volume name validation is specific to the host os and not on container image
@@ -595,7 +602,11 @@ func (s *VolumeStore) create(ctx context.Context, name, driverName string, opts,
 
 	logrus.Debugf("Registering new volume reference: driver %q, name %q", vd.Name(), name)
 	if v, _ = vd.Get(name); v == nil {
-		v, err = vd.Create(name, opts)
+		if cloneSource != "" {
+			v, err = cloneVolume(vd, name, cloneSource, opts)
+		} else {
+			v, err = vd.Create(name, opts)
+		}
 		if err != nil {
 			if _, err := s.drivers.ReleaseDriver(driverName); err != nil {
 				logrus.WithError(err).WithField("driver", driverName).Error("Error releasing reference to volume driver")
@@ -623,6 +634,16 @@ func (s *VolumeStore) create(ctx context.Context, name, driverName string, opts,
 	return volumeWrapper{v, labels, vd.Scope(), opts}, nil
 }
 
+// cloneVolume makes the driver vd create the volume name as a copy of the
+// volume source.
+func cloneVolume(vd volume.Driver, name, source string, opts map[string]string) (volume.Volume, error) {
+	c, ok := vd.(volume.Cloner)
+	if !ok || !c.CanClone() {
+		return nil, errdefs.NotImplemented(errors.Errorf("volume driver %s does not support cloning volumes", vd.Name()))
+	}
+	return c.Clone(name, source, opts)
+}
+
 // Get looks if a volume with the given name exists and returns it if so
 func (s *VolumeStore) Get(ctx context.Context, name string, getOptions ...opts.GetOption) (volume.Volume, error) {
 	var cfg opts.GetConfig
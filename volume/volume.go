@@ -39,6 +39,19 @@ type Capability struct {
 	// A `local` scope indicates that the driver only manages volumes resources local to the host
 	// Scope is declared by the driver
 	Scope string
+	// Clone indicates that the driver is able to create a volume with a copy
+	// of the contents of another of its volumes by itself
+	Clone bool
+}
+
+// Cloner is implemented by drivers which may be able to clone volumes
+// natively, for example with a snapshot of the storage backing the volume.
+type Cloner interface {
+	// CanClone returns true if the driver supports cloning volumes.
+	CanClone() bool
+	// Clone makes a new volume with the given name and options, with a copy
+	// of the contents of the volume named source.
+	Clone(name, source string, opts map[string]string) (Volume, error)
 }
 
 // Volume is a place to store data. It is backed by a specific driver, and can be mounted.